@@ -72,6 +72,22 @@ func generateBishopMoves(bishopMagics [64]uint64, bishopIndexBits [64]int) [64][
 func fillBishopMovesSquare(file int, rank int, bishopMagics [64]uint64, bishopIndexBits [64]int, bishopMoves *[64][1024]Bitboard) {
 	square := file + rank*8
 
+	blockerSets, movesSets := bishopBlockersAndMoves(file, rank)
+	for i, blockers := range blockerSets {
+		moves := movesSets[i]
+
+		key := (uint64(blockers) * bishopMagics[square]) >> (64 - bishopIndexBits[square])
+		if bishopMoves[square][key] != 0 && bishopMoves[square][key] != moves {
+			panic(fmt.Sprintf("Invalid magic number for square %d", square))
+		}
+
+		bishopMoves[square][key] = moves
+	}
+}
+
+// bishopBlockersAndMoves returns all the combinations of blockers of a bishop on the square
+// together with the moves allowed by each combination
+func bishopBlockersAndMoves(file int, rank int) ([]Bitboard, []Bitboard) {
 	// fill choices with all the squares that can be blockers
 	choices := []Bitboard{}
 
@@ -95,6 +111,8 @@ func fillBishopMovesSquare(file int, rank int, bishopMagics [64]uint64, bishopIn
 	// All the combinations of blocked squares can be iterated by
 	// counting up to 2^len(choices) and parsing the bits as blocked squares
 	combinations := 1 << len(choices)
+	blockerSets := make([]Bitboard, 0, combinations)
+	movesSets := make([]Bitboard, 0, combinations)
 	for blockedPieces := int(0); blockedPieces < combinations; blockedPieces++ {
 		blockers := Bitboard(0)
 
@@ -149,11 +167,9 @@ func fillBishopMovesSquare(file int, rank int, bishopMagics [64]uint64, bishopIn
 			}
 		}
 
-		key := (uint64(blockers) * bishopMagics[square]) >> (64 - bishopIndexBits[square])
-		if bishopMoves[square][key] != 0 && bishopMoves[square][key] != moves {
-			panic(fmt.Sprintf("Invalid magic number for square %d", square))
-		}
-
-		bishopMoves[square][key] = moves
+		blockerSets = append(blockerSets, blockers)
+		movesSets = append(movesSets, moves)
 	}
+
+	return blockerSets, movesSets
 }
@@ -0,0 +1,140 @@
+package main
+
+import (
+	. "github.com/ZaninAndrea/chess_engine/chessboard"
+)
+
+// Results of the KPK positions, they are flags so that the results of the
+// positions reachable with a move can be combined
+const (
+	kpkInvalid = 0
+	kpkUnknown = 1
+	kpkDraw    = 2
+	kpkWin     = 4
+)
+
+// kpkPositions is the number of KPK positions: the white pawn is on files A-D and ranks 2-7,
+// the kings on any square and either side can be to move
+const kpkPositions = 2 * 24 * 64 * 64
+
+// kpkIndex returns the index of a KPK position with white as the strong side, turn is 0
+// when white is to move and 1 when black is to move
+func kpkIndex(turn int, blackKing int, whiteKing int, pawn int) int {
+	return whiteKing | blackKing<<6 | turn<<12 | (pawn%8)<<13 | (6-pawn/8)<<15
+}
+
+func kpkDistance(a int, b int) int {
+	fileDistance, rankDistance := a%8-b%8, a/8-b/8
+	if fileDistance < 0 {
+		fileDistance = -fileDistance
+	}
+	if rankDistance < 0 {
+		rankDistance = -rankDistance
+	}
+	if fileDistance > rankDistance {
+		return fileDistance
+	}
+	return rankDistance
+}
+
+func whitePawnAttacks(pawn int) Bitboard {
+	attacks := Bitboard(0)
+	if pawn%8 > 0 {
+		attacks |= Bitboard(1) << (pawn + 7)
+	}
+	if pawn%8 < 7 {
+		attacks |= Bitboard(1) << (pawn + 9)
+	}
+	return attacks
+}
+
+// generateKPKBitbase computes by retrograde analysis which KPK positions are won by the side with
+// the pawn. Positions are classified from the ones with a known result: the pawn promoting safely
+// is a win, the pawn captured or a stalemate is a draw. Then each position takes the best result
+// among the positions reachable with a move until no position changes
+func generateKPKBitbase(kingMoves [64]Bitboard) [kpkPositions / 64]uint64 {
+	results := make([]int, kpkPositions)
+
+	for idx := range results {
+		whiteKing, blackKing, turn := idx&63, (idx>>6)&63, (idx>>12)&1
+		pawn := int(SquareFromFileRank((idx>>13)&3, 6-(idx>>15)))
+
+		switch {
+		case kpkDistance(whiteKing, blackKing) <= 1 || whiteKing == pawn || blackKing == pawn ||
+			(turn == 0 && whitePawnAttacks(pawn)&(Bitboard(1)<<blackKing) != 0):
+			results[idx] = kpkInvalid
+		case turn == 0 && pawn/8 == 6 && whiteKing != pawn+8 && blackKing != pawn+8 &&
+			(kpkDistance(blackKing, pawn+8) > 1 || kpkDistance(whiteKing, pawn+8) == 1):
+			// The pawn promotes and the queen can't be captured
+			results[idx] = kpkWin
+		case turn == 1 && (kingMoves[blackKing]&^(kingMoves[whiteKing]|whitePawnAttacks(pawn)) == 0 ||
+			kingMoves[blackKing]&^kingMoves[whiteKing]&(Bitboard(1)<<pawn) != 0):
+			// Stalemate or the pawn is captured
+			results[idx] = kpkDraw
+		default:
+			results[idx] = kpkUnknown
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+
+		for idx, result := range results {
+			if result != kpkUnknown {
+				continue
+			}
+
+			whiteKing, blackKing, turn := idx&63, (idx>>6)&63, (idx>>12)&1
+			pawn := int(SquareFromFileRank((idx>>13)&3, 6-(idx>>15)))
+
+			reachable := kpkInvalid
+			if turn == 0 {
+				moves := kingMoves[whiteKing]
+				for moves != 0 {
+					sq := moves.LeastSignificant1Bit()
+					moves.ClearLeastSignificant1Bit()
+					reachable |= results[kpkIndex(1, blackKing, sq, pawn)]
+				}
+
+				if pawn/8 < 6 {
+					reachable |= results[kpkIndex(1, blackKing, whiteKing, pawn+8)]
+				}
+				if pawn/8 == 1 && pawn+8 != whiteKing && pawn+8 != blackKing {
+					reachable |= results[kpkIndex(1, blackKing, whiteKing, pawn+16)]
+				}
+			} else {
+				moves := kingMoves[blackKing]
+				for moves != 0 {
+					sq := moves.LeastSignificant1Bit()
+					moves.ClearLeastSignificant1Bit()
+					reachable |= results[kpkIndex(0, sq, whiteKing, pawn)]
+				}
+			}
+
+			// Each side picks its best result, unknown positions are decided in later passes
+			good, bad := kpkWin, kpkDraw
+			if turn == 1 {
+				good, bad = kpkDraw, kpkWin
+			}
+			switch {
+			case reachable&good != 0:
+				results[idx] = good
+			case reachable&kpkUnknown != 0:
+				continue
+			default:
+				results[idx] = bad
+			}
+			changed = true
+		}
+	}
+
+	// The positions still unknown can't be won
+	var bitbase [kpkPositions / 64]uint64
+	for idx, result := range results {
+		if result == kpkWin {
+			bitbase[idx/64] |= 1 << (idx % 64)
+		}
+	}
+
+	return bitbase
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	. "github.com/ZaninAndrea/chess_engine/chessboard"
+)
+
+// magicCandidate returns a random number with a density of bits set depending on the attempt:
+// sparse numbers are more likely to be magic with one index bit for each blocker, while the
+// magics with less index bits are usually dense
+func magicCandidate(random *rand.Rand, attempt int) uint64 {
+	candidate := random.Uint64()
+	for i := 0; i < 2-attempt%3; i++ {
+		candidate &= random.Uint64()
+	}
+
+	return candidate
+}
+
+// isMagic returns whether the number maps all the combinations of blockers to keys of the
+// passed size without collisions, different blockers can share a key only if they allow
+// the same moves. The table is reused between calls to avoid allocations
+func isMagic(magic uint64, bits int, blockerSets []Bitboard, movesSets []Bitboard, table []Bitboard) bool {
+	table = table[:1<<bits]
+	for i := range table {
+		table[i] = 0
+	}
+
+	for i, blockers := range blockerSets {
+		key := (uint64(blockers) * magic) >> (64 - bits)
+		if table[key] != 0 && table[key] != movesSets[i] {
+			return false
+		}
+		table[key] = movesSets[i]
+	}
+
+	return true
+}
+
+// findMagic searches a magic number with the passed index bits for a square,
+// it returns false if none is found in the passed number of attempts
+func findMagic(blockerSets []Bitboard, movesSets []Bitboard, bits int, attempts int, random *rand.Rand) (uint64, bool) {
+	// The mask contains all the blockers, it's the last combination
+	mask := uint64(blockerSets[len(blockerSets)-1])
+	table := make([]Bitboard, 1<<bits)
+
+	for i := 0; i < attempts; i++ {
+		magic := magicCandidate(random, i)
+
+		// The magic must spread the mask on the top bits, which become the key
+		if Bitboard((mask*magic)&0xFF00000000000000).PopCount() < 6 {
+			continue
+		}
+
+		if isMagic(magic, bits, blockerSets, movesSets, table) {
+			return magic, true
+		}
+	}
+
+	return 0, false
+}
+
+// minBlockers is the smallest number of blockers of a sliding piece, the one of a bishop on most
+// of the outer squares. Reducing the index bits by as much would leave a square without an index
+const minBlockers = 5
+
+// findMagics searches the magic numbers of a sliding piece for all the squares. The index bits of each
+// square are reduced by up to the passed amount when a magic with less bits is found in time,
+// otherwise the square keeps one bit for each blocker
+func findMagics(blockersAndMoves func(file int, rank int) ([]Bitboard, []Bitboard), reduce int, attempts int, random *rand.Rand) ([64]uint64, [64]int) {
+	var magics [64]uint64
+	var indexBits [64]int
+
+	for r := 0; r < 8; r++ {
+		for f := 0; f < 8; f++ {
+			square := f + r*8
+			blockerSets, movesSets := blockersAndMoves(f, r)
+			fullBits := blockerSets[len(blockerSets)-1].PopCount()
+
+			// Each square needs at least one index bit
+			bits := fullBits - reduce
+			if bits < 1 {
+				bits = 1
+			}
+
+			found := false
+			for ; bits <= fullBits && !found; bits++ {
+				magics[square], found = findMagic(blockerSets, movesSets, bits, attempts, random)
+				indexBits[square] = bits
+			}
+
+			// A magic with one bit for each blocker always exists, keep searching until it's found
+			for !found {
+				magics[square], found = findMagic(blockerSets, movesSets, fullBits, attempts, random)
+				indexBits[square] = fullBits
+			}
+		}
+	}
+
+	return magics, indexBits
+}
+
+// tableSize returns the number of entries of the attacks table with the passed index bits
+func tableSize(indexBits [64]int) int {
+	size := 0
+	for _, bits := range indexBits {
+		size += 1 << bits
+	}
+
+	return size
+}
+
+// magicsSource returns the Go declarations of the magic numbers and of the index bits, in the
+// format used by the chessboard package
+func magicsSource(name string, magics [64]uint64, indexBits [64]int) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "var %sMagicNumbers = [64]uint64{\n", name)
+	for i := 0; i < 64; i += 4 {
+		fmt.Fprintf(&sb, "\t%#x, %#x, %#x, %#x,\n", magics[i], magics[i+1], magics[i+2], magics[i+3])
+	}
+	fmt.Fprintf(&sb, "}\n\nvar %sIndexBits = [64]int{\n", name)
+	for i := 0; i < 64; i += 8 {
+		line := fmt.Sprint(indexBits[i : i+8])
+		fmt.Fprintf(&sb, "\t%s,\n", strings.ReplaceAll(line[1:len(line)-1], " ", ", "))
+	}
+	sb.WriteString("}\n")
+
+	return sb.String()
+}
@@ -2,20 +2,61 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
+	"fmt"
 	"io/ioutil"
+	"math/rand"
+	"os"
 
 	. "github.com/ZaninAndrea/chess_engine/chessboard"
 )
 
+// precomputedJSON is the layout of the precomputed tables exported for the UI,
+// with the attacks of the sliding pieces indexed by square and magic key
+type precomputedJSON struct {
+	KingMoves               [64]Bitboard
+	KnightMoves             [64]Bitboard
+	RookMagics              [64]uint64
+	RookIndexBits           [64]int
+	RookMasks               [64]Bitboard
+	RookMoves               [64][4096]Bitboard
+	BishopMagics            [64]uint64
+	BishopIndexBits         [64]int
+	BishopMasks             [64]Bitboard
+	BishopMoves             [64][1024]Bitboard
+	DoublePawnsForwardMasks [64]Bitboard
+	DoublePawnsSidesMasks   [64]Bitboard
+	PassedPawnWhiteMasks    [64]Bitboard
+	PassedPawnBlackMasks    [64]Bitboard
+	KPKBitbase              [kpkPositions / 64]uint64
+}
+
 func main() {
+	searchMagics := flag.Bool("magics", false, "search new magic numbers and print them instead of writing the tables")
+	reduce := flag.Int("reduce", 0, "index bits the magic search tries to remove from each square")
+	attempts := flag.Int("attempts", 1_000_000, "candidates tried for each square and number of index bits")
+	seed := flag.Int64("seed", 1, "seed of the random magic candidates")
+	binaryFormat := flag.Bool("binary", false, "write the tables in the binary format embedded by the chessboard package instead of the JSON used by the UI")
+	output := flag.String("output", "", "path of the generated file (default ../ui/public/precomputed.json, or ../chessboard/precomputed.bin with -binary)")
+	flag.Parse()
+
+	if *searchMagics {
+		if *reduce < 0 || *reduce >= minBlockers || *attempts <= 0 {
+			fmt.Fprintf(os.Stderr, "-reduce must be between 0 and %d and -attempts must be positive\n", minBlockers-1)
+			flag.Usage()
+			os.Exit(2)
+		}
+
+		printMagics(*reduce, *attempts, *seed)
+		return
+	}
+
 	kingMoves := generateKing()
 	knightMoves := generateKnight()
 
-	// Magics from https://github.com/GunshipPenguin/shallow-blue/
-	rookMagics := [64]uint64{0xa8002c000108020, 0x6c00049b0002001, 0x100200010090040, 0x2480041000800801, 0x280028004000800, 0x900410008040022, 0x280020001001080, 0x2880002041000080, 0xa000800080400034, 0x4808020004000, 0x2290802004801000, 0x411000d00100020, 0x402800800040080, 0xb000401004208, 0x2409000100040200, 0x1002100004082, 0x22878001e24000, 0x1090810021004010, 0x801030040200012, 0x500808008001000, 0xa08018014000880, 0x8000808004000200, 0x201008080010200, 0x801020000441091, 0x800080204005, 0x1040200040100048, 0x120200402082, 0xd14880480100080, 0x12040280080080, 0x100040080020080, 0x9020010080800200, 0x813241200148449, 0x491604001800080, 0x100401000402001, 0x4820010021001040, 0x400402202000812, 0x209009005000802, 0x810800601800400, 0x4301083214000150, 0x204026458e001401, 0x40204000808000, 0x8001008040010020, 0x8410820820420010, 0x1003001000090020, 0x804040008008080, 0x12000810020004, 0x1000100200040208, 0x430000a044020001, 0x280009023410300, 0xe0100040002240, 0x200100401700, 0x2244100408008080, 0x8000400801980, 0x2000810040200, 0x8010100228810400, 0x2000009044210200, 0x4080008040102101, 0x40002080411d01, 0x2005524060000901, 0x502001008400422, 0x489a000810200402, 0x1004400080a13, 0x4000011008020084, 0x26002114058042}
-	bishopMagics := [64]uint64{0x89a1121896040240, 0x2004844802002010, 0x2068080051921000, 0x62880a0220200808, 0x4042004000000, 0x100822020200011, 0xc00444222012000a, 0x28808801216001, 0x400492088408100, 0x201c401040c0084, 0x840800910a0010, 0x82080240060, 0x2000840504006000, 0x30010c4108405004, 0x1008005410080802, 0x8144042209100900, 0x208081020014400, 0x4800201208ca00, 0xf18140408012008, 0x1004002802102001, 0x841000820080811, 0x40200200a42008, 0x800054042000, 0x88010400410c9000, 0x520040470104290, 0x1004040051500081, 0x2002081833080021, 0x400c00c010142, 0x941408200c002000, 0x658810000806011, 0x188071040440a00, 0x4800404002011c00, 0x104442040404200, 0x511080202091021, 0x4022401120400, 0x80c0040400080120, 0x8040010040820802, 0x480810700020090, 0x102008e00040242, 0x809005202050100, 0x8002024220104080, 0x431008804142000, 0x19001802081400, 0x200014208040080, 0x3308082008200100, 0x41010500040c020, 0x4012020c04210308, 0x208220a202004080, 0x111040120082000, 0x6803040141280a00, 0x2101004202410000, 0x8200000041108022, 0x21082088000, 0x2410204010040, 0x40100400809000, 0x822088220820214, 0x40808090012004, 0x910224040218c9, 0x402814422015008, 0x90014004842410, 0x1000042304105, 0x10008830412a00, 0x2520081090008908, 0x40102000a0a60140}
-	rookIndexBits := [64]int{12, 11, 11, 11, 11, 11, 11, 12, 11, 10, 10, 10, 10, 10, 10, 11, 11, 10, 10, 10, 10, 10, 10, 11, 11, 10, 10, 10, 10, 10, 10, 11, 11, 10, 10, 10, 10, 10, 10, 11, 11, 10, 10, 10, 10, 10, 10, 11, 11, 10, 10, 10, 10, 10, 10, 11, 12, 11, 11, 11, 11, 11, 11, 12}
-	bishopIndexBits := [64]int{6, 5, 5, 5, 5, 5, 5, 6, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 7, 7, 7, 7, 5, 5, 5, 5, 7, 9, 9, 7, 5, 5, 5, 5, 7, 9, 9, 7, 5, 5, 5, 5, 7, 7, 7, 7, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 6, 5, 5, 5, 5, 5, 5, 6}
+	// The magics are the ones of the chessboard package, so the tables match its lookups
+	rookMagics, rookIndexBits := RookMagicNumbers, RookIndexBits
+	bishopMagics, bishopIndexBits := BishopMagicNumbers, BishopIndexBits
 
 	rookMasks := generateRookMasks()
 	rookMoves := generateRookMoves(rookMagics, rookIndexBits)
@@ -25,7 +66,37 @@ func main() {
 	forwardFileMask, sideFilesMask := generateDoubledPawnMasks()
 	whitePassedMasks, blackPassedMasks := passedPawnMasks()
 
-	byteJSON, err := json.Marshal(PrecomputedData{
+	kpkBitbase := generateKPKBitbase(kingMoves)
+
+	if *binaryFormat {
+		var rookRows, bishopRows [64][]Bitboard
+		for sq := 0; sq < 64; sq++ {
+			rookRows[sq] = rookMoves[sq][:]
+			bishopRows[sq] = bishopMoves[sq][:]
+		}
+
+		data := PrecomputedData{
+			KingMoves:               kingMoves,
+			KnightMoves:             knightMoves,
+			DoublePawnsForwardMasks: forwardFileMask,
+			DoublePawnsSidesMasks:   sideFilesMask,
+			PassedPawnWhiteMasks:    whitePassedMasks,
+			PassedPawnBlackMasks:    blackPassedMasks,
+			KPKBitbase:              kpkBitbase,
+		}
+		data.RookMagics, data.RookMoves = compactMoves(rookMasks, rookMagics, rookIndexBits, rookRows)
+		data.BishopMagics, data.BishopMoves = compactMoves(bishopMasks, bishopMagics, bishopIndexBits, bishopRows)
+
+		if *output == "" {
+			*output = "../chessboard/precomputed.bin"
+		}
+		if err := data.Save(*output); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	byteJSON, err := json.Marshal(precomputedJSON{
 		KingMoves:               kingMoves,
 		KnightMoves:             knightMoves,
 		RookMagics:              rookMagics,
@@ -40,14 +111,54 @@ func main() {
 		DoublePawnsSidesMasks:   sideFilesMask,
 		PassedPawnWhiteMasks:    whitePassedMasks,
 		PassedPawnBlackMasks:    blackPassedMasks,
+		KPKBitbase:              kpkBitbase,
 	})
 
 	if err != nil {
 		panic(err)
 	}
 
-	err = ioutil.WriteFile("./precomputed.json", byteJSON, 0644)
+	if *output == "" {
+		*output = "../ui/public/precomputed.json"
+	}
+	err = ioutil.WriteFile(*output, byteJSON, 0644)
 	if err != nil {
 		panic(err)
 	}
 }
+
+// compactMoves stores the moves of all the squares one after the other, keeping only the keys used
+// by the index bits of each square, and returns the magics of the chessboard package indexing them
+func compactMoves(masks [64]Bitboard, magics [64]uint64, indexBits [64]int, moves [64][]Bitboard) ([64]Magic, []Bitboard) {
+	var compactMagics [64]Magic
+	table := []Bitboard{}
+
+	for sq := 0; sq < 64; sq++ {
+		compactMagics[sq] = Magic{
+			Mask:   masks[sq],
+			Number: magics[sq],
+			Shift:  uint8(64 - indexBits[sq]),
+			Offset: uint32(len(table)),
+		}
+		table = append(table, moves[sq][:1<<indexBits[sq]]...)
+	}
+
+	return compactMagics, table
+}
+
+// printMagics searches the magic numbers of rooks and bishops, verifies them on all the combinations
+// of blockers with the ray generators and prints them in the format of the chessboard package
+func printMagics(reduce int, attempts int, seed int64) {
+	random := rand.New(rand.NewSource(seed))
+
+	rookMagics, rookIndexBits := findMagics(rookBlockersAndMoves, reduce, attempts, random)
+	bishopMagics, bishopIndexBits := findMagics(bishopBlockersAndMoves, reduce, attempts, random)
+
+	// Filling the tables panics if a magic maps two different sets of moves to the same key
+	generateRookMoves(rookMagics, rookIndexBits)
+	generateBishopMoves(bishopMagics, bishopIndexBits)
+
+	fmt.Fprintf(os.Stderr, "Rook table: %d entries, bishop table: %d entries\n", tableSize(rookIndexBits), tableSize(bishopIndexBits))
+	fmt.Println(magicsSource("Rook", rookMagics, rookIndexBits))
+	fmt.Print(magicsSource("Bishop", bishopMagics, bishopIndexBits))
+}
@@ -63,6 +63,22 @@ func generateRookMoves(rookMagics [64]uint64, rookIndexBits [64]int) [64][4096]B
 func fillRookMovesSquare(file int, rank int, rookMagics [64]uint64, rookIndexBits [64]int, rookMoves *[64][4096]Bitboard) {
 	square := file + rank*8
 
+	blockerSets, movesSets := rookBlockersAndMoves(file, rank)
+	for i, blockers := range blockerSets {
+		moves := movesSets[i]
+
+		key := (uint64(blockers) * rookMagics[square]) >> (64 - rookIndexBits[square])
+		if rookMoves[square][key] != 0 && rookMoves[square][key] != moves {
+			panic(fmt.Sprintf("Invalid magic number for square %d", square))
+		}
+
+		rookMoves[square][key] = moves
+	}
+}
+
+// rookBlockersAndMoves returns all the combinations of blockers of a rook on the square
+// together with the moves allowed by each combination
+func rookBlockersAndMoves(file int, rank int) ([]Bitboard, []Bitboard) {
 	// fill choices with all the squares that can be blockers
 	choices := []Bitboard{}
 
@@ -86,6 +102,8 @@ func fillRookMovesSquare(file int, rank int, rookMagics [64]uint64, rookIndexBit
 	// All the combinations of blocked squares can be iterated by
 	// counting up to 2^len(choices) and parsing the bits as blocked squares
 	combinations := 1 << len(choices)
+	blockerSets := make([]Bitboard, 0, combinations)
+	movesSets := make([]Bitboard, 0, combinations)
 	for blockedPieces := int(0); blockedPieces < combinations; blockedPieces++ {
 		blockers := Bitboard(0)
 
@@ -140,11 +158,9 @@ func fillRookMovesSquare(file int, rank int, rookMagics [64]uint64, rookIndexBit
 			}
 		}
 
-		key := (uint64(blockers) * rookMagics[square]) >> (64 - rookIndexBits[square])
-		if rookMoves[square][key] != 0 && rookMoves[square][key] != moves {
-			panic(fmt.Sprintf("Invalid magic number for square %d", square))
-		}
-
-		rookMoves[square][key] = moves
+		blockerSets = append(blockerSets, blockers)
+		movesSets = append(movesSets, moves)
 	}
+
+	return blockerSets, movesSets
 }
@@ -0,0 +1,88 @@
+package chessboard
+
+// Attacker is a piece attacking a square
+type Attacker struct {
+	Square Square
+	Piece  Piece
+}
+
+// AttackMap returns the number of pieces of the passed color attacking each square,
+// the sliding pieces are blocked by the pieces of both colors
+func (pos *Position) AttackMap(color Color) [64]int {
+	attackMap := [64]int{}
+	for sq := Square(0); sq < 64; sq++ {
+		attackMap[sq] = pos.AttackersTo(sq, color).PopCount()
+	}
+
+	return attackMap
+}
+
+// Attackers returns the pieces of both colors attacking the square, the white ones first
+func (pos *Position) Attackers(sq Square) []Attacker {
+	attackers := []Attacker{}
+	for _, color := range []Color{WhiteColor, BlackColor} {
+		for _, from := range pos.AttackersTo(sq, color).Squares() {
+			attackers = append(attackers, Attacker{from, pos.board.Piece(from)})
+		}
+	}
+
+	return attackers
+}
+
+// Pinned returns the pieces of the passed color which can't leave the line between
+// their king and an enemy sliding piece without exposing the king
+func (pos *Position) Pinned(color Color) Bitboard {
+	if pos.board.Pieces(color, King) == 0 {
+		return 0
+	}
+
+	king := pos.board.kingSquare(color)
+	return pos.sliderBlockers(king, pos.board.sidePieces(color.Other())) & pos.board.sidePieces(color).all
+}
+
+// DiscoveredAttackers returns the pieces of the passed color which give a discovered check when they
+// move away from the line between a friendly sliding piece and the enemy king
+func (pos *Position) DiscoveredAttackers(color Color) Bitboard {
+	if pos.board.Pieces(color.Other(), King) == 0 {
+		return 0
+	}
+
+	king := pos.board.kingSquare(color.Other())
+	return pos.sliderBlockers(king, pos.board.sidePieces(color)) & pos.board.sidePieces(color).all
+}
+
+// sliderBlockers returns the pieces which are the only piece between the square and one of the sliding
+// pieces in snipers, looking for rooks and queens on the lines and bishops and queens on the diagonals
+func (pos *Position) sliderBlockers(sq Square, snipers sidePieces) Bitboard {
+	precomputedData := pos.precomputedData
+	occupied := ^pos.board.emptySquares
+
+	candidates := rookAttacks(precomputedData, sq, 0)&(snipers.rooks|snipers.queens) |
+		bishopAttacks(precomputedData, sq, 0)&(snipers.bishops|snipers.queens)
+
+	blockers := Bitboard(0)
+	for candidates != 0 {
+		sniper := Square(candidates.LeastSignificant1Bit())
+		candidates.ClearLeastSignificant1Bit()
+
+		between := betweenSquares(precomputedData, sq, sniper) & occupied
+		if between.PopCount() == 1 {
+			blockers |= between
+		}
+	}
+
+	return blockers
+}
+
+// betweenSquares returns the squares strictly between a and b when they are on the same
+// line or diagonal, otherwise it returns an empty bitboard
+func betweenSquares(precomputedData *PrecomputedData, a Square, b Square) Bitboard {
+	if rookAttacks(precomputedData, a, 0)&b.Bitboard() != 0 {
+		return rookAttacks(precomputedData, a, b.Bitboard()) & rookAttacks(precomputedData, b, a.Bitboard())
+	}
+	if bishopAttacks(precomputedData, a, 0)&b.Bitboard() != 0 {
+		return bishopAttacks(precomputedData, a, b.Bitboard()) & bishopAttacks(precomputedData, b, a.Bitboard())
+	}
+
+	return 0
+}
@@ -0,0 +1,66 @@
+package chessboard
+
+import "testing"
+
+func TestAttackMap(t *testing.T) {
+	game := NewGame()
+	pos := game.Position()
+	whiteMap := pos.AttackMap(WhiteColor)
+	blackMap := pos.AttackMap(BlackColor)
+
+	var tests = []struct {
+		sq    Square
+		white int
+		black int
+	}{
+		{F3, 3, 0},
+		{D3, 2, 0},
+		{E4, 0, 0},
+		{C6, 0, 3},
+		{E2, 4, 0},
+	}
+
+	for _, test := range tests {
+		if whiteMap[test.sq] != test.white || blackMap[test.sq] != test.black {
+			t.Errorf("%s should be attacked by %d white and %d black pieces, %d and %d were returned instead",
+				test.sq, test.white, test.black, whiteMap[test.sq], blackMap[test.sq])
+		}
+	}
+}
+
+func TestAttackersPinsAndDiscoveredAttacks(t *testing.T) {
+	// The knight on d2 and the pawn on e2 are pinned, the knight on f7 blocks the bishop on h5
+	game := NewGameFromFEN("4k3/4rN2/8/7B/1b6/8/3NP3/4K3 w - - 0 1")
+	pos := game.Position()
+
+	expected := []Attacker{{H5, WhiteBishop}, {E7, BlackRook}, {E8, BlackKing}}
+	attackers := pos.Attackers(F7)
+	if len(attackers) != len(expected) {
+		t.Fatalf("f7 should be attacked by %v, %v was returned instead", expected, attackers)
+	}
+	for i := range expected {
+		if attackers[i] != expected[i] {
+			t.Errorf("f7 should be attacked by %v, %v was returned instead", expected, attackers)
+		}
+	}
+
+	if pinned := pos.Pinned(WhiteColor); pinned != D2.Bitboard()|E2.Bitboard() {
+		t.Errorf("The pieces on d2 and e2 should be pinned, %v was returned instead", pinned)
+	}
+	if pinned := pos.Pinned(BlackColor); pinned != 0 {
+		t.Errorf("No black piece should be pinned, %v was returned instead", pinned)
+	}
+	if discovered := pos.DiscoveredAttackers(WhiteColor); discovered != F7.Bitboard() {
+		t.Errorf("The knight on f7 should give a discovered check, %v was returned instead", discovered)
+	}
+	if discovered := pos.DiscoveredAttackers(BlackColor); discovered != 0 {
+		t.Errorf("No black piece should give a discovered check, %v was returned instead", discovered)
+	}
+
+	// Two pieces between the king and the slider don't make a pin
+	game = NewGameFromFEN("4k3/8/8/8/1b6/2P5/3N4/4K3 w - - 0 1")
+	pos = game.Position()
+	if pinned := pos.Pinned(WhiteColor); pinned != 0 {
+		t.Errorf("No white piece should be pinned, %v was returned instead", pinned)
+	}
+}
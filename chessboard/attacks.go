@@ -0,0 +1,145 @@
+package chessboard
+
+const fileABitboard Bitboard = 0x0101010101010101
+const fileHBitboard Bitboard = fileABitboard << 7
+const rank1Bitboard Bitboard = 0xFF
+
+// lightSquaresBitboard contains all the light squares of the board (A1 is dark)
+const lightSquaresBitboard Bitboard = 0x55AA55AA55AA55AA
+
+// fileBitboard returns the bitboard of the file containing the square
+func fileBitboard(sq Square) Bitboard {
+	return fileABitboard << (sq % 8)
+}
+
+// rankBitboard returns the bitboard of the passed rank (0=1 1=2 ... 7=8)
+func rankBitboard(rank int) Bitboard {
+	return rank1Bitboard << (8 * rank)
+}
+
+// relativeRank returns the rank of the square from the point of view of the passed color,
+// e.g. the rank where pawns promote is always 7
+func relativeRank(sq Square, color Color) int {
+	if color == WhiteColor {
+		return int(sq / 8)
+	}
+
+	return 7 - int(sq/8)
+}
+
+// forward shifts all the squares of the bitboard one rank towards the opponent of the passed color
+func forward(bb Bitboard, color Color) Bitboard {
+	if color == WhiteColor {
+		return bb << 8
+	}
+
+	return bb >> 8
+}
+
+// adjacentSquares returns the squares next to the passed one, which are the squares attacked by a king
+func adjacentSquares(sq Square) Bitboard {
+	bb := sq.Bitboard()
+	row := bb | (bb&^fileABitboard)>>1 | (bb&^fileHBitboard)<<1
+
+	return (row | row<<8 | row>>8) &^ bb
+}
+
+// rookAttacks returns the squares attacked by a rook on the passed square
+func rookAttacks(precomputedData *PrecomputedData, sq Square, occupied Bitboard) Bitboard {
+	return precomputedData.RookMoves[precomputedData.RookMagics[sq].index(occupied)]
+}
+
+// bishopAttacks returns the squares attacked by a bishop on the passed square
+func bishopAttacks(precomputedData *PrecomputedData, sq Square, occupied Bitboard) Bitboard {
+	return precomputedData.BishopMoves[precomputedData.BishopMagics[sq].index(occupied)]
+}
+
+// pawnAttacks returns the squares attacked by the passed pawns of the given color
+func pawnAttacks(pawns Bitboard, color Color) Bitboard {
+	if color == WhiteColor {
+		return (pawns&^fileABitboard)<<7 | (pawns&^fileHBitboard)<<9
+	}
+
+	return (pawns&^fileABitboard)>>9 | (pawns&^fileHBitboard)>>7
+}
+
+// sidePieces contains the bitboards of the pieces of a single color
+type sidePieces struct {
+	king    Bitboard
+	queens  Bitboard
+	rooks   Bitboard
+	bishops Bitboard
+	knights Bitboard
+	pawns   Bitboard
+	all     Bitboard
+}
+
+// sidePieces returns the bitboards of the pieces of the passed color
+func (b *Board) sidePieces(color Color) sidePieces {
+	if color == WhiteColor {
+		return sidePieces{b.bbWhiteKing, b.bbWhiteQueen, b.bbWhiteRook, b.bbWhiteBishop, b.bbWhiteKnight, b.bbWhitePawn, b.whiteSquares}
+	}
+
+	return sidePieces{b.bbBlackKing, b.bbBlackQueen, b.bbBlackRook, b.bbBlackBishop, b.bbBlackKnight, b.bbBlackPawn, b.blackSquares}
+}
+
+// Pieces returns the bitboard of the pieces of the passed color and kind
+func (b *Board) Pieces(color Color, kind PieceKind) Bitboard {
+	pieces := b.sidePieces(color)
+
+	switch kind {
+	case King:
+		return pieces.king
+	case Queen:
+		return pieces.queens
+	case Rook:
+		return pieces.rooks
+	case Bishop:
+		return pieces.bishops
+	case Knight:
+		return pieces.knights
+	case Pawn:
+		return pieces.pawns
+	default:
+		panic("Unrecognized piece kind")
+	}
+}
+
+// attackersTo returns the pieces of the passed color attacking the square,
+// the sliding pieces are blocked by the occupied squares
+func (b *Board) attackersTo(precomputedData *PrecomputedData, sq Square, color Color, occupied Bitboard) Bitboard {
+	pieces := b.sidePieces(color)
+
+	return precomputedData.KingMoves[sq]&pieces.king |
+		precomputedData.KnightMoves[sq]&pieces.knights |
+		rookAttacks(precomputedData, sq, occupied)&(pieces.rooks|pieces.queens) |
+		bishopAttacks(precomputedData, sq, occupied)&(pieces.bishops|pieces.queens) |
+		pawnAttacks(sq.Bitboard(), color.Other())&pieces.pawns
+}
+
+// kingSquare returns the square of the king of the passed color
+func (b *Board) kingSquare(color Color) Square {
+	if color == WhiteColor {
+		return b.whiteKingSquare
+	}
+
+	return b.blackKingSquare
+}
+
+// squareDistance returns the number of king moves needed to go from a square to the other
+func squareDistance(a Square, b Square) int {
+	fileDistance := int(a%8) - int(b%8)
+	if fileDistance < 0 {
+		fileDistance = -fileDistance
+	}
+	rankDistance := int(a/8) - int(b/8)
+	if rankDistance < 0 {
+		rankDistance = -rankDistance
+	}
+
+	if fileDistance > rankDistance {
+		return fileDistance
+	}
+
+	return rankDistance
+}
@@ -63,8 +63,19 @@ func (b *Bitboard) ClearLeastSignificant1Bit() {
 	*b = *b & (*b - 1)
 }
 
+// Squares returns the 1-bits of the bitboard as squares, from A1 to H8
+func (b Bitboard) Squares() []Square {
+	squares := make([]Square, 0, b.PopCount())
+	for b != 0 {
+		squares = append(squares, Square(b.LeastSignificant1Bit()))
+		b.ClearLeastSignificant1Bit()
+	}
+
+	return squares
+}
+
 // IsSquareOccupied returns whether the passed square is a 1-bit in the bitboard
-func (b *Bitboard) IsSquareOccupied(sq square) bool {
+func (b *Bitboard) IsSquareOccupied(sq Square) bool {
 	return (*b)&sq.Bitboard() != 0
 }
 
@@ -73,7 +84,7 @@ func (b Bitboard) String() string {
 
 	for r := 7; r >= 0; r-- {
 		for f := 0; f < 8; f++ {
-			squareBB := (square(f + r*8)).Bitboard()
+			squareBB := (Square(f + r*8)).Bitboard()
 			if b&squareBB != 0 {
 				s += "1 "
 			} else {
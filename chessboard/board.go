@@ -19,8 +19,10 @@ type Board struct {
 	whiteSquares    Bitboard
 	blackSquares    Bitboard
 	emptySquares    Bitboard
-	whiteKingSquare square
-	blackKingSquare square
+	whiteKingSquare Square
+	blackKingSquare Square
+	// explosions makes the captures explode the pieces around them as in atomic chess
+	explosions bool
 }
 
 // FillSupportBitboards computes and sets the support bitboard in the Board
@@ -30,12 +32,12 @@ func (b *Board) FillSupportBitboards() {
 	b.blackSquares = b.bbBlackKing | b.bbBlackQueen | b.bbBlackRook |
 		b.bbBlackBishop | b.bbBlackKnight | b.bbBlackPawn
 	b.emptySquares = ^(b.whiteSquares | b.blackSquares)
-	b.whiteKingSquare = square(b.bbWhiteKing.LeastSignificant1Bit())
-	b.blackKingSquare = square(b.bbBlackKing.LeastSignificant1Bit())
+	b.whiteKingSquare = Square(b.bbWhiteKing.LeastSignificant1Bit())
+	b.blackKingSquare = Square(b.bbBlackKing.LeastSignificant1Bit())
 }
 
 // Piece returns the piece in a given square of the board
-func (b *Board) Piece(s square) Piece {
+func (b *Board) Piece(s Square) Piece {
 	bbSquare := s.Bitboard()
 	switch {
 	case b.bbWhiteKing&bbSquare != 0:
@@ -72,7 +74,7 @@ func (b Board) String() string {
 	for r := 7; r >= 0; r-- {
 		s += fmt.Sprintf("%d ", r+1)
 		for f := 0; f < 8; f++ {
-			s += b.Piece(square(f+r*8)).String() + " "
+			s += b.Piece(Square(f+r*8)).String() + " "
 		}
 		s += "\n"
 	}
@@ -81,10 +83,64 @@ func (b Board) String() string {
 	return s
 }
 
+// castle moves the king and the rook of a castling move. The king and the rook are removed
+// before being placed, because in Chess960 they can end on each other's starting square.
+// Returns the update to the zobrist hash
+func (b *Board) castle(move *Move) ZobristHash {
+	king, rook := WhiteKing, WhiteRook
+	kings, rooks, own := &b.bbWhiteKing, &b.bbWhiteRook, &b.whiteSquares
+	if *move&(BlackKingCastleFlag|BlackQueenCastleFlag) != 0 {
+		king, rook = BlackKing, BlackRook
+		kings, rooks, own = &b.bbBlackKing, &b.bbBlackRook, &b.blackSquares
+	}
+
+	kingFrom, kingTo, rookFrom := move.From(), move.To(), move.CastleRook()
+	// The rook ends next to the king, on the F or D file
+	rookTo := kingTo - 1
+	if *move&(WhiteQueenCastleFlag|BlackQueenCastleFlag) != 0 {
+		rookTo = kingTo + 1
+	}
+
+	removed := kingFrom.Bitboard() | rookFrom.Bitboard()
+	added := kingTo.Bitboard() | rookTo.Bitboard()
+	*kings = *kings&^kingFrom.Bitboard() | kingTo.Bitboard()
+	*rooks = *rooks&^rookFrom.Bitboard() | rookTo.Bitboard()
+	*own = *own&^removed | added
+	b.emptySquares = (b.emptySquares | removed) &^ added
+
+	if king == WhiteKing {
+		b.whiteKingSquare = kingTo
+	} else {
+		b.blackKingSquare = kingTo
+	}
+
+	return zobristHashMoves[king-1][kingFrom] ^ zobristHashMoves[king-1][kingTo] ^
+		zobristHashMoves[rook-1][rookFrom] ^ zobristHashMoves[rook-1][rookTo]
+}
+
+// removeCastlingRook removes the rook of the side to move in the square,
+// used to check the attacks on the path of a castling king
+func (b *Board) removeCastlingRook(sq Square) {
+	bb := sq.Bitboard()
+	b.bbWhiteRook &^= bb
+	b.bbBlackRook &^= bb
+	b.whiteSquares &^= bb
+	b.blackSquares &^= bb
+	b.emptySquares |= bb
+}
+
 // Move updates the boarding moving the piece in the starting square to the target square
 // it also captures the square in the target square if needed.
 // Returns the update to the zobrist hash
 func (b *Board) Move(move *Move) ZobristHash {
+	if move.IsCastle() {
+		return b.castle(move)
+	}
+	if move.IsDrop() {
+		return b.drop(move)
+	}
+
+	explosion := b.isExplosion(move)
 	var piece Piece
 	var hash ZobristHash
 
@@ -166,67 +222,164 @@ func (b *Board) Move(move *Move) ZobristHash {
 		b.whiteSquares = b.whiteSquares & (^toBB)
 	}
 
-	// Move rook in castling
-	if move.IsCastle() {
-		if *move&WhiteKingCastleFlag != 0 {
-			b.bbWhiteRook |= F1.Bitboard()
-			b.bbWhiteRook ^= H1.Bitboard()
+	// capture en passant pawn
+	if move.IsEnPassant() {
+		if *move&WhiteEnPassantFlag != 0 {
+			blackPawnPosition := (move.To() - 8).Bitboard()
 
-			b.whiteSquares |= F1.Bitboard()
-			b.whiteSquares ^= H1.Bitboard()
+			b.bbBlackPawn ^= blackPawnPosition
+			b.blackSquares ^= blackPawnPosition
+			b.emptySquares |= blackPawnPosition
+		} else {
+			whitePawnPosition := (move.To() + 8).Bitboard()
 
-			b.emptySquares ^= F1.Bitboard()
-			b.emptySquares |= H1.Bitboard()
+			b.bbWhitePawn ^= whitePawnPosition
+			b.whiteSquares ^= whitePawnPosition
+			b.emptySquares |= whitePawnPosition
+		}
+	}
 
-			hash ^= zobristHashMoves[WhiteRook-1][F1] ^ zobristHashMoves[WhiteRook-1][H1]
-		} else if *move&WhiteQueenCastleFlag != 0 {
-			b.bbWhiteRook |= D1.Bitboard()
-			b.bbWhiteRook ^= A1.Bitboard()
+	if explosion {
+		hash ^= b.explode(move.To())
+	}
 
-			b.whiteSquares |= D1.Bitboard()
-			b.whiteSquares ^= A1.Bitboard()
+	return hash
+}
+
+// isExplosion returns whether the move is a capture exploding the pieces around it,
+// it must be called before applying the move to the board
+func (b *Board) isExplosion(move *Move) bool {
+	if !b.explosions || move.IsCastle() || move.IsDrop() {
+		return false
+	}
 
-			b.emptySquares ^= D1.Bitboard()
-			b.emptySquares |= A1.Bitboard()
+	return move.IsEnPassant() || b.Piece(move.To()) != NoPiece
+}
+
+// explode removes the capturing piece in the passed square and all the pieces except the pawns
+// next to it, as happens after a capture in atomic chess. Returns the update to the zobrist hash
+func (b *Board) explode(center Square) ZobristHash {
+	var hash ZobristHash
 
-			hash ^= zobristHashMoves[WhiteRook-1][D1] ^ zobristHashMoves[WhiteRook-1][A1]
-		} else if *move&BlackKingCastleFlag != 0 {
-			b.bbBlackRook |= F8.Bitboard()
-			b.bbBlackRook ^= H8.Bitboard()
+	blast := (adjacentSquares(center)&^(b.bbWhitePawn|b.bbBlackPawn) | center.Bitboard()) &^ b.emptySquares
+	for exploded := blast; exploded != 0; exploded.ClearLeastSignificant1Bit() {
+		sq := Square(exploded.LeastSignificant1Bit())
+		hash ^= zobristHashMoves[b.Piece(sq)-1][sq]
+	}
 
-			b.blackSquares |= F8.Bitboard()
-			b.blackSquares ^= H8.Bitboard()
+	b.bbWhiteKing &^= blast
+	b.bbWhiteQueen &^= blast
+	b.bbWhiteRook &^= blast
+	b.bbWhiteBishop &^= blast
+	b.bbWhiteKnight &^= blast
+	b.bbWhitePawn &^= blast
+	b.bbBlackKing &^= blast
+	b.bbBlackQueen &^= blast
+	b.bbBlackRook &^= blast
+	b.bbBlackBishop &^= blast
+	b.bbBlackKnight &^= blast
+	b.bbBlackPawn &^= blast
+	b.FillSupportBitboards()
 
-			b.emptySquares ^= F8.Bitboard()
-			b.emptySquares |= H8.Bitboard()
-			hash ^= zobristHashMoves[BlackRook-1][F8] ^ zobristHashMoves[BlackRook-1][H8]
-		} else if *move&BlackQueenCastleFlag != 0 {
-			b.bbBlackRook |= D8.Bitboard()
-			b.bbBlackRook ^= A8.Bitboard()
+	return hash
+}
 
-			b.blackSquares |= D8.Bitboard()
-			b.blackSquares ^= A8.Bitboard()
+// drop places the piece of a crazyhouse drop on its empty square.
+// Returns the update to the zobrist hash
+func (b *Board) drop(move *Move) ZobristHash {
+	piece := move.DropPiece()
+	toBB := move.To().Bitboard()
 
-			b.emptySquares ^= D8.Bitboard()
-			b.emptySquares |= A8.Bitboard()
-			hash ^= zobristHashMoves[BlackRook-1][D8] ^ zobristHashMoves[BlackRook-1][A8]
+	switch piece {
+	case WhiteQueen:
+		b.bbWhiteQueen |= toBB
+	case WhiteRook:
+		b.bbWhiteRook |= toBB
+	case WhiteBishop:
+		b.bbWhiteBishop |= toBB
+	case WhiteKnight:
+		b.bbWhiteKnight |= toBB
+	case WhitePawn:
+		b.bbWhitePawn |= toBB
+	case BlackQueen:
+		b.bbBlackQueen |= toBB
+	case BlackRook:
+		b.bbBlackRook |= toBB
+	case BlackBishop:
+		b.bbBlackBishop |= toBB
+	case BlackKnight:
+		b.bbBlackKnight |= toBB
+	case BlackPawn:
+		b.bbBlackPawn |= toBB
+	default:
+		panic("Invalid dropped piece")
+	}
+
+	b.emptySquares &^= toBB
+	if piece.Color() == WhiteColor {
+		b.whiteSquares |= toBB
+	} else {
+		b.blackSquares |= toBB
+	}
+
+	return zobristHashMoves[piece-1][move.To()]
+}
+
+// pawnHashUpdate returns the update to the pawn zobrist hash caused by the move,
+// it must be called before applying the move to the board
+func (b *Board) pawnHashUpdate(move *Move) ZobristHash {
+	var hash ZobristHash
+
+	if move.IsDrop() {
+		if piece := move.DropPiece(); piece == WhitePawn || piece == BlackPawn {
+			hash ^= zobristHashMoves[piece-1][move.To()]
 		}
+
+		return hash
+	}
+
+	piece := b.Piece(move.From())
+	if piece == WhitePawn || piece == BlackPawn {
+		hash ^= zobristHashMoves[piece-1][move.From()]
+		// The capturing pawn explodes in atomic chess, while the pawns around it survive
+		if move.Promotion() == NoPiece && !b.isExplosion(move) {
+			hash ^= zobristHashMoves[piece-1][move.To()]
+		}
+	}
+
+	targetPiece := b.Piece(move.To())
+	if targetPiece == WhitePawn || targetPiece == BlackPawn {
+		hash ^= zobristHashMoves[targetPiece-1][move.To()]
 	}
 
-	// capture en passant pawn
 	if move.IsEnPassant() {
 		if *move&WhiteEnPassantFlag != 0 {
-			blackPawnPosition := (move.To() - 8).Bitboard()
+			hash ^= zobristHashMoves[BlackPawn-1][move.To()-8]
+		} else {
+			hash ^= zobristHashMoves[WhitePawn-1][move.To()+8]
+		}
+	}
 
-			b.bbBlackPawn ^= blackPawnPosition
-			b.blackSquares ^= blackPawnPosition
-			b.emptySquares |= blackPawnPosition
+	return hash
+}
+
+// pawnHash computes from scratch the zobrist hash of the pawns on the board
+func (b *Board) pawnHash() ZobristHash {
+	var hash ZobristHash
+
+	for _, piece := range []Piece{WhitePawn, BlackPawn} {
+		var pawns Bitboard
+		if piece == WhitePawn {
+			pawns = b.bbWhitePawn
 		} else {
-			whitePawnPosition := (move.To() + 8).Bitboard()
+			pawns = b.bbBlackPawn
+		}
 
-			b.bbWhitePawn ^= whitePawnPosition
-			b.whiteSquares ^= whitePawnPosition
-			b.emptySquares |= whitePawnPosition
+		for pawns != 0 {
+			sq := pawns.LeastSignificant1Bit()
+			pawns.ClearLeastSignificant1Bit()
+
+			hash ^= zobristHashMoves[piece-1][sq]
 		}
 	}
 
@@ -234,7 +387,7 @@ func (b *Board) Move(move *Move) ZobristHash {
 }
 
 // IsUnderAttack returns whether the current board is in check
-func (board *Board) IsUnderAttack(precomputedData *PrecomputedData, turn Color, sq square) bool {
+func (board *Board) IsUnderAttack(precomputedData *PrecomputedData, turn Color, sq Square) bool {
 	var enemyKnights Bitboard
 	var enemyBishopLikes Bitboard
 	var enemyRookLikes Bitboard
@@ -253,7 +406,13 @@ func (board *Board) IsUnderAttack(precomputedData *PrecomputedData, turn Color,
 	}
 
 	kingCollisions := precomputedData.KingMoves[sq] & enemyKing
-	if kingCollisions != 0 {
+	if board.explosions {
+		// In atomic chess the kings can't capture, because they would explode, and a king next
+		// to the enemy one can't be attacked because its capture would explode both kings
+		if kingCollisions != 0 {
+			return false
+		}
+	} else if kingCollisions != 0 {
 		return true
 	}
 
@@ -265,17 +424,13 @@ func (board *Board) IsUnderAttack(precomputedData *PrecomputedData, turn Color,
 	}
 
 	// Simulate rook and queens moving horizontally/vertically
-	blockers := (^board.emptySquares) & precomputedData.RookMasks[sq]
-	key := (uint64(blockers) * precomputedData.RookMagics[sq]) >> (64 - precomputedData.RookIndexBits[sq])
-	rookCollisions := precomputedData.RookMoves[sq][key] & enemyRookLikes
+	rookCollisions := rookAttacks(precomputedData, sq, ^board.emptySquares) & enemyRookLikes
 	if rookCollisions != 0 {
 		return true
 	}
 
 	// Simulate bishop and queens moving diagonally
-	blockers = (^board.emptySquares) & precomputedData.BishopMasks[sq]
-	key = (uint64(blockers) * precomputedData.BishopMagics[sq]) >> (64 - precomputedData.BishopIndexBits[sq])
-	bishopCollisions := precomputedData.BishopMoves[sq][key] & enemyBishopLikes
+	bishopCollisions := bishopAttacks(precomputedData, sq, ^board.emptySquares) & enemyBishopLikes
 	if bishopCollisions != 0 {
 		return true
 	}
@@ -0,0 +1,80 @@
+package chessboard
+
+import "strings"
+
+// chess960KnightPlacements are the 10 ways to place the two knights on the 5 squares left
+// after placing the bishops and the queen, in the standard Chess960 numbering
+var chess960KnightPlacements = [10][2]int{
+	{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 2},
+	{1, 3}, {1, 4}, {2, 3}, {2, 4}, {3, 4},
+}
+
+// Chess960FEN returns the fen of the Chess960 starting position with the passed index between
+// 0 and 959, following the standard numbering in which 518 is the standard chess starting position.
+// Panics if the index is out of range
+func Chess960FEN(index int) string {
+	if index < 0 || index >= 960 {
+		panic("The Chess960 position index should be between 0 and 959")
+	}
+
+	var backRank [8]byte
+	// The light squared bishop is on the b, d, f or h file and the dark squared one on the a, c, e or g file
+	backRank[index%4*2+1] = 'b'
+	index /= 4
+	backRank[index%4*2] = 'b'
+	index /= 4
+
+	// The queen and the knights are placed on the remaining empty squares
+	placeOnEmpty := func(nth int, piece byte) {
+		for file := range backRank {
+			if backRank[file] != 0 {
+				continue
+			}
+			if nth == 0 {
+				backRank[file] = piece
+				return
+			}
+			nth--
+		}
+	}
+	placeOnEmpty(index%6, 'q')
+	index /= 6
+
+	knights := chess960KnightPlacements[index]
+	placeOnEmpty(knights[1], 'n')
+	placeOnEmpty(knights[0], 'n')
+
+	// The king is placed between the two rooks
+	placeOnEmpty(0, 'r')
+	placeOnEmpty(0, 'k')
+	placeOnEmpty(0, 'r')
+
+	black := string(backRank[:])
+	return black + "/pppppppp/8/8/8/8/PPPPPPPP/" + strings.ToUpper(black) + " w KQkq - 0 1"
+}
+
+// NewChess960Game initializes a game from the Chess960 starting position with the passed index
+func NewChess960Game(index int) Game {
+	return NewGameFromFEN(Chess960FEN(index))
+}
+
+// Perft returns the number of leaf nodes of the legal moves tree with the passed depth,
+// it's used to verify the move generation against known values
+func (game *Game) Perft(depth int) int {
+	moves := game.LegalMoves()
+	if depth <= 1 {
+		if depth <= 0 {
+			return 1
+		}
+		return len(moves)
+	}
+
+	nodes := 0
+	for _, move := range moves {
+		game.Move(move)
+		nodes += game.Perft(depth - 1)
+		game.UndoMove()
+	}
+
+	return nodes
+}
@@ -0,0 +1,137 @@
+package chessboard
+
+import "testing"
+
+func TestChess960FEN(t *testing.T) {
+	var tests = []struct {
+		index int
+		fen   string
+	}{
+		{0, "bbqnnrkr/pppppppp/8/8/8/8/PPPPPPPP/BBQNNRKR w KQkq - 0 1"},
+		{518, "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+		{959, "rkrnnqbb/pppppppp/8/8/8/8/PPPPPPPP/RKRNNQBB w KQkq - 0 1"},
+	}
+
+	for _, test := range tests {
+		if fen := Chess960FEN(test.index); fen != test.fen {
+			t.Errorf("The Chess960 position %d should be %s, %s was returned instead", test.index, test.fen, fen)
+		}
+	}
+
+	// The precomputed data is decoded once, because creating 960 games is slow
+	game := NewChess960Game(0)
+	positions := map[string]bool{}
+	for i := 0; i < 960; i++ {
+		fen := Chess960FEN(i)
+		positions[fen] = true
+
+		pos := parseFEN(fen, game.precomputedData)
+		if pos.board.bbWhiteBishop&lightSquaresBitboard == 0 || pos.board.bbWhiteBishop&^lightSquaresBitboard == 0 {
+			t.Errorf("The bishops of the Chess960 position %d should be on different colors", i)
+		}
+		king := pos.board.kingSquare(WhiteColor)
+		if pos.castlingRooks[0] < king || pos.castlingRooks[1] > king {
+			t.Errorf("The king of the Chess960 position %d should be between the rooks", i)
+		}
+		if got := pos.FEN(); got != fen {
+			t.Errorf("The fen of the Chess960 position %d should be %s, %s was returned instead", i, fen, got)
+		}
+	}
+	if len(positions) != 960 {
+		t.Errorf("There should be 960 different Chess960 positions, %d were returned instead", len(positions))
+	}
+}
+
+func TestPerft(t *testing.T) {
+	var tests = []struct {
+		fen   string
+		nodes []int
+	}{
+		// Standard chess
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []int{20, 400, 8902}},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []int{48, 2039, 97862}},
+		{"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", []int{14, 191, 2812}},
+		{"r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []int{6, 264, 9467}},
+		{"rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", []int{44, 1486, 62379}},
+		// Chess960
+		{"bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []int{21, 528, 12189}},
+		{"2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []int{21, 807, 18002}},
+		{"b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", []int{20, 479, 10471}},
+		{"qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9", []int{22, 593, 13440}},
+		{"1nbbnrkr/p1p1ppp1/3p4/1p3P1p/3Pq2P/8/PPP1P1P1/QNBBNRKR w HFhf - 0 9", []int{28, 1120, 31058}},
+		{"qnbnr1kr/ppp1b1pp/4p3/3p1p2/8/2NPP3/PPP1BPPP/QNB1R1KR w HEhe - 1 9", []int{29, 899, 26578}},
+		{"q1bnrkr1/ppppp2p/2n2p2/4b1p1/2NP4/8/PPP1PPPP/QNB1RRKB w ge - 1 9", []int{30, 860, 24566}},
+		{"qbn1brkr/ppp1p1p1/2n4p/3p1p2/P7/6PP/QPPPPP2/1BNNBRKR w HFhf - 0 9", []int{25, 635, 17054}},
+		{"qnnbbrkr/1p2ppp1/2pp3p/p7/1P5P/2NP4/P1P1PPP1/Q1NBBRKR w HFhf - 0 9", []int{24, 572, 15243}},
+		{"qn1rbbkr/ppp2p1p/1n1pp1p1/8/3P4/P6P/1PP1PPPK/QNNRBB1R w hd - 2 9", []int{28, 811, 23175}},
+	}
+
+	for _, test := range tests {
+		game := NewGameFromFEN(test.fen)
+		for depth, expected := range test.nodes {
+			if nodes := game.Perft(depth + 1); nodes != expected {
+				t.Errorf("Perft %d of %s should be %d, %d was returned instead", depth+1, test.fen, expected, nodes)
+			}
+		}
+	}
+}
+
+func TestChess960Castling(t *testing.T) {
+	var tests = []struct {
+		fen      string
+		uci      string
+		uci960   string
+		expected string
+	}{
+		// The king moves to the starting square of the rook
+		{"4k3/8/8/8/8/8/8/1R3KR1 w KQ - 0 1", "f1g1", "f1g1", "4k3/8/8/8/8/8/8/1R3RK1 b - - 1 2"},
+		// The king doesn't move
+		{"6kr/8/8/8/8/8/8/4K3 b k - 0 1", "g8g8", "g8h8", "5rk1/8/8/8/8/8/8/4K3 w - - 1 2"},
+		{"4k3/8/8/8/8/8/8/RK5R w KQ - 0 1", "b1c1", "b1a1", "4k3/8/8/8/8/8/8/2KR3R b - - 1 2"},
+		{"4k3/8/8/8/8/8/8/RK2R3 w EA - 0 1", "b1g1", "b1e1", "4k3/8/8/8/8/8/8/R4RK1 b - - 1 2"},
+		// The rook on e8 attacks the path of the king after the rook on e1 moves
+		{"rk2r3/8/8/8/8/8/8/RK2R3 w EAea - 0 1", "b1g1", "b1e1", ""},
+		// The castling rook hides the attack of the rook on a1 to the destination of the king
+		{"4k3/8/8/8/8/8/8/rR1K4 w B - 0 1", "d1c1", "d1b1", ""},
+	}
+
+	for _, test := range tests {
+		game := NewGameFromFEN(test.fen)
+		move, err := game.ParseUCIMove(test.uci960)
+		if test.expected == "" {
+			if err == nil {
+				t.Errorf("%s should not be a legal move in %s", test.uci960, test.fen)
+			}
+			continue
+		}
+		if err != nil || !move.IsCastle() {
+			t.Errorf("%s should be a castling move in %s", test.uci960, test.fen)
+			continue
+		}
+
+		if move.UCI() != test.uci || move.UCIChess960() != test.uci960 {
+			t.Errorf("The castling move should be written as %s and %s, %s and %s were returned instead",
+				test.uci, test.uci960, move.UCI(), move.UCIChess960())
+		}
+
+		hash := game.position.hash
+		game.Move(move)
+		if fen := game.position.FEN(); fen != test.expected {
+			t.Errorf("Castling in %s should lead to %s, %s was returned instead", test.fen, test.expected, fen)
+		}
+		if reparsed := NewGameFromFEN(game.position.FEN()); reparsed.position.hash != game.position.hash {
+			t.Errorf("The hash after castling in %s should match the one of the parsed fen", test.fen)
+		}
+
+		game.UndoMove()
+		if game.position.hash != hash {
+			t.Errorf("The hash after undoing the castling move in %s should be restored", test.fen)
+		}
+	}
+
+	// Inner rooks are written with their file in X-FEN
+	game := NewGameFromFEN("rk2r1r1/8/8/8/8/8/8/RK2R1R1 w EAea - 0 1")
+	if fen := game.position.FEN(); fen != "rk2r1r1/8/8/8/8/8/8/RK2R1R1 w EQeq - 0 1" {
+		t.Errorf("The castle rights should be written in X-FEN, %s was returned instead", fen)
+	}
+}
@@ -0,0 +1,383 @@
+package chessboard
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TrainingRecordSize is the size in bytes of a position in the binary training data format.
+// Each record is independent, so files can be concatenated and shuffled freely
+const TrainingRecordSize = 32
+
+// TrainingRecord is a position recorded by the self-play data generator
+type TrainingRecord struct {
+	FEN string
+	// Score is the search score from white's point of view in centipawns
+	Score int
+	// Result is the result of the game for white: 1 for a win, 0.5 for a draw and 0 for a loss
+	Result float64
+}
+
+// fenPieces contains the FEN letter of each piece, indexed by Piece
+const fenPieces = " KQRBNPkqrbnp"
+
+// packTrainingRecord encodes a position in the binary training data format, all the values
+// are little endian:
+//   - bytes 0-7: the occupied squares bitboard
+//   - bytes 8-23: the pieces on the occupied squares as 4 bits Piece values, in square order
+//     starting from the low bits
+//   - byte 24: bit 0 is set when black is to move, bits 1 to 4 are the castle rights KQkq
+//   - byte 25: the en passant square, 64 when there is none
+//   - byte 26: the half move clock, capped at 255
+//   - byte 27: the game result for white, 0 for a loss, 1 for a draw and 2 for a win
+//   - bytes 28-29: the move number
+//   - bytes 30-31: the score from white's point of view in centipawns
+func packTrainingRecord(pos *Position, score int, result float64) [TrainingRecordSize]byte {
+	var record [TrainingRecordSize]byte
+
+	occupied := ^pos.board.emptySquares
+	binary.LittleEndian.PutUint64(record[0:8], uint64(occupied))
+	for i := 0; occupied != 0; i++ {
+		sq := Square(occupied.LeastSignificant1Bit())
+		occupied.ClearLeastSignificant1Bit()
+
+		record[8+i/2] |= byte(pos.board.Piece(sq)) << (4 * (i % 2))
+	}
+
+	if pos.turn == BlackColor {
+		record[24] |= 1
+	}
+	for i, right := range []bool{pos.castleRights.WhiteKingSide, pos.castleRights.WhiteQueenSide,
+		pos.castleRights.BlackKingSide, pos.castleRights.BlackQueenSide} {
+		if right {
+			record[24] |= 2 << i
+		}
+	}
+
+	record[25] = 64
+	if pos.enPassantSquare != NoSquare {
+		record[25] = byte(pos.enPassantSquare)
+	}
+
+	record[26] = byte(clamp(pos.halfMoveClock, 0, math.MaxUint8))
+	record[27] = byte(result * 2)
+	binary.LittleEndian.PutUint16(record[28:30], uint16(clamp(pos.moveCount, 1, math.MaxUint16)))
+	binary.LittleEndian.PutUint16(record[30:32], uint16(int16(clamp(score, math.MinInt16, math.MaxInt16))))
+
+	return record
+}
+
+func clamp(value int, min int, max int) int {
+	if value < min {
+		return min
+	}
+	if value > max {
+		return max
+	}
+
+	return value
+}
+
+// UnpackTrainingRecord decodes a position in the binary training data format
+func UnpackTrainingRecord(data []byte) (TrainingRecord, error) {
+	if len(data) != TrainingRecordSize {
+		return TrainingRecord{}, fmt.Errorf("a record should be %d bytes long", TrainingRecordSize)
+	}
+
+	occupied := Bitboard(binary.LittleEndian.Uint64(data[0:8]))
+	if occupied.PopCount() > 32 {
+		return TrainingRecord{}, errors.New("too many pieces in the record")
+	}
+
+	var pieces [64]Piece
+	for i := 0; occupied != 0; i++ {
+		sq := occupied.LeastSignificant1Bit()
+		occupied.ClearLeastSignificant1Bit()
+
+		piece := Piece(data[8+i/2] >> (4 * (i % 2)) & 0xF)
+		if piece == NoPiece || piece > BlackPawn {
+			return TrainingRecord{}, fmt.Errorf("invalid piece %d in the record", piece)
+		}
+		pieces[sq] = piece
+	}
+
+	var fen strings.Builder
+	for rank := 7; rank >= 0; rank-- {
+		empty := 0
+		for file := 0; file <= 7; file++ {
+			piece := pieces[SquareFromFileRank(file, rank)]
+			if piece == NoPiece {
+				empty++
+				continue
+			}
+
+			if empty > 0 {
+				fen.WriteString(strconv.Itoa(empty))
+				empty = 0
+			}
+			fen.WriteByte(fenPieces[piece])
+		}
+
+		if empty > 0 {
+			fen.WriteString(strconv.Itoa(empty))
+		}
+		if rank > 0 {
+			fen.WriteByte('/')
+		}
+	}
+
+	if data[24]&1 == 0 {
+		fen.WriteString(" w ")
+	} else {
+		fen.WriteString(" b ")
+	}
+
+	rights := CastleRights{data[24]&2 != 0, data[24]&4 != 0, data[24]&8 != 0, data[24]&16 != 0}.String()
+	if rights == "" {
+		rights = "-"
+	}
+	fen.WriteString(rights)
+
+	switch enPassant := data[25]; {
+	case enPassant == 64:
+		fen.WriteString(" -")
+	case enPassant < 64:
+		fen.WriteString(" " + Square(enPassant).String())
+	default:
+		return TrainingRecord{}, fmt.Errorf("invalid en passant square %d in the record", enPassant)
+	}
+
+	if data[27] > 2 {
+		return TrainingRecord{}, fmt.Errorf("invalid result %d in the record", data[27])
+	}
+	fmt.Fprintf(&fen, " %d %d", data[26], binary.LittleEndian.Uint16(data[28:30]))
+
+	return TrainingRecord{
+		FEN:    fen.String(),
+		Score:  int(int16(binary.LittleEndian.Uint16(data[30:32]))),
+		Result: float64(data[27]) / 2,
+	}, nil
+}
+
+// ConvertTrainingData converts binary training data to text, writing a line like
+// "<fen> | <score> | <result>" for each record with the result written as 1.0, 0.5 or 0.0.
+// The text format can be used directly as a tuning position set. Returns the number of records
+func ConvertTrainingData(r io.Reader, w io.Writer) (int, error) {
+	reader := bufio.NewReader(r)
+	writer := bufio.NewWriter(w)
+	data := make([]byte, TrainingRecordSize)
+
+	count := 0
+	for ; ; count++ {
+		if _, err := io.ReadFull(reader, data); err == io.EOF {
+			break
+		} else if err != nil {
+			return count, fmt.Errorf("record %d: %w", count+1, err)
+		}
+
+		record, err := UnpackTrainingRecord(data)
+		if err != nil {
+			return count, fmt.Errorf("record %d: %w", count+1, err)
+		}
+
+		if _, err := fmt.Fprintf(writer, "%s | %d | %.1f\n", record.FEN, record.Score, record.Result); err != nil {
+			return count, err
+		}
+	}
+
+	return count, writer.Flush()
+}
+
+// DataGenerator plays self-play games and records quiet positions with the search score
+// and the result of the game, which are the data needed to train a network
+type DataGenerator struct {
+	// Depth limits the search of each move, 0 means no limit
+	Depth int
+	// Nodes limits the search of each move, 0 means no limit
+	Nodes int
+	// RandomPlies is the number of random moves starting each game, which makes the games different
+	RandomPlies int
+	// MaxPlies ends the games which are still going as draws
+	MaxPlies int
+	Threads  int
+	// Seed chooses the random openings, the same seed and settings generate the same data
+	Seed int64
+	// EvalParams are the evaluation parameters of the engines, the defaults when nil
+	EvalParams *EvalParams
+	// Network makes the engines use the network evaluation when not nil
+	Network *Network
+}
+
+// NewDataGenerator returns a data generator searching each move to depth 4
+func NewDataGenerator() *DataGenerator {
+	return &DataGenerator{
+		Depth:       4,
+		RandomPlies: 8,
+		MaxPlies:    400,
+		Threads:     1,
+		Seed:        1,
+	}
+}
+
+// generatedGame contains the records of a finished game
+type generatedGame struct {
+	index   int
+	records []byte
+}
+
+// Generate plays the games on all the threads and writes the recorded positions to w. The games
+// are written in order, so the data doesn't depend on the number of threads. When progress isn't
+// nil it's called after each game with the number of games and positions written so far.
+// Returns the number of positions written
+func (gen *DataGenerator) Generate(games int, w io.Writer, progress func(games int, positions int)) (int, error) {
+	if gen.Depth <= 0 && gen.Nodes <= 0 {
+		return 0, errors.New("the search should be limited by depth or nodes")
+	}
+
+	threads := gen.Threads
+	if threads < 1 {
+		threads = 1
+	}
+
+	indices := make(chan int)
+	results := make(chan generatedGame)
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < threads; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// Each thread clones its own base game for the games it plays
+			base := NewGame()
+			for index := range indices {
+				select {
+				case results <- generatedGame{index, gen.playGame(&base, index)}:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(indices)
+		for i := 0; i < games; i++ {
+			select {
+			case indices <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// The games finishing out of order wait until the previous ones are written
+	pending := map[int][]byte{}
+	next := 0
+	positions := 0
+	var err error
+	for result := range results {
+		if err != nil {
+			continue
+		}
+
+		pending[result.index] = result.records
+		for records, ok := pending[next]; ok; records, ok = pending[next] {
+			delete(pending, next)
+			next++
+
+			if _, err = w.Write(records); err != nil {
+				close(done)
+				break
+			}
+			positions += len(records) / TrainingRecordSize
+
+			if progress != nil {
+				progress(next, positions)
+			}
+		}
+	}
+
+	return positions, err
+}
+
+// playGame plays a self-play game and returns its records in the binary training data format
+func (gen *DataGenerator) playGame(base *Game, index int) []byte {
+	// Each game has its own random source, so the games don't depend on the thread playing them
+	random := rand.New(rand.NewSource(gen.Seed*1_000_000_007 + int64(index)))
+
+	game := base.Clone()
+	for i := 0; i < gen.RandomPlies && game.Result() == NoResult; i++ {
+		legalMoves := game.LegalMoves()
+		game.Move(legalMoves[random.Intn(len(legalMoves))])
+	}
+
+	eng := NewBruteForceEngine(&game)
+	eng.MaxDepth = gen.Depth
+	if gen.Depth <= 0 {
+		eng.MaxDepth = -1
+	}
+	eng.MaxNodes = gen.Nodes
+	eng.LogOutput = io.Discard
+	if gen.EvalParams != nil {
+		eng.EvalParams = gen.EvalParams
+	}
+	if gen.Network != nil {
+		eng.EvalMode = NetworkEvaluation
+		eng.Network = gen.Network
+	}
+
+	type recordedPosition struct {
+		position Position
+		score    int
+	}
+	recorded := []recordedPosition{}
+
+	result := 0.5
+	for plies := 0; plies < gen.MaxPlies; plies++ {
+		gameResult := game.Result()
+		if gameResult == Checkmate {
+			// The side to move is mated
+			result = 0
+			if game.position.turn == BlackColor {
+				result = 1
+			}
+		}
+		if gameResult != NoResult {
+			break
+		}
+
+		line := eng.Analyze(1)[0]
+
+		// Only quiet positions are recorded: the static evaluation of positions with checks
+		// and captures doesn't match the search score, and mate scores can't be learned
+		_, mate := MateIn(line.Score)
+		if line.Depth > 0 && !mate && !game.position.inCheck && !line.Move.IsCapture() && line.Move.Promotion() == NoPiece {
+			recorded = append(recorded, recordedPosition{*game.position, line.Score * int(game.position.turn) * 100 / 256})
+		}
+
+		game.Move(line.Move)
+	}
+
+	records := make([]byte, 0, len(recorded)*TrainingRecordSize)
+	for i := range recorded {
+		record := packTrainingRecord(&recorded[i].position, recorded[i].score, result)
+		records = append(records, record[:]...)
+	}
+
+	return records
+}
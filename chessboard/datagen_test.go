@@ -0,0 +1,123 @@
+package chessboard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTrainingRecordRoundTrip(t *testing.T) {
+	var tests = []struct {
+		fen    string
+		score  int
+		result float64
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", 20, 0.5},
+		{"rnbqkbnr/ppp1pppp/8/8/3pP3/8/PPPP1PPP/RNBQKBNR b Kq e3 0 3", -150, 0},
+		{"8/5k2/8/8/8/8/2K1R3/8 w - - 99 300", 40000, 1},
+	}
+
+	for _, test := range tests {
+		game := NewGameFromFEN(test.fen)
+		data := packTrainingRecord(game.position, test.score, test.result)
+
+		record, err := UnpackTrainingRecord(data[:])
+		if err != nil {
+			t.Errorf("Unpacking %s should succeed, %v was returned instead", test.fen, err)
+			continue
+		}
+
+		if record.FEN != test.fen {
+			t.Errorf("The unpacked fen should be %s, %s was returned instead", test.fen, record.FEN)
+		}
+		if expected := clamp(test.score, -32768, 32767); record.Score != expected {
+			t.Errorf("The unpacked score of %s should be %d, %d was returned instead", test.fen, expected, record.Score)
+		}
+		if record.Result != test.result {
+			t.Errorf("The unpacked result of %s should be %v, %v was returned instead", test.fen, test.result, record.Result)
+		}
+	}
+
+	game := NewGame()
+	valid := packTrainingRecord(game.position, 0, 0.5)
+	invalidPiece, invalidResult := valid, valid
+	invalidPiece[8] |= 0xF
+	invalidResult[27] = 3
+	for name, data := range map[string][]byte{
+		"a short record":    valid[:TrainingRecordSize-1],
+		"an invalid piece":  invalidPiece[:],
+		"an invalid result": invalidResult[:],
+		"too many pieces":   append([]byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, valid[8:]...),
+	} {
+		if _, err := UnpackTrainingRecord(data); err == nil {
+			t.Errorf("Unpacking %s should fail, no error was returned instead", name)
+		}
+	}
+}
+
+func TestDataGenerator(t *testing.T) {
+	generate := func(threads int) []byte {
+		gen := NewDataGenerator()
+		gen.Depth = 1
+		gen.RandomPlies = 2
+		gen.MaxPlies = 8
+		gen.Threads = threads
+
+		var data bytes.Buffer
+		positions, err := gen.Generate(3, &data, nil)
+		if err != nil {
+			t.Fatalf("Generating the data should succeed, %v was returned instead", err)
+		}
+		if positions*TrainingRecordSize != data.Len() {
+			t.Errorf("%d positions should be written, %d bytes were written instead", positions, data.Len())
+		}
+
+		return data.Bytes()
+	}
+
+	data := generate(1)
+	if len(data) == 0 {
+		t.Fatalf("The generator should record some positions")
+	}
+	if !bytes.Equal(data, generate(2)) {
+		t.Errorf("The generated data should not depend on the number of threads")
+	}
+
+	var text strings.Builder
+	count, err := ConvertTrainingData(bytes.NewReader(data), &text)
+	if err != nil || count != len(data)/TrainingRecordSize {
+		t.Fatalf("Converting the data should return %d records, %d and %v were returned instead", len(data)/TrainingRecordSize, count, err)
+	}
+
+	game := NewGame()
+	lines := strings.Split(strings.TrimSpace(text.String()), "\n")
+	for _, line := range lines {
+		pos, err := ParseTuningPosition(line, game.precomputedData)
+		if err != nil {
+			t.Errorf("The converted line %s should be a tuning position, %v was returned instead", line, err)
+			continue
+		}
+		if pos.position.inCheck {
+			t.Errorf("The position %s in check should not be recorded", pos.FEN)
+		}
+	}
+
+	if _, err := ConvertTrainingData(bytes.NewReader(data[:len(data)-1]), &text); err == nil {
+		t.Errorf("Converting truncated data should fail, no error was returned instead")
+	}
+}
+
+func TestMaxNodes(t *testing.T) {
+	game := NewGame()
+	eng := NewBruteForceEngine(&game)
+	eng.MaxNodes = 2000
+	eng.LogOutput = &bytes.Buffer{}
+
+	lines := eng.Analyze(60)
+	if len(lines) == 0 || lines[0].Move == nil {
+		t.Fatalf("The node limited search should return a move")
+	}
+	if eng.stats.nodes > 2*eng.MaxNodes {
+		t.Errorf("The search should stop after about %d nodes, %d nodes were searched instead", eng.MaxNodes, eng.stats.nodes)
+	}
+}
@@ -0,0 +1,365 @@
+package chessboard
+
+import "strings"
+
+// knownWinScore is added to the evaluation of endgames which are won with correct play,
+// so that the engine prefers converting to them over keeping more material
+const knownWinScore = 10 * 256
+
+// scaleFactorNormal is the scale factor of positions without drawish endgame knowledge,
+// the end game score is multiplied by the scale factor and divided by scaleFactorNormal
+const scaleFactorNormal = 64
+
+// materialKey identifies the material on the board: the number of queens, rooks,
+// bishops, knights and pawns of each side in 4 bits each, white first
+type materialKey uint64
+
+// newMaterialKey returns the material key of the board
+func newMaterialKey(board *Board) materialKey {
+	return sideMaterialKey(board.sidePieces(WhiteColor)) | sideMaterialKey(board.sidePieces(BlackColor))<<20
+}
+
+func sideMaterialKey(pieces sidePieces) materialKey {
+	return materialKey(pieces.queens.PopCount()) |
+		materialKey(pieces.rooks.PopCount())<<4 |
+		materialKey(pieces.bishops.PopCount())<<8 |
+		materialKey(pieces.knights.PopCount())<<12 |
+		materialKey(pieces.pawns.PopCount())<<16
+}
+
+// materialKeyFromCode returns the material key of a signature such as "KBNK", the pieces
+// of the strong side come before the second king. Panics if the code is invalid
+func materialKeyFromCode(code string, strong Color) materialKey {
+	second := strings.LastIndexByte(code, 'K')
+	if len(code) < 2 || code[0] != 'K' || second == 0 {
+		panic("Invalid endgame code " + code)
+	}
+
+	sideKey := func(pieces string) materialKey {
+		key := materialKey(0)
+		for _, piece := range pieces {
+			shift := strings.IndexRune("QRBNP", piece)
+			if shift == -1 {
+				panic("Invalid endgame code " + code)
+			}
+			key += 1 << (4 * shift)
+		}
+
+		return key
+	}
+
+	strongKey, weakKey := sideKey(code[1:second]), sideKey(code[second+1:])
+	if strong == WhiteColor {
+		return strongKey | weakKey<<20
+	}
+
+	return weakKey | strongKey<<20
+}
+
+// endgameEvaluator returns the evaluation of a position from the point of view of the strong side
+type endgameEvaluator func(pos *Position, strong Color, params *EvalParams) int
+
+// endgameScaler returns the scale factor of the end game score of the strong side,
+// scaleFactorNormal leaves the score unchanged and 0 makes the position a draw
+type endgameScaler func(pos *Position, strong Color, params *EvalParams) int
+
+type endgameEvaluation struct {
+	name     string
+	strong   Color
+	evaluate endgameEvaluator
+}
+
+type endgameScaling struct {
+	name   string
+	strong Color
+	scale  endgameScaler
+}
+
+// endgameRegistry contains the specialised evaluation and scaling functions of the endgames,
+// indexed by the material of the positions they apply to
+type endgameRegistry struct {
+	evaluations map[materialKey]endgameEvaluation
+	scalings    map[materialKey]endgameScaling
+}
+
+// endgames is the registry used by the static evaluation
+var endgames = newEndgameRegistry()
+
+func newEndgameRegistry() *endgameRegistry {
+	registry := &endgameRegistry{map[materialKey]endgameEvaluation{}, map[materialKey]endgameScaling{}}
+
+	registry.addEvaluation("KPK", evaluateKPK)
+	registry.addEvaluation("KBNK", evaluateKBNK)
+	registry.addEvaluation("KNNK", evaluateKNNK)
+	registry.addEvaluation("KRKP", evaluateKRKP)
+	registry.addEvaluation("KQKP", evaluateKQKP)
+	registry.addEvaluation("KQKR", evaluateKQKR)
+	registry.addEvaluation("KRKB", evaluateKRKB)
+	registry.addEvaluation("KRKN", evaluateKRKN)
+
+	for _, code := range []string{"KBPK", "KBPPK", "KBPPPK"} {
+		registry.addScaling(code, scaleKBPsK)
+	}
+
+	return registry
+}
+
+// addEvaluation registers an evaluator for the passed signature with either side as the strong one
+func (registry *endgameRegistry) addEvaluation(code string, evaluate endgameEvaluator) {
+	for _, strong := range []Color{WhiteColor, BlackColor} {
+		registry.evaluations[materialKeyFromCode(code, strong)] = endgameEvaluation{code, strong, evaluate}
+	}
+}
+
+// addScaling registers a scaler for the passed signature with either side as the strong one
+func (registry *endgameRegistry) addScaling(code string, scale endgameScaler) {
+	for _, strong := range []Color{WhiteColor, BlackColor} {
+		registry.scalings[materialKeyFromCode(code, strong)] = endgameScaling{code, strong, scale}
+	}
+}
+
+// evaluation returns the specialised evaluator of the position if there is one. Positions where a side
+// has only the king and the other has enough material to force mate use the generic KXK evaluator
+func (registry *endgameRegistry) evaluation(pos *Position, key materialKey) (endgameEvaluation, bool) {
+	if evaluation, ok := registry.evaluations[key]; ok {
+		return evaluation, true
+	}
+
+	for _, strong := range []Color{WhiteColor, BlackColor} {
+		weak := pos.board.sidePieces(strong.Other())
+		if weak.all == weak.king && canForceMate(pos.board.sidePieces(strong)) {
+			return endgameEvaluation{"KXK", strong, evaluateKXK}, true
+		}
+	}
+
+	return endgameEvaluation{}, false
+}
+
+// scaleFactor returns the scale factor of the end game score of the strong side and the name of the
+// rule which computed it, an empty name means that no rule applies
+func (registry *endgameRegistry) scaleFactor(pos *Position, key materialKey, strong Color, params *EvalParams) (string, int) {
+	if scaling, ok := registry.scalings[key]; ok && scaling.strong == strong {
+		if scale := scaling.scale(pos, strong, params); scale != scaleFactorNormal {
+			return scaling.name, scale
+		}
+	}
+
+	strongPieces, weakPieces := pos.board.sidePieces(strong), pos.board.sidePieces(strong.Other())
+
+	// Opposite coloured bishops are drawish even with a couple of pawns more
+	if strongPieces.queens|strongPieces.rooks|strongPieces.knights|weakPieces.queens|weakPieces.rooks|weakPieces.knights == 0 &&
+		strongPieces.bishops.PopCount() == 1 && weakPieces.bishops.PopCount() == 1 &&
+		(strongPieces.bishops&lightSquaresBitboard == 0) != (weakPieces.bishops&lightSquaresBitboard == 0) {
+		extraPawns := strongPieces.pawns.PopCount() - weakPieces.pawns.PopCount()
+		if extraPawns < 0 {
+			extraPawns = 0
+		}
+
+		scale := 16 + 8*extraPawns
+		if scale > scaleFactorNormal {
+			scale = scaleFactorNormal
+		}
+		return "Opposite coloured bishops", scale
+	}
+
+	// Without pawns the strong side needs about a rook more to win
+	if strongPieces.pawns == 0 {
+		strongMaterial := materialForSide(strongPieces, params).EndGame
+		weakPieces.pawns = 0
+		weakMaterial := materialForSide(weakPieces, params).EndGame
+
+		if strongMaterial-weakMaterial <= params.BishopValue.EndGame {
+			switch {
+			case strongMaterial < params.RookValue.EndGame:
+				return "No pawns", 0
+			case weakMaterial <= params.BishopValue.EndGame:
+				return "No pawns", 4
+			default:
+				return "No pawns", 14
+			}
+		}
+	}
+
+	return "", scaleFactorNormal
+}
+
+// canForceMate returns whether the pieces can force mate against a lone king
+func canForceMate(pieces sidePieces) bool {
+	return pieces.queens|pieces.rooks != 0 ||
+		(pieces.bishops&lightSquaresBitboard != 0 && pieces.bishops&^lightSquaresBitboard != 0) ||
+		(pieces.bishops != 0 && pieces.knights != 0) ||
+		pieces.knights.PopCount() >= 3
+}
+
+// centerDistance returns the number of king moves along ranks and files needed to reach the center from the square
+func centerDistance(sq Square) int {
+	distance := func(coordinate int) int {
+		if coordinate < 4 {
+			return 3 - coordinate
+		}
+		return coordinate - 4
+	}
+
+	return distance(int(sq%8)) + distance(int(sq/8))
+}
+
+// promotionSquare returns the square where the pawns of the passed color on the file promote
+func promotionSquare(file int, color Color) Square {
+	if color == WhiteColor {
+		return SquareFromFileRank(file, 7)
+	}
+
+	return SquareFromFileRank(file, 0)
+}
+
+// pushToEdge rewards the weak king being close to the edge of the board
+func pushToEdge(sq Square) int {
+	return 32 * centerDistance(sq)
+}
+
+// pushClose rewards the kings being close to each other, which is needed to mate
+func pushClose(a Square, b Square) int {
+	return 32 * (7 - squareDistance(a, b))
+}
+
+// evaluateKXK drives the lone king to the edge of the board, where it can be mated
+func evaluateKXK(pos *Position, strong Color, params *EvalParams) int {
+	strongKing, weakKing := pos.board.kingSquare(strong), pos.board.kingSquare(strong.Other())
+
+	return knownWinScore + materialForSide(pos.board.sidePieces(strong), params).EndGame +
+		pushToEdge(weakKing) + pushClose(strongKing, weakKing)
+}
+
+// evaluateKBNK drives the lone king to a corner of the colour of the bishop, the only ones where
+// bishop and knight can mate
+func evaluateKBNK(pos *Position, strong Color, params *EvalParams) int {
+	strongKing, weakKing := pos.board.kingSquare(strong), pos.board.kingSquare(strong.Other())
+
+	corners := [2]Square{A8, H1}
+	if pos.board.sidePieces(strong).bishops&lightSquaresBitboard == 0 {
+		corners = [2]Square{A1, H8}
+	}
+
+	manhattanDistance := func(a Square, b Square) int {
+		fileDistance, rankDistance := int(a%8)-int(b%8), int(a/8)-int(b/8)
+		if fileDistance < 0 {
+			fileDistance = -fileDistance
+		}
+		if rankDistance < 0 {
+			rankDistance = -rankDistance
+		}
+		return fileDistance + rankDistance
+	}
+	cornerDistance := manhattanDistance(weakKing, corners[0])
+	if distance := manhattanDistance(weakKing, corners[1]); distance < cornerDistance {
+		cornerDistance = distance
+	}
+
+	return knownWinScore + params.BishopValue.EndGame + params.KnightValue.EndGame +
+		32*(14-cornerDistance) + pushClose(strongKing, weakKing)
+}
+
+// evaluateKNNK is a draw, two knights can't force mate
+func evaluateKNNK(pos *Position, strong Color, params *EvalParams) int {
+	return 0
+}
+
+// evaluateKPK looks up the result in the KPK bitbase, won positions are better with the pawn closer to promotion
+func evaluateKPK(pos *Position, strong Color, params *EvalParams) int {
+	if !ProbeKPK(*pos) {
+		return 0
+	}
+
+	pawn := Square(pos.board.sidePieces(strong).pawns.LeastSignificant1Bit())
+	return knownWinScore + params.PawnValue.EndGame + 16*relativeRank(pawn, strong)
+}
+
+// evaluateKRKP is won when the strong king stops the pawn or the weak king is far from it,
+// otherwise the pawn supported by its king can hold the draw
+func evaluateKRKP(pos *Position, strong Color, params *EvalParams) int {
+	weak := strong.Other()
+	strongKing, weakKing := pos.board.kingSquare(strong), pos.board.kingSquare(weak)
+	rook := Square(pos.board.sidePieces(strong).rooks.LeastSignificant1Bit())
+	pawn := Square(pos.board.sidePieces(weak).pawns.LeastSignificant1Bit())
+	queening := promotionSquare(int(pawn%8), weak)
+	pushed := Square(int(pawn) + 8*int(weak))
+
+	tempo := func(color Color) int {
+		if pos.turn == color {
+			return 1
+		}
+		return 0
+	}
+
+	switch {
+	case pawn%8 == strongKing%8 && relativeRank(strongKing, weak) > relativeRank(pawn, weak):
+		return params.RookValue.EndGame - 20*squareDistance(strongKing, pawn)
+	case squareDistance(weakKing, pawn) >= 3+tempo(weak) && squareDistance(weakKing, rook) >= 3:
+		return params.RookValue.EndGame - 20*squareDistance(strongKing, pawn)
+	case relativeRank(weakKing, strong) <= 2 && squareDistance(weakKing, pawn) == 1 &&
+		relativeRank(strongKing, strong) >= 3 && squareDistance(strongKing, pawn) > 2+tempo(strong):
+		return 205 - 20*squareDistance(strongKing, pawn)
+	default:
+		return 512 - 20*(squareDistance(strongKing, pushed)-squareDistance(weakKing, pushed)-squareDistance(pawn, queening))
+	}
+}
+
+// evaluateKQKP is won unless a bishop or rook pawn on the seventh rank is supported by its king
+func evaluateKQKP(pos *Position, strong Color, params *EvalParams) int {
+	weak := strong.Other()
+	strongKing, weakKing := pos.board.kingSquare(strong), pos.board.kingSquare(weak)
+	pawn := Square(pos.board.sidePieces(weak).pawns.LeastSignificant1Bit())
+
+	score := pushClose(strongKing, weakKing)
+	file := pawn % 8
+	if relativeRank(pawn, weak) != 6 || squareDistance(weakKing, pawn) != 1 || !(file == 0 || file == 2 || file == 5 || file == 7) {
+		score += params.QueenValue.EndGame - params.PawnValue.EndGame
+	}
+
+	return score
+}
+
+// evaluateKQKR is won by driving the weak king to the edge
+func evaluateKQKR(pos *Position, strong Color, params *EvalParams) int {
+	strongKing, weakKing := pos.board.kingSquare(strong), pos.board.kingSquare(strong.Other())
+
+	return knownWinScore + params.QueenValue.EndGame - params.RookValue.EndGame +
+		pushToEdge(weakKing) + pushClose(strongKing, weakKing)
+}
+
+// evaluateKRKB is drawish, the strong side can only hope to trap the king on the edge
+func evaluateKRKB(pos *Position, strong Color, params *EvalParams) int {
+	return pushToEdge(pos.board.kingSquare(strong.Other()))
+}
+
+// evaluateKRKN is drawish, but the knight separated from its king can be lost
+func evaluateKRKN(pos *Position, strong Color, params *EvalParams) int {
+	weakKing := pos.board.kingSquare(strong.Other())
+	knight := Square(pos.board.sidePieces(strong.Other()).knights.LeastSignificant1Bit())
+
+	return pushToEdge(weakKing) + 16*squareDistance(weakKing, knight)
+}
+
+// scaleKBPsK draws the positions with pawns on a rook file when the bishop doesn't control the
+// promotion square and the weak king reached it
+func scaleKBPsK(pos *Position, strong Color, params *EvalParams) int {
+	pieces := pos.board.sidePieces(strong)
+	var file int
+	switch {
+	case pieces.pawns&^fileABitboard == 0:
+		file = 0
+	case pieces.pawns&^fileHBitboard == 0:
+		file = 7
+	default:
+		return scaleFactorNormal
+	}
+
+	queening := promotionSquare(file, strong)
+	bishopOnLight := pieces.bishops&lightSquaresBitboard != 0
+	queeningOnLight := queening.Bitboard()&lightSquaresBitboard != 0
+	if bishopOnLight != queeningOnLight && squareDistance(pos.board.kingSquare(strong.Other()), queening) <= 1 {
+		return 0
+	}
+
+	return scaleFactorNormal
+}
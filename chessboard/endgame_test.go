@@ -0,0 +1,171 @@
+package chessboard
+
+import "testing"
+
+func TestMaterialKey(t *testing.T) {
+	var tests = []struct {
+		fen    string
+		code   string
+		strong Color
+	}{
+		{"8/8/8/4k3/8/8/8/KBN5 w - - 0 1", "KBNK", WhiteColor},
+		{"8/8/8/4K3/8/8/8/kbn5 w - - 0 1", "KBNK", BlackColor},
+		{"8/8/8/4k3/8/2p5/8/K6Q w - - 0 1", "KQKP", WhiteColor},
+		{"8/3pp3/8/4k3/8/8/1P6/K7 w - - 0 1", "KPPKP", BlackColor},
+	}
+
+	for _, test := range tests {
+		game := NewGameFromFEN(test.fen)
+		if key := materialKeyFromCode(test.code, test.strong); newMaterialKey(&game.position.board) != key {
+			t.Errorf("The material key of %s should be the one of %s for %v", test.fen, test.code, test.strong)
+		}
+	}
+
+	for _, code := range []string{"", "K", "BNK", "KXK"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Parsing the endgame code %q should panic", code)
+				}
+			}()
+			materialKeyFromCode(code, WhiteColor)
+		}()
+	}
+}
+
+func TestEndgameEvaluation(t *testing.T) {
+	var tests = []struct {
+		fen     string
+		endgame string
+		min     int
+		max     int
+	}{
+		{"8/8/8/4k3/8/8/8/R3K3 w - - 0 1", "KXK", knownWinScore, Infinity},
+		{"8/8/8/4K3/8/8/8/r3k3 w - - 0 1", "KXK", -Infinity, -knownWinScore},
+		{"8/8/8/4K3/8/8/8/k1BN4 w - - 0 1", "KBNK", knownWinScore, Infinity},
+		{"8/8/8/4k3/8/8/8/1NN1K3 w - - 0 1", "KNNK", 0, 0},
+		{"8/8/8/8/P7/8/8/K5k1 w - - 0 1", "KPK", knownWinScore, Infinity},
+		{"8/8/4K3/4P3/8/8/8/k7 b - - 0 1", "KPK", knownWinScore, Infinity},
+		{"k7/8/8/8/P7/8/8/K7 w - - 0 1", "KPK", 0, 0},
+		{"8/8/8/8/8/4p2k/4K3/R7 w - - 0 1", "KRKP", 1000, knownWinScore},
+		{"8/8/8/K7/8/8/pk6/7Q w - - 0 1", "KQKP", 0, 256},
+		{"8/8/8/K7/8/8/2kp4/7Q w - - 0 1", "KQKP", 2000, knownWinScore},
+		{"4k3/8/8/8/8/8/8/2q1K3 w - - 0 1", "KXK", -Infinity, -knownWinScore},
+		{"4k3/p7/8/8/8/8/3q4/4K2R b - - 0 1", "", -Infinity, 0},
+	}
+
+	game := NewGame()
+	eng := NewBruteForceEngine(&game)
+	for _, test := range tests {
+		trace := evaluateFEN(eng, test.fen)
+
+		if trace.Endgame != test.endgame {
+			t.Errorf("The endgame of %s should be %q, %q was returned instead", test.fen, test.endgame, trace.Endgame)
+		}
+		if trace.Total < test.min || trace.Total > test.max {
+			t.Errorf("The evaluation of %s should be between %d and %d, %d was returned instead", test.fen, test.min, test.max, trace.Total)
+		}
+	}
+
+	// The lone king is mated in the corners of the colour of the bishop
+	right := evaluateFEN(eng, "8/8/8/4K3/8/8/8/k1BN4 w - - 0 1").Total
+	wrong := evaluateFEN(eng, "k7/8/8/4K3/8/8/8/2BN4 w - - 0 1").Total
+	if right <= wrong {
+		t.Errorf("KBNK should be better with the king in the corner of the bishop %d than in the other one %d", right, wrong)
+	}
+	mirrored := evaluateFEN(eng, "K1bn4/8/8/8/4k3/8/8/8 b - - 0 1").Total
+	if mirrored != -right {
+		t.Errorf("The mirrored KBNK evaluation should be %d, %d was returned instead", -right, mirrored)
+	}
+}
+
+func TestEndgameScaling(t *testing.T) {
+	var tests = []struct {
+		fen     string
+		endgame string
+		scale   int
+	}{
+		// The bishop doesn't control the promotion square
+		{"k7/8/8/8/8/8/P7/K1B5 w - - 0 1", "KBPK", 0},
+		{"k7/8/8/8/8/8/P7/KB6 w - - 0 1", "", scaleFactorNormal},
+		{"4k3/8/4b3/8/3PP3/2B5/8/4K3 w - - 0 1", "Opposite coloured bishops", 32},
+		{"4k3/8/3b4/8/3PP3/2B5/8/4K3 w - - 0 1", "", scaleFactorNormal},
+		{"4k3/8/8/3r4/8/8/8/2B1KR2 w - - 0 1", "No pawns", 14},
+		// A lone minor piece can't win against pawns
+		{"4k3/pp6/8/8/8/8/8/2B1K3 w - - 0 1", "No pawns", 0},
+		{"4k3/p7/8/8/8/8/P7/2B1K3 w - - 0 1", "", scaleFactorNormal},
+	}
+
+	game := NewGame()
+	eng := NewBruteForceEngine(&game)
+	for _, test := range tests {
+		trace := evaluateFEN(eng, test.fen)
+
+		if trace.Endgame != test.endgame || trace.Scale != test.scale {
+			t.Errorf("The scaling of %s should be %q with scale %d, %q with scale %d was returned instead",
+				test.fen, test.endgame, test.scale, trace.Endgame, trace.Scale)
+		}
+	}
+
+	eng.EndgameEval = false
+	if trace := evaluateFEN(eng, tests[0].fen); trace.Endgame != "" || trace.Scale != scaleFactorNormal {
+		t.Errorf("The endgame scaling should not be applied when disabled")
+	}
+}
+
+// evaluateFEN returns the evaluation trace of the position
+func evaluateFEN(eng *BruteForceEngine, fen string) EvalTrace {
+	game := NewGameFromFEN(fen)
+	return eng.Evaluate(game.Position())
+}
+
+func TestProbeKPK(t *testing.T) {
+	var tests = []struct {
+		fen string
+		win bool
+	}{
+		// The king on a key square wins with either side to move
+		{"4k3/8/4K3/4P3/8/8/8/8 w - - 0 1", true},
+		{"4k3/8/4K3/4P3/8/8/8/8 b - - 0 1", true},
+		{"1k6/8/1K6/1P6/8/8/8/8 w - - 0 1", true},
+		{"6k1/8/6K1/6P1/8/8/8/8 w - - 0 1", true},
+		// With the pawn on the sixth rank the side to move decides the result
+		{"4k3/8/3KP3/8/8/8/8/8 w - - 0 1", true},
+		{"4k3/8/3KP3/8/8/8/8/8 b - - 0 1", false},
+		{"4k3/8/4P3/4K3/8/8/8/8 w - - 0 1", false},
+		{"4k3/8/4P3/4K3/8/8/8/8 b - - 0 1", false},
+		// Opposition in front of the pawn
+		{"8/8/8/4k3/8/4K3/4P3/8 w - - 0 1", false},
+		{"8/8/8/4k3/8/4K3/4P3/8 b - - 0 1", true},
+		// The lone king reaching the corner draws against a rook pawn
+		{"k7/8/8/8/P7/8/8/K7 w - - 0 1", false},
+		{"7k/8/8/8/8/8/7P/7K b - - 0 1", false},
+		{"8/1K6/8/P7/8/8/8/4k3 b - - 0 1", true},
+		// Rule of the square
+		{"8/8/8/8/P7/8/8/K5k1 w - - 0 1", true},
+		{"8/8/8/8/P7/8/8/K5k1 b - - 0 1", true},
+		{"8/8/8/8/P4k2/8/8/K7 w - - 0 1", true},
+		{"8/8/8/8/P4k2/8/8/K7 b - - 0 1", false},
+		{"8/8/8/1k6/P7/8/8/7K w - - 0 1", false},
+		// Black pawns are flipped
+		{"8/8/8/8/4p3/4k3/8/4K3 b - - 0 1", true},
+		{"8/8/8/8/8/4k3/4p3/4K3 w - - 0 1", false},
+		{"8/8/8/8/8/3kp3/8/4K3 b - - 0 1", true},
+		{"8/8/8/8/8/3kp3/8/4K3 w - - 0 1", false},
+	}
+
+	for _, test := range tests {
+		game := NewGameFromFEN(test.fen)
+		if win := ProbeKPK(game.Position()); win != test.win {
+			t.Errorf("The KPK result of %s should be a win: %v, %v was returned instead", test.fen, test.win, win)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Probing a position which is not KPK should panic")
+		}
+	}()
+	game := NewGameFromFEN("4k3/8/4K3/4P3/8/8/8/7R w - - 0 1")
+	ProbeKPK(game.Position())
+}
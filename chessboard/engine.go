@@ -2,126 +2,583 @@ package chessboard
 
 import (
 	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Infinity contains a very high int16 number
 const Infinity = 15_000_000
 
-// CheckmateScore contains the score given to a checkmate loss
-const CheckmateScore = -1_000_000
+// MateScore contains the score given to delivering checkmate at the root, a mate found
+// n plies deeper scores MateScore-n so that the engine prefers the fastest mate
+const MateScore = 32_000
+
+// CheckmateScore contains the score given to a checkmate loss at the root
+const CheckmateScore = -MateScore
+
+// MaxPly contains the maximum distance from the root reachable by the search,
+// scores closer than MaxPly to MateScore are mate scores
+const MaxPly = 128
 
 // DrawScore contains the score given to a draw
 const DrawScore = 0
 
+// EvalMode selects the static evaluation used by the engine
+type EvalMode int
+
+const (
+	// ClassicalEvaluation uses the hand crafted evaluation terms weighted by EvalParams
+	ClassicalEvaluation EvalMode = iota
+	// NetworkEvaluation uses the neural network of the engine
+	NetworkEvaluation
+)
+
+func (mode EvalMode) String() string {
+	switch mode {
+	case ClassicalEvaluation:
+		return "Classical"
+	case NetworkEvaluation:
+		return "Network"
+	default:
+		panic("Unknown evaluation mode")
+	}
+}
+
 // BruteForceEngine explores all the tree to find the best move
 type BruteForceEngine struct {
-	trackedGame               *Game
-	game                      Game
-	MaxDepth                  int
-	MaterialDifferenceEval    bool
-	PositionDifferenceEval    bool
-	CenterControlEval         bool
-	DoubledIsolatedPawnsEval  bool
-	PassedPawnsEval           bool
-	QuiescentSearchEnabled    bool
-	AlphaBetaPruningEnabled   bool
-	TranspositionTableEnabled bool
-	MoveSortingEnabled        bool
-	AspirationSearchEnabled   bool
-	AspirationWindowWidth     int
+	trackedGame *Game
+	game        Game
+	MaxDepth    int
+	// MaxNodes stops the search when the main thread explored this many nodes, 0 means no limit.
+	// The lines of the last completed depth are returned
+	MaxNodes                 int
+	MaterialDifferenceEval   bool
+	PositionDifferenceEval   bool
+	CenterControlEval        bool
+	DoubledIsolatedPawnsEval bool
+	PassedPawnsEval          bool
+	// EvalParams contains the weights of the evaluation terms, it can be shared between engines
+	// but it must not be modified while a search is running
+	EvalParams *EvalParams
+	// EvalMode selects between the classical evaluation terms and the neural network
+	EvalMode EvalMode
+	// Network is the neural network used by the NetworkEvaluation mode, it can be shared between engines
+	Network                      *Network
+	PawnStructureEval            bool
+	PawnHashTableEnabled         bool
+	MobilityEval                 bool
+	KingSafetyEval               bool
+	BishopPairEval               bool
+	RookFilesEval                bool
+	KnightOutpostsEval           bool
+	HangingPiecesEval            bool
+	TrappedPiecesEval            bool
+	ThreatsEval                  bool
+	EndgameEval                  bool
+	QuiescentSearchEnabled       bool
+	AlphaBetaPruningEnabled      bool
+	TranspositionTableEnabled    bool
+	MoveSortingEnabled           bool
+	AspirationSearchEnabled      bool
+	AspirationWindowWidth        int
+	MateDistancePruningEnabled   bool
+	CheckExtensionsEnabled       bool
+	RecaptureExtensionsEnabled   bool
+	SingleReplyExtensionsEnabled bool
+	Threads                      int
+	MultiPV                      int
+	LogOutput                    io.Writer
+
+	// helperID is 0 for the main search thread and positive for Lazy SMP helpers
+	helperID int
+	// pawnTable caches the pawn structure evaluations, it is allocated on the first use
+	pawnTable *pawnTable
+	// evaluations and quiescentEvaluations are the transposition tables shared by the search
+	// threads, they are allocated by the first search and cleared by the following ones
+	evaluations          *ZobristTable
+	quiescentEvaluations *ZobristTable
+	// rootPly is the number of moves played in the game before the search root
+	rootPly int
+	// rootDepth is the nominal depth of the current iteration, it limits the extensions
+	rootDepth   int
+	searchStart time.Time
+	deadline    *int64
+	stop        *int32
+	done        chan struct{}
+	stats       searchStats
+	lastSearch  SearchInfo
+	lastLines   []AnalysisLine
+}
+
+// searchStats contains the counters collected by a single search thread
+type searchStats struct {
+	nodes       int
+	selDepth    int
+	cacheHits   int
+	cacheMisses int
+}
+
+// SearchInfo contains the statistics about the last search, aggregated over all threads
+type SearchInfo struct {
+	Depth int
+	// SelDepth is the maximum distance from the root reached by extensions and quiescent search
+	SelDepth    int
+	Nodes       int
+	CacheHits   int
+	CacheMisses int
+	ThreadNodes []int
 }
 
 // NewBruteForceEngine initializes a BruteForceEngine
 func NewBruteForceEngine(game *Game) *BruteForceEngine {
 	return &BruteForceEngine{trackedGame: game,
-		game:                      *game,
-		MaterialDifferenceEval:    true,
-		PositionDifferenceEval:    true,
-		QuiescentSearchEnabled:    true,
-		AlphaBetaPruningEnabled:   true,
-		CenterControlEval:         true,
-		TranspositionTableEnabled: false,
-		MoveSortingEnabled:        true,
-		DoubledIsolatedPawnsEval:  true,
-		PassedPawnsEval:           true,
-		MaxDepth:                  -1,
-		AspirationSearchEnabled:   true,
-		AspirationWindowWidth:     180,
+		game:                         *game,
+		MaterialDifferenceEval:       true,
+		PositionDifferenceEval:       true,
+		QuiescentSearchEnabled:       true,
+		AlphaBetaPruningEnabled:      true,
+		CenterControlEval:            true,
+		TranspositionTableEnabled:    false,
+		MoveSortingEnabled:           true,
+		DoubledIsolatedPawnsEval:     true,
+		PassedPawnsEval:              true,
+		EvalParams:                   DefaultEvalParams(),
+		PawnStructureEval:            true,
+		PawnHashTableEnabled:         true,
+		MobilityEval:                 true,
+		KingSafetyEval:               true,
+		BishopPairEval:               true,
+		RookFilesEval:                true,
+		KnightOutpostsEval:           true,
+		HangingPiecesEval:            true,
+		TrappedPiecesEval:            true,
+		ThreatsEval:                  true,
+		EndgameEval:                  true,
+		MaxDepth:                     -1,
+		AspirationSearchEnabled:      true,
+		AspirationWindowWidth:        180,
+		MateDistancePruningEnabled:   true,
+		CheckExtensionsEnabled:       true,
+		RecaptureExtensionsEnabled:   true,
+		SingleReplyExtensionsEnabled: true,
+		Threads:                      1,
+		MultiPV:                      1,
+		LogOutput:                    os.Stdout,
 	}
 }
 
-var _nodeHits = 0
+// SetGame makes the engine search the passed game, keeping the tables allocated by the
+// previous searches. It must not be called while a search is running
+func (eng *BruteForceEngine) SetGame(game *Game) {
+	eng.trackedGame = game
+	eng.game = *game
+}
+
+// SearchInfo returns the statistics collected during the last search
+func (eng *BruteForceEngine) SearchInfo() SearchInfo {
+	return eng.lastSearch
+}
+
+// AnalysisLine contains a candidate move with its evaluation and the main line
+// the engine expects to follow it
+type AnalysisLine struct {
+	Move  *Move
+	Score int
+	Depth int
+	PV    []*Move
+}
 
-// BestMove returns the best move as computed by the AI
+// BestMove returns the best move as computed by the AI, nil when the side to move has no legal moves
 func (eng *BruteForceEngine) BestMove(remainingTime int) *Move {
-	var endTime time.Time
-	if eng.MaxDepth == -1 {
-		endTime = time.Now().Add(time.Duration(remainingTime) * (time.Second / 40))
+	lines := eng.Analyze(remainingTime)
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return lines[0].Move
+}
+
+// Analyze searches the tracked position and returns the best MultiPV lines,
+// ranked from the best to the worst. No lines are returned when the side to
+// move is checkmated or stalemated
+func (eng *BruteForceEngine) Analyze(remainingTime int) []AnalysisLine {
+	eng.StartAnalysis(remainingTime)
+	return eng.Wait()
+}
+
+// noDeadline is the deadline of the searches without a time limit
+const noDeadline = math.MaxInt64
+
+// StartAnalysis starts searching the tracked position in the background, the search
+// can be interrupted at any time with Stop and its result collected with Wait
+func (eng *BruteForceEngine) StartAnalysis(remainingTime int) {
+	now := time.Now()
+	eng.startSearch(now, eng.moveDeadline(now, remainingTime), nil, 0)
+}
+
+// StartMateSearch starts looking in the background for a forced checkmate in at most maxMoves
+// moves like FindMate, falling back to the search of StartAnalysis when there is none. The
+// search can be interrupted at any time with Stop and its result collected with Wait
+func (eng *BruteForceEngine) StartMateSearch(maxMoves int, remainingTime int) {
+	now := time.Now()
+	eng.startSearch(now, eng.moveDeadline(now, remainingTime), nil, maxMoves)
+}
+
+// Ponder starts searching in the background, on the opponent's time, the position reached
+// when the opponent replies with the expected move (nil searches the tracked position itself).
+// The search has no time limit until PonderHit is called
+func (eng *BruteForceEngine) Ponder(expectedMove *Move) {
+	eng.startSearch(time.Now(), noDeadline, expectedMove, 0)
+}
+
+// PonderHit turns the running ponder search into the search for the engine's move when the
+// opponent played the expected move. The work already done is kept and the time spent
+// pondering counts towards the time allotted to the move
+func (eng *BruteForceEngine) PonderHit(remainingTime int) {
+	atomic.StoreInt64(eng.deadline, eng.moveDeadline(eng.searchStart, remainingTime))
+}
+
+// PonderMiss aborts the running ponder search when the opponent didn't play the expected move
+func (eng *BruteForceEngine) PonderMiss() {
+	eng.Stop()
+	eng.Wait()
+}
+
+// PonderMove returns the opponent's reply expected by the main line of the last search,
+// nil if the main line doesn't contain it
+func (eng *BruteForceEngine) PonderMove() *Move {
+	if len(eng.lastLines) == 0 || len(eng.lastLines[0].PV) < 2 {
+		return nil
+	}
+
+	return eng.lastLines[0].PV[1]
+}
+
+// Stop interrupts the running search, which returns the lines of the last completed depth
+func (eng *BruteForceEngine) Stop() {
+	if eng.stop != nil {
+		atomic.StoreInt32(eng.stop, 1)
+	}
+}
+
+// Wait waits for the running search to end and returns its MultiPV lines
+func (eng *BruteForceEngine) Wait() []AnalysisLine {
+	<-eng.done
+	return eng.lastLines
+}
+
+// moveDeadline returns the time at which the search for a move started at the passed time
+// should end, as nanoseconds since the unix epoch
+func (eng *BruteForceEngine) moveDeadline(start time.Time, remainingTime int) int64 {
+	if eng.MaxDepth != -1 || eng.MaxNodes > 0 {
+		return noDeadline
+	}
+
+	return start.Add(time.Duration(remainingTime) * (time.Second / 40)).UnixNano()
+}
+
+// timeUp returns whether the deadline of the search has passed
+func (eng *BruteForceEngine) timeUp() bool {
+	return time.Now().UnixNano() > atomic.LoadInt64(eng.deadline)
+}
+
+// startSearch prepares the state shared by the search threads and starts them in the background,
+// a positive mateMoves looks for a forced mate in that many moves before the normal search
+func (eng *BruteForceEngine) startSearch(start time.Time, deadline int64, expectedMove *Move, mateMoves int) {
+	eng.game = eng.trackedGame.Clone()
+	eng.setupNetwork()
+	if expectedMove != nil {
+		eng.game.Move(expectedMove)
+	}
+	eng.rootPly = len(eng.game.moves)
+	eng.stats = searchStats{}
+	eng.searchStart = start
+	eng.deadline = &deadline
+	var stop int32
+	eng.stop = &stop
+	eng.done = make(chan struct{})
+
+	// Lazy SMP: the helper threads search the same root position on their own copy of the game,
+	// their only contribution is filling the shared transposition tables, which makes the
+	// main thread's move sorting and cutoffs more effective. Starting odd helpers one ply
+	// deeper spreads the threads over different depths
+	helpers := make([]*BruteForceEngine, 0, eng.Threads)
+	for i := 1; i < eng.Threads; i++ {
+		helper := *eng
+		helper.game = eng.game.Clone()
+		helper.setupNetwork()
+		helper.helperID = i
+		helper.pawnTable = nil
+		helper.MultiPV = 1
+		helpers = append(helpers, &helper)
+	}
+
+	go eng.search(helpers, mateMoves)
+}
+
+// transpositionTableEnabled returns whether the cached evaluations can cut the search, the
+// tables are always used when Threads > 1 because the Lazy SMP helpers share their work only
+// through them
+func (eng *BruteForceEngine) transpositionTableEnabled() bool {
+	return eng.TranspositionTableEnabled || eng.Threads > 1
+}
+
+// setupNetwork makes the search game keep the network accumulators updated when
+// the network evaluation is used
+func (eng *BruteForceEngine) setupNetwork() {
+	if eng.EvalMode != NetworkEvaluation {
+		return
+	}
+
+	if eng.Network == nil {
+		panic("The network evaluation mode requires a network")
+	}
+	eng.game.setNetwork(eng.Network)
+}
+
+// search runs the main search thread and the helpers, storing the result when done
+func (eng *BruteForceEngine) search(helpers []*BruteForceEngine, mateMoves int) {
+	// There is nothing to search in checkmated and stalemated positions
+	if len(eng.game.LegalMoves()) == 0 {
+		eng.lastSearch = SearchInfo{}
+		eng.lastLines = nil
+		close(eng.done)
+		return
+	}
+
+	if mateMoves > 0 {
+		if found, line := eng.findMate(mateMoves); found {
+			eng.lastLines = []AnalysisLine{{Move: line[0], Score: MateScore - len(line), Depth: len(line), PV: line}}
+			close(eng.done)
+			return
+		}
+	}
+
+	// The transposition tables are shared by all the threads
+	if eng.evaluations == nil {
+		eng.evaluations = &(ZobristTable{})
+		eng.quiescentEvaluations = &(ZobristTable{})
 	} else {
-		endTime = time.Now().Add(720 * time.Hour)
+		*eng.evaluations = ZobristTable{}
+		*eng.quiescentEvaluations = ZobristTable{}
+	}
+	evaluations, quiescentEvaluations := eng.evaluations, eng.quiescentEvaluations
+
+	var wg sync.WaitGroup
+	for i, helper := range helpers {
+		wg.Add(1)
+		go func(helper *BruteForceEngine, startDepth int) {
+			defer wg.Done()
+			helper.iterativeDeepening(startDepth, evaluations, quiescentEvaluations)
+		}(helper, 1+(i+1)%2)
+	}
+
+	lines, depth := eng.iterativeDeepening(1, evaluations, quiescentEvaluations)
+
+	// Stop the helpers as soon as the main thread is done
+	atomic.StoreInt32(eng.stop, 1)
+	wg.Wait()
+
+	info := SearchInfo{Depth: depth, ThreadNodes: []int{eng.stats.nodes}}
+	info.add(eng.stats)
+	for _, helper := range helpers {
+		info.add(helper.stats)
+		info.ThreadNodes = append(info.ThreadNodes, helper.stats.nodes)
+	}
+	eng.lastSearch = info
+	eng.lastLines = lines
+
+	fmt.Fprintf(eng.LogOutput, "Depth reached: %d, Selective depth: %d, Nodes explored: %d, Cache hits: %d, Cache misses: %d\n", info.Depth, info.SelDepth, info.Nodes, info.CacheHits, info.CacheMisses)
+
+	close(eng.done)
+}
+
+func (info *SearchInfo) add(stats searchStats) {
+	info.Nodes += stats.nodes
+	if stats.selDepth > info.SelDepth {
+		info.SelDepth = stats.selDepth
+	}
+	info.CacheHits += stats.cacheHits
+	info.CacheMisses += stats.cacheMisses
+}
+
+// ply returns the distance of the current position from the search root
+func (eng *BruteForceEngine) ply() int {
+	return len(eng.game.moves) - eng.rootPly
+}
+
+// scoreToCache converts a mate score relative to the root into one relative to the current
+// position, so that it stays valid when the position is reached at a different ply
+func scoreToCache(score int, ply int) int {
+	if score > MateScore-MaxPly {
+		return score + ply
+	}
+	if score < CheckmateScore+MaxPly {
+		return score - ply
+	}
+
+	return score
+}
+
+// scoreFromCache converts a cached mate score back into one relative to the root
+func scoreFromCache(score int, ply int) int {
+	if score > MateScore-MaxPly {
+		return score - ply
+	}
+	if score < CheckmateScore+MaxPly {
+		return score + ply
+	}
+
+	return score
+}
+
+// MateIn returns the number of moves before checkmate encoded in a score: positive when
+// the side to move is mating, negative when it is getting mated. The boolean is false when
+// the score isn't a mate score
+func MateIn(score int) (int, bool) {
+	if score > MateScore-MaxPly {
+		return (MateScore - score + 1) / 2, true
+	}
+	if score < CheckmateScore+MaxPly {
+		return -(score - CheckmateScore) / 2, true
 	}
 
-	eng.game = *eng.trackedGame
+	return 0, false
+}
+
+// stopped returns whether the search has been stopped by another thread
+func (eng *BruteForceEngine) stopped() bool {
+	return eng.stop != nil && atomic.LoadInt32(eng.stop) != 0
+}
 
-	_nodeHits = 0
-	_zobristCacheHits = 0
-	_zobristCacheMisses = 0
+// iterativeDeepening searches the tracked position with increasing depth until the time runs out,
+// returns the MultiPV lines of the last completed iteration and its depth
+func (eng *BruteForceEngine) iterativeDeepening(startDepth int, evaluations *ZobristTable, quiescentEvaluations *ZobristTable) ([]AnalysisLine, int) {
+	legalMoves := eng.game.LegalMoves()
 
-	move := eng.game.LegalMoves()[0]
-	evaluations := &(ZobristTable{})
-	quiescentEvaluations := &(ZobristTable{})
-	depth := 1
+	linesCount := eng.MultiPV
+	if linesCount < 1 {
+		linesCount = 1
+	}
+	if linesCount > len(legalMoves) {
+		linesCount = len(legalMoves)
+	}
+
+	lines := []AnalysisLine{{Move: legalMoves[0], PV: []*Move{legalMoves[0]}}}
+	depth := startDepth
 	previousScore := eng.StaticEvaluation()
 	for eng.MaxDepth == -1 || depth <= eng.MaxDepth {
-		var aborted bool
-		var bestMove *Move
-		var score int
-
-		if eng.AspirationSearchEnabled {
-			aborted, bestMove, score = eng.NegaMax(
-				depth,
-				previousScore-eng.AspirationWindowWidth,
-				previousScore+eng.AspirationWindowWidth,
-				endTime,
-				evaluations,
-				quiescentEvaluations,
-			)
+		// Each line searches the root excluding the moves of the better lines
+		depthLines := make([]AnalysisLine, 0, linesCount)
+		excludedMoves := make([]*Move, 0, linesCount)
+		aborted := false
+
+		for len(depthLines) < linesCount {
+			var line AnalysisLine
+
+			// The aspiration window is centered on the previous best score,
+			// so it is only useful for the first line
+			if len(depthLines) == 0 {
+				aborted, line = eng.aspirationSearch(depth, previousScore, evaluations, quiescentEvaluations)
+			} else {
+				aborted, line = eng.searchRoot(depth, -Infinity, Infinity, evaluations, quiescentEvaluations, excludedMoves)
+			}
 
 			if aborted {
 				break
 			}
-		}
 
-		// If the aspiration search is disabled than we perform a full search directly
-		// If the evaluation from the aspiration search is outside the bound of the aspiration window we must research
-		if !eng.AspirationSearchEnabled || score >= previousScore+eng.AspirationWindowWidth || score <= previousScore-eng.AspirationWindowWidth {
-			aborted, bestMove, score = eng.NegaMax(
-				depth,
-				-Infinity,
-				Infinity,
-				endTime,
-				evaluations,
-				quiescentEvaluations,
-			)
+			depthLines = append(depthLines, line)
+			excludedMoves = append(excludedMoves, line.Move)
 		}
 
 		if aborted {
 			break
 		}
 
-		move = bestMove
-		previousScore = score
+		lines = depthLines
+		previousScore = lines[0].Score
+		eng.logLines(lines)
 		depth++
 	}
 
-	fmt.Printf("Depth reached: %d, Nodes explored: %d, Cache hits: %d, Cache misses: %d\n", depth-1, _nodeHits, _zobristCacheHits, _zobristCacheMisses)
+	return lines, depth - 1
+}
+
+// aspirationSearch searches the root with a narrow window around the expected score,
+// researching with a full window if the score falls outside of it
+func (eng *BruteForceEngine) aspirationSearch(depth int, expectedScore int, evaluations *ZobristTable, quiescentEvaluations *ZobristTable) (bool, AnalysisLine) {
+	var aborted bool
+	var line AnalysisLine
+
+	if eng.AspirationSearchEnabled {
+		aborted, line = eng.searchRoot(
+			depth,
+			expectedScore-eng.AspirationWindowWidth,
+			expectedScore+eng.AspirationWindowWidth,
+			evaluations,
+			quiescentEvaluations,
+			nil,
+		)
+
+		if aborted {
+			return true, line
+		}
+	}
+
+	// If the aspiration search is disabled than we perform a full search directly
+	// If the evaluation from the aspiration search is outside the bound of the aspiration window we must research
+	if !eng.AspirationSearchEnabled || line.Score >= expectedScore+eng.AspirationWindowWidth || line.Score <= expectedScore-eng.AspirationWindowWidth {
+		aborted, line = eng.searchRoot(
+			depth,
+			-Infinity,
+			Infinity,
+			evaluations,
+			quiescentEvaluations,
+			nil,
+		)
+	}
+
+	return aborted, line
+}
+
+// logLines logs diagnostics about the lines found with the last depth of search
+func (eng *BruteForceEngine) logLines(lines []AnalysisLine) {
+	if eng.helperID != 0 {
+		return
+	}
 
-	return move
+	for i, line := range lines {
+		if len(lines) > 1 {
+			fmt.Fprintf(eng.LogOutput, "Line: %d, ", i+1)
+		}
+		if mate, ok := MateIn(line.Score); ok {
+			fmt.Fprintf(eng.LogOutput, "Depth: %d, Mate in: %d, Main line: ", line.Depth, mate)
+		} else {
+			fmt.Fprintf(eng.LogOutput, "Depth: %d, Score: %d, Main line: ", line.Depth, line.Score)
+		}
+		for _, move := range line.PV {
+			fmt.Fprintf(eng.LogOutput, "%s ", *move)
+		}
+		fmt.Fprintln(eng.LogOutput)
+	}
 }
 
 // NegaMax does a negamax search of the tree up to the passed depth
 func (eng *BruteForceEngine) NegaMax(depth int, alpha int, beta int, endTime time.Time, evaluations *ZobristTable, quiescentEvaluations *ZobristTable) (bool, *Move, int) {
+	deadline := endTime.UnixNano()
+	eng.deadline = &deadline
+
+	aborted, line := eng.searchRoot(depth, alpha, beta, evaluations, quiescentEvaluations, nil)
+	return aborted, line.Move, line.Score
+}
+
+// searchRoot does a negamax search of the root position ignoring the excluded moves
+func (eng *BruteForceEngine) searchRoot(depth int, alpha int, beta int, evaluations *ZobristTable, quiescentEvaluations *ZobristTable, excludedMoves []*Move) (bool, AnalysisLine) {
 	var legalMoves []*Move
 
 	// We can use the evaluation score from the previous iteration to sort the moves,
@@ -133,28 +590,45 @@ func (eng *BruteForceEngine) NegaMax(depth int, alpha int, beta int, endTime tim
 		legalMoves = eng.game.LegalMoves()
 	}
 
-	bestMove := legalMoves[0]
+	var bestMove *Move
 	bestScore := -Infinity
 	bestPositionalScore := -Infinity
 
 	// mainLine is a diagnostic value that tracks what the bot thinks
-	// would be the perfect game from now on
+	// would be the perfect game from now on, the moves are stored in reverse order
 	mainLine := []*Move{}
 
+	eng.rootDepth = depth
+	previousMove := eng.game.lastMove()
+
 	// Try each move, recursively compute the score of the resulting position and
 	// choose the best move for us (that is the worst for our opponent)
 	for i := 0; i < len(legalMoves); i++ {
+		if isMoveExcluded(legalMoves[i], excludedMoves) {
+			continue
+		}
+		if bestMove == nil {
+			bestMove = legalMoves[i]
+		}
+
 		// Abort search if running out of time
-		if time.Now().After(endTime) {
-			return true, nil, 0
+		if eng.timeUp() || eng.stopped() {
+			return true, AnalysisLine{}
 		}
 
 		eng.game.Move(legalMoves[i])
+		extension := eng.extension(legalMoves[i], previousMove, len(legalMoves))
 
 		// Get the evaluation of the position from our opponents point of view and flip it (best for us is worst for our opponent)
-		score, variation := eng.recNegaMax(depth-1, -beta, -alpha, evaluations, quiescentEvaluations)
+		score, variation := eng.recNegaMax(depth-1+extension, -beta, -alpha, evaluations, quiescentEvaluations)
 		score = -score
 
+		// The score of an interrupted search is meaningless
+		if eng.stopped() {
+			eng.game.UndoMove()
+			return true, AnalysisLine{}
+		}
+
 		if score > bestScore {
 			bestScore = score
 			bestPositionalScore = -eng.StaticEvaluation()
@@ -168,11 +642,12 @@ func (eng *BruteForceEngine) NegaMax(depth int, alpha int, beta int, endTime tim
 				// stop the search already
 				if alpha > beta && eng.AlphaBetaPruningEnabled {
 					eng.game.UndoMove()
-					return false, bestMove, alpha
+					return false, newAnalysisLine(bestMove, alpha, depth, mainLine)
 				}
 			}
-		} else if score == bestScore {
-			// When the score is the same choose the move with the better static evaluation
+		} else if _, isMate := MateIn(score); score == bestScore && !isMate {
+			// When the score is the same choose the move with the better static evaluation,
+			// mate scores are excluded because mate distance pruning returns them as bounds
 			positionalScore := -eng.StaticEvaluation()
 			if positionalScore > bestPositionalScore {
 				bestScore = score
@@ -185,31 +660,107 @@ func (eng *BruteForceEngine) NegaMax(depth int, alpha int, beta int, endTime tim
 		eng.game.UndoMove()
 	}
 
-	// Log diagnostics about the best move found with this depth of search
-	fmt.Printf("Depth: %d, Score: %d, Main line: ", depth, bestScore)
-	for i := len(mainLine) - 1; i >= 0; i-- {
-		fmt.Printf("%s ", *mainLine[i])
+	return false, newAnalysisLine(bestMove, bestScore, depth, mainLine)
+}
+
+// newAnalysisLine builds an AnalysisLine from a main line stored in reverse order
+func newAnalysisLine(move *Move, score int, depth int, reversedMainLine []*Move) AnalysisLine {
+	pv := make([]*Move, len(reversedMainLine))
+	for i, move := range reversedMainLine {
+		pv[len(pv)-1-i] = move
+	}
+
+	return AnalysisLine{Move: move, Score: score, Depth: depth, PV: pv}
+}
+
+func isMoveExcluded(move *Move, excludedMoves []*Move) bool {
+	for _, excluded := range excludedMoves {
+		if excluded == move {
+			return true
+		}
 	}
-	fmt.Println()
 
-	return false, bestMove, bestScore
+	return false
+}
+
+// extension returns how many plies deeper than the nominal depth should be searched the position
+// reached with the move just played, to see further in forcing sequences
+func (eng *BruteForceEngine) extension(move *Move, previousMove *Move, legalMovesCount int) int {
+	// Limiting the extensions to twice the nominal depth prevents the search from exploding
+	if eng.ply() > 2*eng.rootDepth {
+		return 0
+	}
+
+	switch {
+	case eng.CheckExtensionsEnabled && eng.game.position.inCheck:
+		return 1
+	case eng.SingleReplyExtensionsEnabled && legalMovesCount == 1:
+		return 1
+	case eng.RecaptureExtensionsEnabled && previousMove != nil && previousMove.IsCapture() &&
+		move.IsCapture() && move.To() == previousMove.To():
+		return 1
+	}
+
+	return 0
+}
+
+// updateSelDepth records the distance of the current position from the root in the statistics
+func (eng *BruteForceEngine) updateSelDepth() {
+	if ply := eng.ply(); ply > eng.stats.selDepth {
+		eng.stats.selDepth = ply
+	}
+}
+
+// countNode records a visited node in the statistics and stops the search when the node limit is reached.
+// Only the main thread counts towards the limit, so that the limit doesn't depend on the helpers
+func (eng *BruteForceEngine) countNode() {
+	eng.stats.nodes++
+	eng.updateSelDepth()
+
+	if eng.MaxNodes > 0 && eng.helperID == 0 && eng.stats.nodes >= eng.MaxNodes {
+		eng.Stop()
+	}
 }
 
 func (eng *BruteForceEngine) recNegaMax(depth int, alpha int, beta int, evaluationCache *ZobristTable, quiescentCache *ZobristTable) (int, []*Move) {
-	_nodeHits++
+	if eng.stopped() {
+		return 0, []*Move{}
+	}
+	eng.countNode()
 
 	switch eng.game.Result() {
 	case Draw:
 		return DrawScore, []*Move{}
-	case Checkmate:
-		return CheckmateScore, []*Move{}
+	case Checkmate, VariantLoss:
+		return CheckmateScore + eng.ply(), []*Move{}
+	case VariantWin:
+		return MateScore - eng.ply(), []*Move{}
+	}
+
+	// Mate distance pruning: even delivering mate on the next move can't score better than
+	// a mate already found closer to the root, and being mated here is the worst possible outcome
+	if eng.MateDistancePruningEnabled {
+		if alpha < CheckmateScore+eng.ply() {
+			alpha = CheckmateScore + eng.ply()
+		}
+		if beta > MateScore-eng.ply()-1 {
+			beta = MateScore - eng.ply() - 1
+		}
+		if alpha >= beta {
+			return alpha, []*Move{}
+		}
+	}
+
+	// The stack of positions can't grow indefinitely
+	if eng.ply() >= MaxPly {
+		return eng.StaticEvaluation(), []*Move{}
 	}
 
 	if depth == 0 {
 		// When reaching depth 0 we can procede the search deeper but considering only capture
 		// moves, this way mitigate the horizon effect and correctly assess trades
 		if eng.QuiescentSearchEnabled {
-			_nodeHits-- // avoid double counting this node
+			eng.stats.nodes-- // avoid double counting this node
 			return eng.quiescentSearch(7, alpha, beta, evaluationCache, quiescentCache)
 		}
 
@@ -221,12 +772,12 @@ func (eng *BruteForceEngine) recNegaMax(depth int, alpha int, beta int, evaluati
 
 	// If this position's evaluation is cached we don't need to recompute it, we could have also stored a lower bound
 	// because the search had been stopped by alpha-beta pruning.
-	if found, value := evaluationCache.Get(eng.game.position.hash); eng.TranspositionTableEnabled && found && value.Depth() >= depth {
+	if found, value := eng.cacheGet(evaluationCache, eng.game.position.hash); eng.transpositionTableEnabled() && found && value.Depth() >= depth {
 		if !value.LowerBound() {
-			return value.Evaluation(), mainLine
+			return scoreFromCache(value.Evaluation(), eng.ply()), mainLine
 		}
 
-		bestScore := value.Evaluation()
+		bestScore := scoreFromCache(value.Evaluation(), eng.ply())
 		if bestScore > alpha {
 			alpha = bestScore
 
@@ -245,13 +796,20 @@ func (eng *BruteForceEngine) recNegaMax(depth int, alpha int, beta int, evaluati
 		legalMoves = eng.game.LegalMoves()
 	}
 
+	previousMove := eng.game.lastMove()
 	for i := 0; i < len(legalMoves); i++ {
 		eng.game.Move(legalMoves[i])
+		extension := eng.extension(legalMoves[i], previousMove, len(legalMoves))
 
-		score, variation := eng.recNegaMax(depth-1, -beta, -alpha, evaluationCache, quiescentCache)
+		score, variation := eng.recNegaMax(depth-1+extension, -beta, -alpha, evaluationCache, quiescentCache)
 		score = -score
 		eng.game.UndoMove()
 
+		// Don't store the results of an interrupted search in the cache
+		if eng.stopped() {
+			return 0, []*Move{}
+		}
+
 		if score > bestScore {
 			bestScore = score
 			mainLine = append(variation, legalMoves[i])
@@ -263,7 +821,7 @@ func (eng *BruteForceEngine) recNegaMax(depth int, alpha int, beta int, evaluati
 				// previous move will opt for a move giving us a weaker position.
 				if alpha > beta && eng.AlphaBetaPruningEnabled {
 					// Store evaluation in the cache
-					hash := eng.game.position.hash.HashValue().SetData(int16(alpha), int8(depth), true)
+					hash := eng.game.position.hash.HashValue().SetData(int16(scoreToCache(alpha, eng.ply())), int8(depth), true)
 					evaluationCache.Set(eng.game.position.hash.Key(), hash)
 
 					return alpha, mainLine
@@ -273,20 +831,22 @@ func (eng *BruteForceEngine) recNegaMax(depth int, alpha int, beta int, evaluati
 	}
 
 	// Store evaluation in the cache
-	hash := eng.game.position.hash.HashValue().SetData(int16(bestScore), int8(depth), false)
+	hash := eng.game.position.hash.HashValue().SetData(int16(scoreToCache(bestScore, eng.ply())), int8(depth), false)
 	evaluationCache.Set(eng.game.position.hash.Key(), hash)
 
 	return bestScore, mainLine
 }
 
 func (eng *BruteForceEngine) quiescentSearch(depth int, alpha int, beta int, evaluationCache *ZobristTable, quiescentCache *ZobristTable) (int, []*Move) {
-	_nodeHits++
+	eng.countNode()
 
 	switch eng.game.Result() {
 	case Draw:
 		return DrawScore, []*Move{}
-	case Checkmate:
-		return CheckmateScore, []*Move{}
+	case Checkmate, VariantLoss:
+		return CheckmateScore + eng.ply(), []*Move{}
+	case VariantWin:
+		return MateScore - eng.ply(), []*Move{}
 	}
 
 	// At depth 0 we statically evaluate the position with the implemented heuristics
@@ -306,12 +866,12 @@ func (eng *BruteForceEngine) quiescentSearch(depth int, alpha int, beta int, eva
 	// Inside the quiescent search we can read evaluations from both the quiescent evaluations cache
 	// and the full evaluation cache; the latter don't need to be depth checked, because they surely
 	// searched deeper than the quiescent search would do.
-	if found, value := quiescentCache.Get(eng.game.position.hash); eng.TranspositionTableEnabled && found && value.Depth() >= depth {
+	if found, value := eng.cacheGet(quiescentCache, eng.game.position.hash); eng.transpositionTableEnabled() && found && value.Depth() >= depth {
 		if !value.LowerBound() {
-			return value.Evaluation(), mainLine
+			return scoreFromCache(value.Evaluation(), eng.ply()), mainLine
 		}
 
-		bestScore = value.Evaluation()
+		bestScore = scoreFromCache(value.Evaluation(), eng.ply())
 		if bestScore > alpha {
 			alpha = bestScore
 
@@ -319,12 +879,12 @@ func (eng *BruteForceEngine) quiescentSearch(depth int, alpha int, beta int, eva
 				return alpha, mainLine
 			}
 		}
-	} else if found, value := evaluationCache.Get(eng.game.position.hash); eng.TranspositionTableEnabled && found {
+	} else if found, value := eng.cacheGet(evaluationCache, eng.game.position.hash); eng.transpositionTableEnabled() && found {
 		if !value.LowerBound() {
-			return value.Evaluation(), mainLine
+			return scoreFromCache(value.Evaluation(), eng.ply()), mainLine
 		}
 
-		bestScore = value.Evaluation()
+		bestScore = scoreFromCache(value.Evaluation(), eng.ply())
 		if bestScore > alpha {
 			alpha = bestScore
 
@@ -375,7 +935,7 @@ func (eng *BruteForceEngine) quiescentSearch(depth int, alpha int, beta int, eva
 
 					if alpha > beta && eng.AlphaBetaPruningEnabled {
 						// Store the evaluation in the cache
-						hash := eng.game.position.hash.HashValue().SetData(int16(alpha), int8(depth), true)
+						hash := eng.game.position.hash.HashValue().SetData(int16(scoreToCache(alpha, eng.ply())), int8(depth), true)
 						quiescentCache.Set(eng.game.position.hash.Key(), hash)
 						return alpha, mainLine
 					}
@@ -390,7 +950,7 @@ func (eng *BruteForceEngine) quiescentSearch(depth int, alpha int, beta int, eva
 	}
 
 	// Store the evaluation in the cache
-	hash := eng.game.position.hash.HashValue().SetData(int16(bestScore), int8(depth), true)
+	hash := eng.game.position.hash.HashValue().SetData(int16(scoreToCache(bestScore, eng.ply())), int8(depth), true)
 	quiescentCache.Set(eng.game.position.hash.Key(), hash)
 
 	return bestScore, mainLine
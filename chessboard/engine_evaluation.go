@@ -1,62 +1,105 @@
 package chessboard
 
-func materialDifference(pos *Position) int {
-	whitePawnCount := pos.board.bbWhitePawn.PopCount() * 256
-	blackPawnCount := pos.board.bbBlackPawn.PopCount() * 256
-	whiteKnightCount := pos.board.bbWhiteKnight.PopCount() * 832
-	blackKnightCount := pos.board.bbBlackKnight.PopCount() * 832
-	whiteBishopCount := pos.board.bbWhiteBishop.PopCount() * 896
-	blackBishopCount := pos.board.bbBlackBishop.PopCount() * 896
-	whiteRookCount := pos.board.bbWhiteRook.PopCount() * 1280
-	blackRookCount := pos.board.bbBlackRook.PopCount() * 1280
-	whiteQueenCount := pos.board.bbWhiteQueen.PopCount() * 2496
-	blackQueenCount := pos.board.bbBlackQueen.PopCount() * 2496
-
-	whiteTotal := whitePawnCount +
-		whiteKnightCount +
-		whiteBishopCount +
-		whiteRookCount +
-		whiteQueenCount
-	blackTotal := blackPawnCount +
-		blackKnightCount +
-		blackBishopCount +
-		blackRookCount +
-		blackQueenCount
-
-	difference := whiteTotal - blackTotal
-	total := whiteTotal + blackTotal
-	pieceRatio := (difference * 100) / (total + 1)
-
-	return difference +
-		pieceRatio
-}
-
-// Values from https://www.chessprogramming.org/Simplified_Evaluation_Function converted to 256th of a pawn
-var pawnSquareValue = [64]int{0, 0, 0, 0, 0, 0, 0, 0, 12, 25, 25, -51, -51, 25, 25, 12, 12, -12, -25, 0, 0, -25, -12, 12, 0, 0, 0, 51, 51, 0, 0, 0, 12, 12, 25, 64, 64, 25, 12, 12, 25, 25, 51, 76, 76, 51, 25, 25, 128, 128, 128, 128, 128, 128, 128, 128, 0, 0, 0, 0, 0, 0, 0, 0}
-var knightSquareValue = [64]int{-128, -102, -76, -76, -76, -76, -102, -128, -102, -51, 0, 12, 12, 0, -51, -102, -76, 12, 25, 38, 38, 25, 12, -76, -76, 0, 38, 51, 51, 38, 0, -76, -76, 12, 38, 51, 51, 38, 12, -76, -76, 0, 25, 38, 38, 25, 0, -76, -102, -51, 0, 0, 0, 0, -51, -102, -128, -102, -76, -76, -76, -76, -102, -128}
-var bishopSquareValue = [64]int{-51, -25, -25, -25, -25, -25, -25, -51, -25, 12, 0, 0, 0, 0, 12, -25, -25, 25, 25, 25, 25, 25, 25, -25, -25, 0, 25, 25, 25, 25, 0, -25, -25, 12, 12, 25, 25, 12, 12, -25, -25, 0, 12, 25, 25, 12, 0, -25, -25, 0, 0, 0, 0, 0, 0, -25, -51, -25, -25, -25, -25, -25, -25, -51}
-var rookSquareValue = [64]int{0, 0, 0, 12, 12, 0, 0, 0, -12, 0, 0, 0, 0, 0, 0, -12, -12, 0, 0, 0, 0, 0, 0, -12, -12, 0, 0, 0, 0, 0, 0, -12, -12, 0, 0, 0, 0, 0, 0, -12, -12, 0, 0, 0, 0, 0, 0, -12, 12, 25, 25, 25, 25, 25, 25, 12, 0, 0, 0, 0, 0, 0, 0, 0}
-var queenSquareValue = [64]int{-51, -25, -25, -12, -12, -25, -25, -51, -25, 0, 12, 0, 0, 0, 0, -25, -25, 12, 12, 12, 12, 12, 0, -25, 0, 0, 12, 12, 12, 12, 0, -12, -12, 0, 12, 12, 12, 12, 0, -12, -25, 0, 12, 12, 12, 12, 0, -25, -25, 0, 0, 0, 0, 0, 0, -25, -51, -25, -25, -12, -12, -25, -25, -51}
-var kingMiddleGameSquareValue = [64]int{51, 76, 25, 0, 0, 25, 76, 51, 51, 51, 0, 0, 0, 0, 51, 51, -25, -51, -51, -51, -51, -51, -51, -25, -51, -76, -76, -102, -102, -76, -76, -51, -76, -102, -102, -128, -128, -102, -102, -76, -76, -102, -102, -128, -128, -102, -102, -76, -76, -102, -102, -128, -128, -102, -102, -76, -76, -102, -102, -128, -128, -102, -102, -76}
-var kingEndGameSquareValue = [64]int{-128, -76, -76, -76, -76, -76, -76, -128, -76, -76, 0, 0, 0, 0, -76, -76, -76, -25, 51, 76, 76, 51, -25, -76, -76, -25, 76, 102, 102, 76, -25, -76, -76, -25, 76, 102, 102, 76, -25, -76, -76, -25, 51, 76, 76, 51, -25, -76, -76, -51, -25, 0, 0, -25, -51, -76, -128, -102, -76, -51, -51, -76, -102, -128}
-
-func positionDifference(pos *Position) int {
-	score := 0
+import "fmt"
+
+// maxGamePhase is the game phase of a position with all the pieces on the board
+const maxGamePhase = 24
+
+// Contribution of each piece to the game phase, pawns and kings don't count
+const (
+	knightPhase = 1
+	bishopPhase = 1
+	rookPhase   = 2
+	queenPhase  = 4
+)
+
+// TaperedScore contains the value of an evaluation term in the middle game and in the end game
+type TaperedScore struct {
+	MiddleGame int
+	EndGame    int
+}
 
-	score += pieceSquareValues(pos.board.bbWhitePawn, &pawnSquareValue, true) -
-		pieceSquareValues(pos.board.bbBlackPawn, &pawnSquareValue, false)
-	score += pieceSquareValues(pos.board.bbWhiteKnight, &knightSquareValue, true) -
-		pieceSquareValues(pos.board.bbBlackKnight, &knightSquareValue, false)
-	score += pieceSquareValues(pos.board.bbWhiteBishop, &bishopSquareValue, true) -
-		pieceSquareValues(pos.board.bbBlackBishop, &bishopSquareValue, false)
-	score += pieceSquareValues(pos.board.bbWhiteRook, &rookSquareValue, true) -
-		pieceSquareValues(pos.board.bbBlackRook, &rookSquareValue, false)
-	score += pieceSquareValues(pos.board.bbWhiteQueen, &queenSquareValue, true) -
-		pieceSquareValues(pos.board.bbBlackQueen, &queenSquareValue, false)
-	score += pieceSquareValues(pos.board.bbWhiteKing, &kingMiddleGameSquareValue, true) -
-		pieceSquareValues(pos.board.bbBlackKing, &kingMiddleGameSquareValue, false)
+func (s TaperedScore) add(other TaperedScore) TaperedScore {
+	return TaperedScore{s.MiddleGame + other.MiddleGame, s.EndGame + other.EndGame}
+}
 
-	return score
+func (s TaperedScore) sub(other TaperedScore) TaperedScore {
+	return TaperedScore{s.MiddleGame - other.MiddleGame, s.EndGame - other.EndGame}
+}
+
+func (s TaperedScore) mul(factor int) TaperedScore {
+	return TaperedScore{s.MiddleGame * factor, s.EndGame * factor}
+}
+
+// Taper interpolates between the middle game and end game values according to the game phase
+func (s TaperedScore) Taper(phase int) int {
+	return (s.MiddleGame*phase + s.EndGame*(maxGamePhase-phase)) / maxGamePhase
+}
+
+// gamePhase returns a number between 0 (only pawns and kings left) and maxGamePhase
+// (all the pieces on the board) computed from the remaining non-pawn material
+func gamePhase(pos *Position) int {
+	phase := (pos.board.bbWhiteKnight|pos.board.bbBlackKnight).PopCount()*knightPhase +
+		(pos.board.bbWhiteBishop|pos.board.bbBlackBishop).PopCount()*bishopPhase +
+		(pos.board.bbWhiteRook|pos.board.bbBlackRook).PopCount()*rookPhase +
+		(pos.board.bbWhiteQueen|pos.board.bbBlackQueen).PopCount()*queenPhase
+
+	// Promotions can bring the phase over the starting one
+	if phase > maxGamePhase {
+		phase = maxGamePhase
+	}
+
+	return phase
+}
+
+// material returns the value of the pieces of both sides
+func material(pos *Position, params *EvalParams) (TaperedScore, TaperedScore) {
+	return materialForSide(pos.board.sidePieces(WhiteColor), params), materialForSide(pos.board.sidePieces(BlackColor), params)
+}
+
+func materialForSide(pieces sidePieces, params *EvalParams) TaperedScore {
+	return params.PawnValue.mul(pieces.pawns.PopCount()).
+		add(params.KnightValue.mul(pieces.knights.PopCount())).
+		add(params.BishopValue.mul(pieces.bishops.PopCount())).
+		add(params.RookValue.mul(pieces.rooks.PopCount())).
+		add(params.QueenValue.mul(pieces.queens.PopCount()))
+}
+
+// pieceRatio rewards trading pieces when ahead in material, the returned score is
+// positive when white is ahead
+func pieceRatio(white TaperedScore, black TaperedScore, params *EvalParams) TaperedScore {
+	difference := white.sub(black)
+	total := white.add(black)
+
+	return TaperedScore{
+		(difference.MiddleGame * params.PieceRatioWeight) / (total.MiddleGame + 1),
+		(difference.EndGame * params.PieceRatioWeight) / (total.EndGame + 1),
+	}
+}
+
+// splitScore splits a score from white's point of view between the two sides,
+// each side gets the parts of the score in its favour
+func splitScore(score TaperedScore) (TaperedScore, TaperedScore) {
+	white, black := TaperedScore{}, TaperedScore{}
+
+	if score.MiddleGame > 0 {
+		white.MiddleGame = score.MiddleGame
+	} else {
+		black.MiddleGame = -score.MiddleGame
+	}
+	if score.EndGame > 0 {
+		white.EndGame = score.EndGame
+	} else {
+		black.EndGame = -score.EndGame
+	}
+
+	return white, black
+}
+
+// pieceSquares returns the piece-square table scores of a piece type for both sides
+func pieceSquares(white Bitboard, black Bitboard, middleGame *[64]int, endGame *[64]int) (TaperedScore, TaperedScore) {
+	return TaperedScore{pieceSquareValues(white, middleGame, true), pieceSquareValues(white, endGame, true)},
+		TaperedScore{pieceSquareValues(black, middleGame, false), pieceSquareValues(black, endGame, false)}
 }
 
 func pieceSquareValues(piece Bitboard, values *[64]int, isWhite bool) int {
@@ -84,118 +127,289 @@ func pieceSquareValues(piece Bitboard, values *[64]int, isWhite bool) int {
 
 var centerBitboard = D4.Bitboard() | D5.Bitboard() | E4.Bitboard() | E5.Bitboard()
 
-func centerControl(pos *Position) int {
-	score := 0
+func centerControl(pos *Position, params *EvalParams) (TaperedScore, TaperedScore) {
+	white, black := TaperedScore{}, TaperedScore{}
 
 	if pos.board.bbWhitePawn&centerBitboard != 0 {
-		score += 70
+		white = params.CenterControlBonus
 	}
 	if pos.board.bbBlackPawn&centerBitboard != 0 {
-		score -= 70
+		black = params.CenterControlBonus
 	}
 
-	return score
+	return white, black
 }
 
 // Computes penalties for
-// - doubled pawns, that is when there are 2 same color pawns in the same file
-//   and in the neighbouring files there are no pawns of that same color
-// - isolated pawn, that is a file with a single pawn and without pawns in the neighbouring files
-func doubledOrIsolatedPawnsPenalties(pos *Position, precomputedData *PrecomputedData) int {
-	score := 0
+//   - doubled pawns, that is when there are 2 same color pawns in the same file
+//     and in the neighbouring files there are no pawns of that same color
+//   - isolated pawn, that is a file with a single pawn and without pawns in the neighbouring files
+func doubledOrIsolatedPawnsPenalties(pos *Position, precomputedData *PrecomputedData, params *EvalParams) (TaperedScore, TaperedScore) {
+	return doubledOrIsolatedPawnsForSide(pos.board.bbWhitePawn, precomputedData, params),
+		doubledOrIsolatedPawnsForSide(pos.board.bbBlackPawn, precomputedData, params)
+}
+
+func doubledOrIsolatedPawnsForSide(pawns Bitboard, precomputedData *PrecomputedData, params *EvalParams) TaperedScore {
+	score := TaperedScore{}
 
-	whitePawns := pos.board.bbWhitePawn
-	for whitePawns != 0 {
-		sq := whitePawns.LeastSignificant1Bit()
-		whitePawns.ClearLeastSignificant1Bit()
+	remaining := pawns
+	for remaining != 0 {
+		sq := remaining.LeastSignificant1Bit()
+		remaining.ClearLeastSignificant1Bit()
 
-		if precomputedData.DoublePawnsSidesMasks[sq]&pos.board.bbWhitePawn == 0 {
-			if precomputedData.DoublePawnsForwardMasks[sq]&pos.board.bbWhitePawn != 0 {
+		if precomputedData.DoublePawnsSidesMasks[sq]&pawns == 0 {
+			if precomputedData.DoublePawnsForwardMasks[sq]&pawns != 0 {
 				// doubled isolated pawns
-				score -= 90
+				score = score.add(params.DoubledIsolatedPawnPenalty)
 			} else {
 				// isolated pawn
-				score -= 45
+				score = score.add(params.IsolatedPawnPenalty)
 			}
-		} else if precomputedData.DoublePawnsForwardMasks[sq]&pos.board.bbWhitePawn != 0 {
+		} else if precomputedData.DoublePawnsForwardMasks[sq]&pawns != 0 {
 			// doubled pawn with neighbouring pawn
-			score -= 45
+			score = score.add(params.DoubledPawnPenalty)
 		}
-
 	}
 
-	blackPawns := pos.board.bbBlackPawn
-	for blackPawns != 0 {
-		sq := blackPawns.LeastSignificant1Bit()
-		blackPawns.ClearLeastSignificant1Bit()
+	return score
+}
 
-		if precomputedData.DoublePawnsSidesMasks[sq]&pos.board.bbBlackPawn == 0 {
-			if precomputedData.DoublePawnsForwardMasks[sq]&pos.board.bbBlackPawn != 0 {
-				// doubled pawns
-				score += 90
-			} else {
-				// isolated pawn
-				score += 45
-			}
-		} else if precomputedData.DoublePawnsForwardMasks[sq]&pos.board.bbBlackPawn != 0 {
-			// doubled pawn with neighbouring pawn
-			score += 45
-		}
-	}
+// TraceScore is the contribution of an evaluation term to the score of one side
+type TraceScore struct {
+	MiddleGame int `json:"middleGame"`
+	EndGame    int `json:"endGame"`
+	// Tapered is the score interpolated according to the game phase
+	Tapered int `json:"tapered"`
+}
 
-	return score
+func newTraceScore(score TaperedScore, phase int) TraceScore {
+	return TraceScore{score.MiddleGame, score.EndGame, score.Taper(phase)}
 }
 
-// Compute bonuses for passed pawns
-func passedPawnsBonuses(pos *Position, precomputedData *PrecomputedData) int {
-	score := 0
+// TraceTerm is a single term of the static evaluation, each side gets its own score
+type TraceTerm struct {
+	Name  string     `json:"name"`
+	White TraceScore `json:"white"`
+	Black TraceScore `json:"black"`
+	// Score is the tapered difference between the white and black scores
+	Score int `json:"score"`
+}
 
-	whitePawns := pos.board.bbWhitePawn
-	for whitePawns != 0 {
-		sq := whitePawns.LeastSignificant1Bit()
-		whitePawns.ClearLeastSignificant1Bit()
+// EvalTrace is the static evaluation of a position split in its terms, scores are
+// in 256th of a pawn and positive scores are good for white
+type EvalTrace struct {
+	// Phase goes from 0 (only pawns and kings left) to MaxPhase (all the pieces on the board)
+	Phase    int         `json:"phase"`
+	MaxPhase int         `json:"maxPhase"`
+	Terms    []TraceTerm `json:"terms"`
+	// Tempo is the bonus given to the side to move
+	Tempo int `json:"tempo"`
+	// Endgame is the name of the specialised endgame evaluation or scaling rule applied to the position,
+	// when the position has a specialised evaluation there are no terms
+	Endgame string `json:"endgame,omitempty"`
+	// Scale multiplies the end game score, from 0 (a draw) to MaxScale (the score is unchanged)
+	Scale    int `json:"scale"`
+	MaxScale int `json:"maxScale"`
+	// Total is the static evaluation from white's point of view
+	Total int `json:"total"`
+}
 
-		if precomputedData.PassedPawnWhiteMasks[sq]&pos.board.bbBlackPawn == 0 {
-			score += 120
-		}
+// String formats the trace as a table with a row for each term, scores are in pawns
+func (trace EvalTrace) String() string {
+	pawns := func(score int) string {
+		return fmt.Sprintf("%+.2f", float64(score)/256)
 	}
 
-	blackPawns := pos.board.bbBlackPawn
-	for blackPawns != 0 {
-		sq := blackPawns.LeastSignificant1Bit()
-		blackPawns.ClearLeastSignificant1Bit()
-
-		if precomputedData.PassedPawnBlackMasks[sq]&pos.board.bbWhitePawn == 0 {
-			score -= 120
-		}
+	str := fmt.Sprintf("%-38s | %-20s | %-20s | %s\n", "Term", "White (MG EG phase)", "Black (MG EG phase)", "Total")
+	for _, term := range trace.Terms {
+		str += fmt.Sprintf("%-38s | %6s %6s %6s | %6s %6s %6s | %6s\n", term.Name,
+			pawns(term.White.MiddleGame), pawns(term.White.EndGame), pawns(term.White.Tapered),
+			pawns(term.Black.MiddleGame), pawns(term.Black.EndGame), pawns(term.Black.Tapered),
+			pawns(term.Score))
+	}
+	str += fmt.Sprintf("%-38s | %20s | %20s | %6s\n", "Tempo", "", "", pawns(trace.Tempo))
+	if trace.Endgame != "" {
+		str += fmt.Sprintf("Endgame: %s, end game scale: %d/%d\n", trace.Endgame, trace.Scale, trace.MaxScale)
 	}
+	str += fmt.Sprintf("Game phase: %d/%d, total evaluation: %s (white side)", trace.Phase, trace.MaxPhase, pawns(trace.Total))
 
-	return score
+	return str
 }
 
 // StaticEvaluation returns an evaluation of the current position from a
 // strategic standpoint (e.g. material imbalances, pawn structures, ...) without
 // considering any tactical advantages (e.g. ability to capture a piece)
 func (eng *BruteForceEngine) StaticEvaluation() int {
-	score := 0
+	// The network is trained on standard chess, variants use the classical evaluation
+	if eng.EvalMode == NetworkEvaluation && eng.game.variant == Standard {
+		// Outside of the search the accumulators are computed from scratch
+		if eng.game.accumulators != nil && eng.game.accumulators.network == eng.Network {
+			return eng.game.accumulators.evaluate(eng.game.position.turn)
+		}
+
+		return eng.Network.Evaluate(*eng.game.position)
+	}
+
+	phase := gamePhase(eng.game.position)
+	score := eng.evaluate(eng.game.position, eng.game.precomputedData, eng.game.variant, phase, nil)
+
+	return score * int(eng.game.position.turn)
+}
+
+// Evaluate returns the classical static evaluation of the passed position split in its terms,
+// it can be called while the engine is searching
+func (eng *BruteForceEngine) Evaluate(pos Position) EvalTrace {
+	trace := EvalTrace{Phase: gamePhase(&pos), MaxPhase: maxGamePhase, Terms: []TraceTerm{}, Scale: scaleFactorNormal, MaxScale: scaleFactorNormal}
+	trace.Total = eng.evaluate(&pos, eng.trackedGame.precomputedData, eng.trackedGame.variant, trace.Phase, &trace)
+
+	return trace
+}
+
+// evaluate computes the static evaluation from white's point of view with the rules of the variant,
+// the terms are recorded in trace when it is not nil
+func (eng *BruteForceEngine) evaluate(pos *Position, precomputedData *PrecomputedData, variant Variant, phase int, trace *EvalTrace) int {
+	params := eng.EvalParams
+	score := TaperedScore{}
+	record := func(name string, white TaperedScore, black TaperedScore) {
+		score = score.add(white).sub(black)
+		if trace != nil {
+			trace.Terms = append(trace.Terms, TraceTerm{name, newTraceScore(white, phase), newTraceScore(black, phase), white.sub(black).Taper(phase)})
+		}
+	}
+
+	// Variants can replace the standard evaluation or add their own terms to it
+	variantWhite, variantBlack, replace := variant.evaluate(pos, params)
+	if replace {
+		record(variant.Name(), variantWhite, variantBlack)
+		return score.Taper(phase)
+	}
+
+	// Endgames with a specialised evaluation skip the generic terms, they
+	// are only valid with the standard rules
+	endgameEval := eng.EndgameEval && variant == Standard
+	var key materialKey
+	if endgameEval {
+		key = newMaterialKey(&pos.board)
+		if endgame, ok := endgames.evaluation(pos, key); ok {
+			value := endgame.evaluate(pos, endgame.strong, params) * int(endgame.strong)
+			if trace != nil {
+				trace.Endgame = endgame.name
+				trace.Total = value
+			}
+
+			return value
+		}
+	}
+
+	var white, black TaperedScore
 	if eng.MaterialDifferenceEval {
-		score += materialDifference(eng.game.position)
+		white, black = material(pos, params)
+		record("Material", white, black)
+
+		white, black = splitScore(pieceRatio(white, black, params))
+		record("Piece ratio", white, black)
 	}
 	if eng.PositionDifferenceEval {
-		score += positionDifference(eng.game.position)
+		board := &pos.board
+		white, black = pieceSquares(board.bbWhitePawn, board.bbBlackPawn, &params.PawnMiddleGameSquareValue, &params.PawnEndGameSquareValue)
+		record("Pawn squares", white, black)
+		white, black = pieceSquares(board.bbWhiteKnight, board.bbBlackKnight, &params.KnightMiddleGameSquareValue, &params.KnightEndGameSquareValue)
+		record("Knight squares", white, black)
+		white, black = pieceSquares(board.bbWhiteBishop, board.bbBlackBishop, &params.BishopMiddleGameSquareValue, &params.BishopEndGameSquareValue)
+		record("Bishop squares", white, black)
+		white, black = pieceSquares(board.bbWhiteRook, board.bbBlackRook, &params.RookMiddleGameSquareValue, &params.RookEndGameSquareValue)
+		record("Rook squares", white, black)
+		white, black = pieceSquares(board.bbWhiteQueen, board.bbBlackQueen, &params.QueenMiddleGameSquareValue, &params.QueenEndGameSquareValue)
+		record("Queen squares", white, black)
+		white, black = pieceSquares(board.bbWhiteKing, board.bbBlackKing, &params.KingMiddleGameSquareValue, &params.KingEndGameSquareValue)
+		record("King squares", white, black)
 	}
 	if eng.CenterControlEval {
-		score += centerControl(eng.game.position)
+		white, black = centerControl(pos, params)
+		record("Center control", white, black)
 	}
-	if eng.DoubledIsolatedPawnsEval {
-		score += doubledOrIsolatedPawnsPenalties(eng.game.position, &eng.game.precomputedData)
+	if eng.DoubledIsolatedPawnsEval || eng.PawnStructureEval || eng.PassedPawnsEval {
+		// The trace can be requested while a search is running on the same engine,
+		// so only the evaluations done by the search use the pawn hash table
+		var pawns pawnEntry
+		if eng.PawnHashTableEnabled && trace == nil {
+			pawns = eng.pawnTableEntry(pos, precomputedData)
+		} else {
+			pawns = evaluatePawns(pos, precomputedData, params)
+		}
+
+		if eng.DoubledIsolatedPawnsEval {
+			record("Doubled and isolated pawns", pawns.white.doubledIsolated, pawns.black.doubledIsolated)
+		}
+		if eng.PawnStructureEval {
+			record("Pawn structure", pawns.white.structure, pawns.black.structure)
+		}
+		if eng.PassedPawnsEval {
+			white, black = passedPawnsBonuses(pos, &pawns, params)
+			record("Passed pawns", white, black)
+		}
 	}
-	if eng.PassedPawnsEval {
-		score += passedPawnsBonuses(eng.game.position, &eng.game.precomputedData)
+
+	var whiteAttacks, blackAttacks sideAttacks
+	if eng.MobilityEval || eng.KingSafetyEval || eng.HangingPiecesEval || eng.TrappedPiecesEval || eng.ThreatsEval {
+		whiteAttacks, blackAttacks = computeAttacks(pos, precomputedData, params)
 	}
 
-	// Stabilizes fluctuations between even and odd depth evaluations
-	score += int(eng.game.position.turn) * 15
+	if eng.MobilityEval {
+		record("Mobility", whiteAttacks.mobility, blackAttacks.mobility)
+	}
+	if eng.KingSafetyEval {
+		white, black = kingSafety(pos, &whiteAttacks, &blackAttacks, params)
+		record("King safety", white, black)
+	}
+	if eng.BishopPairEval {
+		white, black = bishopPair(pos, params)
+		record("Bishop pair", white, black)
+	}
+	if eng.RookFilesEval {
+		white, black = rookFiles(pos, params)
+		record("Rooks on open files and seventh rank", white, black)
+	}
+	if eng.KnightOutpostsEval {
+		white, black = knightOutposts(pos, precomputedData, params)
+		record("Knight outposts", white, black)
+	}
+	if eng.HangingPiecesEval {
+		white, black = hangingPieces(pos, &whiteAttacks, &blackAttacks, params)
+		record("Hanging pieces", white, black)
+	}
+	if eng.TrappedPiecesEval {
+		white, black = trappedPieces(&whiteAttacks, &blackAttacks, params)
+		record("Trapped pieces", white, black)
+	}
+	if eng.ThreatsEval {
+		white, black = threats(pos, &whiteAttacks, &blackAttacks, params)
+		record("Threats", white, black)
+	}
 
-	return score * int(eng.game.position.turn)
+	if variant != Standard {
+		record(variant.Name(), variantWhite, variantBlack)
+	}
+
+	// Drawish endgames reduce the end game score of the side which is ahead
+	if endgameEval {
+		strong := WhiteColor
+		if score.EndGame < 0 {
+			strong = BlackColor
+		}
+
+		name, scale := endgames.scaleFactor(pos, key, strong, params)
+		score.EndGame = score.EndGame * scale / scaleFactorNormal
+		if trace != nil {
+			trace.Endgame = name
+			trace.Scale = scale
+		}
+	}
+
+	tempo := int(pos.turn) * params.TempoBonus
+	if trace != nil {
+		trace.Tempo = tempo
+	}
+
+	return score.Taper(phase) + tempo
 }
@@ -0,0 +1,173 @@
+package chessboard
+
+// pawnTableSize is the number of bits of the pawn hash used to index the pawn table
+const pawnTableSize = 14
+
+// pawnScores contains the scores of one side for the terms depending only on the pawns
+type pawnScores struct {
+	doubledIsolated TaperedScore
+	structure       TaperedScore
+	passed          TaperedScore
+}
+
+// pawnEntry contains the evaluation of the terms depending only on the pawns
+type pawnEntry struct {
+	hash        ZobristHash
+	white       pawnScores
+	black       pawnScores
+	whitePassed Bitboard
+	blackPassed Bitboard
+}
+
+// pawnTable caches the pawn structure evaluation indexed by the pawn hash of the position.
+// Pawn structures repeat a lot during the search, so most evaluations are found in the table
+type pawnTable struct {
+	// params are the evaluation parameters used to fill the table
+	params  *EvalParams
+	entries [1 << pawnTableSize]pawnEntry
+}
+
+// pawnTableEntry returns the pawn structure evaluation of the position, reading it
+// from the pawn table when available
+func (eng *BruteForceEngine) pawnTableEntry(pos *Position, precomputedData *PrecomputedData) pawnEntry {
+	// The entries computed with other parameters are stale
+	if eng.pawnTable == nil || eng.pawnTable.params != eng.EvalParams {
+		eng.pawnTable = &pawnTable{params: eng.EvalParams}
+	}
+
+	// An empty slot matches the positions without pawns, whose evaluation is zero anyway
+	entry := &eng.pawnTable.entries[pos.pawnHash>>(64-pawnTableSize)]
+	if entry.hash != pos.pawnHash {
+		*entry = evaluatePawns(pos, precomputedData, eng.EvalParams)
+	}
+
+	return *entry
+}
+
+// evaluatePawns computes all the terms depending only on the pawns
+func evaluatePawns(pos *Position, precomputedData *PrecomputedData, params *EvalParams) pawnEntry {
+	entry := pawnEntry{hash: pos.pawnHash}
+	entry.white.doubledIsolated, entry.black.doubledIsolated = doubledOrIsolatedPawnsPenalties(pos, precomputedData, params)
+	entry.white.structure, entry.white.passed, entry.whitePassed = pawnStructureForSide(pos, precomputedData, WhiteColor, params)
+	entry.black.structure, entry.black.passed, entry.blackPassed = pawnStructureForSide(pos, precomputedData, BlackColor, params)
+
+	return entry
+}
+
+// frontSquares returns the squares in front of the pawn in its own and neighbouring files
+func frontSquares(precomputedData *PrecomputedData, sq Square, color Color) Bitboard {
+	if color == WhiteColor {
+		return precomputedData.PassedPawnWhiteMasks[sq]
+	}
+
+	return precomputedData.PassedPawnBlackMasks[sq]
+}
+
+// supportSquares returns the squares in the neighbouring files on the same rank
+// of the pawn or behind it, where pawns that can defend it are
+func supportSquares(precomputedData *PrecomputedData, sq Square, color Color) Bitboard {
+	if relativeRank(sq, color) == 7 {
+		return 0
+	}
+
+	stopSquare := Square(int(sq) + 8*int(color))
+	return frontSquares(precomputedData, stopSquare, color.Other()) &^ fileBitboard(sq)
+}
+
+// Computes for a single side
+//   - bonuses for supported pawns and pawns side by side (phalanx)
+//   - penalties for backward pawns, that is pawns which can't be defended by other pawns
+//     and can't advance safely
+//   - bonuses for candidate passed pawns, that is pawns on a file without enemy pawns
+//     with enough supporters to force their way through the enemy pawns
+//   - penalties for each group of pawns (island) after the first
+//   - bonuses for passed pawns depending on their rank
+//
+// Returns the structure score, the passed pawns score and the passed pawns
+func pawnStructureForSide(pos *Position, precomputedData *PrecomputedData, color Color, params *EvalParams) (TaperedScore, TaperedScore, Bitboard) {
+	structure := TaperedScore{}
+	passed := TaperedScore{}
+	passedPawns := Bitboard(0)
+
+	own := pos.board.sidePieces(color).pawns
+	enemy := pos.board.sidePieces(color.Other()).pawns
+	ownAttacks := pawnAttacks(own, color)
+	enemyAttacks := pawnAttacks(enemy, color.Other())
+
+	pawns := own
+	files := 0
+	for pawns != 0 {
+		sq := Square(pawns.LeastSignificant1Bit())
+		pawns.ClearLeastSignificant1Bit()
+
+		files |= 1 << (sq % 8)
+		file := fileBitboard(sq)
+		front := frontSquares(precomputedData, sq, color)
+		support := supportSquares(precomputedData, sq, color)
+
+		if front&enemy == 0 && front&file&own == 0 {
+			passedPawns |= sq.Bitboard()
+			passed = passed.add(params.PassedPawnRankBonus[relativeRank(sq, color)])
+		} else if front&file&(own|enemy) == 0 && (support&own).PopCount() >= (front&enemy).PopCount() {
+			structure = structure.add(params.CandidatePasserBonus)
+		}
+
+		if ownAttacks&sq.Bitboard() != 0 {
+			structure = structure.add(params.SupportedPawnBonus)
+		}
+
+		sides := (sq.Bitboard()&^fileABitboard)>>1 | (sq.Bitboard()&^fileHBitboard)<<1
+		if own&sides != 0 {
+			structure = structure.add(params.PhalanxPawnBonus)
+		}
+
+		// Isolated pawns are already penalized on their own
+		isolated := precomputedData.DoublePawnsSidesMasks[sq]&own == 0
+		if !isolated && support&own == 0 && forward(sq.Bitboard(), color)&enemyAttacks != 0 {
+			structure = structure.add(params.BackwardPawnPenalty)
+		}
+	}
+
+	// Each island starts with a file with pawns whose left neighbour has none
+	islands := Bitboard(files &^ (files << 1)).PopCount()
+	if islands > 1 {
+		structure = structure.add(params.PawnIslandPenalty.mul(islands - 1))
+	}
+
+	return structure, passed, passedPawns
+}
+
+// Computes bonuses for passed pawns: the rank bonus from the pawn structure is increased
+// when the enemy king is far from the pawn and our king close to it, and it is halved
+// when the square in front of the pawn is blocked
+func passedPawnsBonuses(pos *Position, pawns *pawnEntry, params *EvalParams) (TaperedScore, TaperedScore) {
+	return pawns.white.passed.add(passedPawnsForSide(pos, pawns.whitePassed, WhiteColor, params)),
+		pawns.black.passed.add(passedPawnsForSide(pos, pawns.blackPassed, BlackColor, params))
+}
+
+func passedPawnsForSide(pos *Position, passedPawns Bitboard, color Color, params *EvalParams) TaperedScore {
+	score := TaperedScore{}
+	ownKing := pos.board.kingSquare(color)
+	enemyKing := pos.board.kingSquare(color.Other())
+
+	for passedPawns != 0 {
+		sq := Square(passedPawns.LeastSignificant1Bit())
+		passedPawns.ClearLeastSignificant1Bit()
+
+		rank := relativeRank(sq, color)
+		stopSquare := Square(int(sq) + 8*int(color))
+
+		if weight := rank - 2; weight > 0 {
+			distanceScore := squareDistance(enemyKing, stopSquare)*params.PassedPawnEnemyKingDistance -
+				squareDistance(ownKing, stopSquare)*params.PassedPawnOwnKingDistance
+			score = score.add(TaperedScore{0, distanceScore * weight})
+		}
+
+		if pos.board.emptySquares&stopSquare.Bitboard() == 0 {
+			blockade := params.PassedPawnRankBonus[rank].mul(params.BlockedPassedPawnPercent)
+			score = score.sub(TaperedScore{blockade.MiddleGame / 100, blockade.EndGame / 100})
+		}
+	}
+
+	return score
+}
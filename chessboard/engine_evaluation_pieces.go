@@ -0,0 +1,284 @@
+package chessboard
+
+// sideAttacks contains the squares attacked by the pieces of one side and the
+// statistics collected while computing them
+type sideAttacks struct {
+	pawns   Bitboard
+	knights Bitboard
+	bishops Bitboard
+	rooks   Bitboard
+	queens  Bitboard
+	king    Bitboard
+	all     Bitboard
+
+	mobility TaperedScore
+	// immobile contains the pieces without any safe square to move to
+	immobile Bitboard
+	// kingAttackers is the number of pieces attacking the enemy king zone
+	kingAttackers int
+	// kingAttackUnits sums the weights of the attacks on the enemy king zone
+	kingAttackUnits int
+}
+
+// kingZone returns the squares around the king of the passed color and the ones in front of them
+func kingZone(precomputedData *PrecomputedData, pos *Position, color Color) Bitboard {
+	sq := pos.board.kingSquare(color)
+	zone := precomputedData.KingMoves[sq] | sq.Bitboard()
+
+	return zone | forward(zone, color)
+}
+
+// computeAttacks returns the attacks of both sides
+func computeAttacks(pos *Position, precomputedData *PrecomputedData, params *EvalParams) (sideAttacks, sideAttacks) {
+	whitePawnAttacks := pawnAttacks(pos.board.bbWhitePawn, WhiteColor)
+	blackPawnAttacks := pawnAttacks(pos.board.bbBlackPawn, BlackColor)
+
+	white := computeSideAttacks(pos, precomputedData, WhiteColor, blackPawnAttacks, kingZone(precomputedData, pos, BlackColor), params)
+	black := computeSideAttacks(pos, precomputedData, BlackColor, whitePawnAttacks, kingZone(precomputedData, pos, WhiteColor), params)
+
+	return white, black
+}
+
+func computeSideAttacks(pos *Position, precomputedData *PrecomputedData, color Color, enemyPawnAttacks Bitboard, enemyKingZone Bitboard, params *EvalParams) sideAttacks {
+	pieces := pos.board.sidePieces(color)
+	occupied := ^pos.board.emptySquares
+	attacks := sideAttacks{}
+
+	// Squares occupied by our pieces or defended by enemy pawns are not safe to move to
+	safeSquares := ^pieces.all &^ enemyPawnAttacks
+
+	visit := func(sq Square, pieceAttacks Bitboard, mobility TaperedScore, baseline int, kingAttackWeight int) {
+		safeCount := (pieceAttacks & safeSquares).PopCount()
+		attacks.mobility = attacks.mobility.add(mobility.mul(safeCount - baseline))
+		if safeCount == 0 {
+			attacks.immobile |= sq.Bitboard()
+		}
+
+		if zoneAttacks := pieceAttacks & enemyKingZone; zoneAttacks != 0 {
+			attacks.kingAttackers++
+			attacks.kingAttackUnits += kingAttackWeight * zoneAttacks.PopCount()
+		}
+	}
+
+	knights := pieces.knights
+	for knights != 0 {
+		sq := Square(knights.LeastSignificant1Bit())
+		knights.ClearLeastSignificant1Bit()
+
+		pieceAttacks := precomputedData.KnightMoves[sq]
+		attacks.knights |= pieceAttacks
+		visit(sq, pieceAttacks, params.KnightMobility, params.KnightMobilityBaseline, params.KnightKingAttackWeight)
+	}
+
+	bishops := pieces.bishops
+	for bishops != 0 {
+		sq := Square(bishops.LeastSignificant1Bit())
+		bishops.ClearLeastSignificant1Bit()
+
+		pieceAttacks := bishopAttacks(precomputedData, sq, occupied)
+		attacks.bishops |= pieceAttacks
+		visit(sq, pieceAttacks, params.BishopMobility, params.BishopMobilityBaseline, params.BishopKingAttackWeight)
+	}
+
+	rooks := pieces.rooks
+	for rooks != 0 {
+		sq := Square(rooks.LeastSignificant1Bit())
+		rooks.ClearLeastSignificant1Bit()
+
+		pieceAttacks := rookAttacks(precomputedData, sq, occupied)
+		attacks.rooks |= pieceAttacks
+		visit(sq, pieceAttacks, params.RookMobility, params.RookMobilityBaseline, params.RookKingAttackWeight)
+	}
+
+	queens := pieces.queens
+	for queens != 0 {
+		sq := Square(queens.LeastSignificant1Bit())
+		queens.ClearLeastSignificant1Bit()
+
+		pieceAttacks := rookAttacks(precomputedData, sq, occupied) | bishopAttacks(precomputedData, sq, occupied)
+		attacks.queens |= pieceAttacks
+		visit(sq, pieceAttacks, params.QueenMobility, params.QueenMobilityBaseline, params.QueenKingAttackWeight)
+	}
+
+	attacks.pawns = pawnAttacks(pieces.pawns, color)
+	attacks.king = precomputedData.KingMoves[pos.board.kingSquare(color)]
+	attacks.all = attacks.pawns | attacks.knights | attacks.bishops | attacks.rooks | attacks.queens | attacks.king
+
+	return attacks
+}
+
+// Computes the king safety of both sides from
+// - the attacks of the enemy pieces on the squares around the king
+// - the pawns sheltering the king and the enemy pawns advancing towards it
+func kingSafety(pos *Position, whiteAttacks *sideAttacks, blackAttacks *sideAttacks, params *EvalParams) (TaperedScore, TaperedScore) {
+	return kingSafetyForSide(pos, WhiteColor, blackAttacks, params), kingSafetyForSide(pos, BlackColor, whiteAttacks, params)
+}
+
+func kingSafetyForSide(pos *Position, color Color, enemyAttacks *sideAttacks, params *EvalParams) TaperedScore {
+	score := TaperedScore{}
+
+	// A single attacker is rarely dangerous on its own
+	if enemyAttacks.kingAttackers >= 2 {
+		units := enemyAttacks.kingAttackUnits
+		penalty := units * units * params.KingAttackMiddleGameWeight
+		if penalty > params.MaxKingAttackPenalty {
+			penalty = params.MaxKingAttackPenalty
+		}
+
+		score = score.add(TaperedScore{-penalty, -units * params.KingAttackEndGameWeight})
+	}
+
+	sq := pos.board.kingSquare(color)
+	files := fileBitboard(sq)
+	if sq%8 > 0 {
+		files |= fileBitboard(sq - 1)
+	}
+	if sq%8 < 7 {
+		files |= fileBitboard(sq + 1)
+	}
+
+	ownPawns := pos.board.sidePieces(color).pawns & files
+	enemyPawns := pos.board.sidePieces(color.Other()).pawns & files
+
+	shield := forward(sq.Bitboard(), color)
+	shield |= forward(shield, color)
+	shield |= shield<<1&^fileABitboard | shield>>1&^fileHBitboard
+
+	nearShield := shield & forward(rankBitboard(int(sq/8)), color)
+	score = score.add(params.PawnShieldBonus.mul((ownPawns & nearShield).PopCount()))
+	score = score.add(params.FarPawnShieldBonus.mul((ownPawns & shield &^ nearShield).PopCount()))
+
+	// Enemy pawns up to three ranks in front of the king are storming it
+	storm := shield | forward(shield, color)
+	score = score.add(params.PawnStormPenalty.mul((enemyPawns & storm).PopCount()))
+
+	return score
+}
+
+// Computes a bonus for the side owning two bishops of different color
+func bishopPair(pos *Position, params *EvalParams) (TaperedScore, TaperedScore) {
+	return bishopPairForSide(pos.board.bbWhiteBishop, params), bishopPairForSide(pos.board.bbBlackBishop, params)
+}
+
+func bishopPairForSide(bishops Bitboard, params *EvalParams) TaperedScore {
+	if bishops&lightSquaresBitboard != 0 && bishops&^lightSquaresBitboard != 0 {
+		return params.BishopPairBonus
+	}
+
+	return TaperedScore{}
+}
+
+// Computes bonuses for
+// - rooks on open files, that is files without pawns
+// - rooks on semi-open files, that is files without pawns of the same color
+// - rooks on the seventh rank confining the enemy king or attacking enemy pawns
+func rookFiles(pos *Position, params *EvalParams) (TaperedScore, TaperedScore) {
+	return rookFilesForSide(pos, WhiteColor, params), rookFilesForSide(pos, BlackColor, params)
+}
+
+func rookFilesForSide(pos *Position, color Color, params *EvalParams) TaperedScore {
+	score := TaperedScore{}
+	own := pos.board.sidePieces(color)
+	enemy := pos.board.sidePieces(color.Other())
+
+	rooks := own.rooks
+	for rooks != 0 {
+		sq := Square(rooks.LeastSignificant1Bit())
+		rooks.ClearLeastSignificant1Bit()
+
+		file := fileBitboard(sq)
+		if file&own.pawns == 0 {
+			if file&enemy.pawns == 0 {
+				score = score.add(params.RookOpenFileBonus)
+			} else {
+				score = score.add(params.RookSemiOpenFileBonus)
+			}
+		}
+
+		if relativeRank(sq, color) == 6 {
+			seventhRank := rankBitboard(int(sq / 8))
+			eighthRank := forward(seventhRank, color)
+			if enemy.pawns&seventhRank != 0 || enemy.king&eighthRank != 0 {
+				score = score.add(params.RookSeventhRankBonus)
+			}
+		}
+	}
+
+	return score
+}
+
+// Computes bonuses for knights on outposts, that is squares in the enemy half of the board
+// defended by a pawn which can't be attacked by enemy pawns
+func knightOutposts(pos *Position, precomputedData *PrecomputedData, params *EvalParams) (TaperedScore, TaperedScore) {
+	return knightOutpostsForSide(pos, precomputedData, WhiteColor, params), knightOutpostsForSide(pos, precomputedData, BlackColor, params)
+}
+
+func knightOutpostsForSide(pos *Position, precomputedData *PrecomputedData, color Color, params *EvalParams) TaperedScore {
+	score := TaperedScore{}
+	own := pos.board.sidePieces(color)
+	enemy := pos.board.sidePieces(color.Other())
+	defendedSquares := pawnAttacks(own.pawns, color)
+
+	knights := own.knights
+	for knights != 0 {
+		sq := Square(knights.LeastSignificant1Bit())
+		knights.ClearLeastSignificant1Bit()
+
+		rank := relativeRank(sq, color)
+		if rank < 3 || rank > 5 || defendedSquares&sq.Bitboard() == 0 {
+			continue
+		}
+
+		// The enemy pawns that could attack the knight are the ones in the neighbouring files
+		// in front of it, the same squares checked for passed pawns
+		var frontSquares Bitboard
+		if color == WhiteColor {
+			frontSquares = precomputedData.PassedPawnWhiteMasks[sq]
+		} else {
+			frontSquares = precomputedData.PassedPawnBlackMasks[sq]
+		}
+
+		if frontSquares&^fileBitboard(sq)&enemy.pawns == 0 {
+			score = score.add(params.KnightOutpostBonus)
+		}
+	}
+
+	return score
+}
+
+// Computes penalties for pieces attacked by the opponent and not defended
+func hangingPieces(pos *Position, whiteAttacks *sideAttacks, blackAttacks *sideAttacks, params *EvalParams) (TaperedScore, TaperedScore) {
+	white := pos.board.sidePieces(WhiteColor)
+	black := pos.board.sidePieces(BlackColor)
+
+	whiteHanging := (white.all &^ white.pawns &^ white.king) & blackAttacks.all &^ whiteAttacks.all
+	blackHanging := (black.all &^ black.pawns &^ black.king) & whiteAttacks.all &^ blackAttacks.all
+
+	return params.HangingPiecePenalty.mul(whiteHanging.PopCount()), params.HangingPiecePenalty.mul(blackHanging.PopCount())
+}
+
+// Computes penalties for attacked pieces without any safe square to escape to
+func trappedPieces(whiteAttacks *sideAttacks, blackAttacks *sideAttacks, params *EvalParams) (TaperedScore, TaperedScore) {
+	whiteTrapped := whiteAttacks.immobile & blackAttacks.all
+	blackTrapped := blackAttacks.immobile & whiteAttacks.all
+
+	return params.TrappedPiecePenalty.mul(whiteTrapped.PopCount()), params.TrappedPiecePenalty.mul(blackTrapped.PopCount())
+}
+
+// Computes penalties for pieces attacked by less valuable enemy pieces
+func threats(pos *Position, whiteAttacks *sideAttacks, blackAttacks *sideAttacks, params *EvalParams) (TaperedScore, TaperedScore) {
+	return threatsForSide(pos.board.sidePieces(WhiteColor), blackAttacks, params), threatsForSide(pos.board.sidePieces(BlackColor), whiteAttacks, params)
+}
+
+func threatsForSide(own sidePieces, enemyAttacks *sideAttacks, params *EvalParams) TaperedScore {
+	score := TaperedScore{}
+
+	pieces := own.knights | own.bishops | own.rooks | own.queens
+	score = score.add(params.PawnThreatPenalty.mul((pieces & enemyAttacks.pawns).PopCount()))
+
+	majors := own.rooks | own.queens
+	score = score.add(params.MinorThreatPenalty.mul((majors & (enemyAttacks.knights | enemyAttacks.bishops)).PopCount()))
+	score = score.add(params.RookThreatPenalty.mul((own.queens & enemyAttacks.rooks).PopCount()))
+
+	return score
+}
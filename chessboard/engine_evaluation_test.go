@@ -0,0 +1,266 @@
+package chessboard
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var defaultParams = DefaultEvalParams()
+
+// difference returns the score of a term from white's point of view
+func difference(white TaperedScore, black TaperedScore) TaperedScore {
+	return white.sub(black)
+}
+
+func TestGamePhase(t *testing.T) {
+	tests := []struct {
+		fen   string
+		phase int
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", maxGamePhase},
+		{"4k3/pppppppp/8/8/8/8/PPPPPPPP/4K3 w - - 0 1", 0},
+		{"r3k3/8/8/8/8/8/8/3QK3 w - - 0 1", rookPhase + queenPhase},
+		{"QQQQk3/8/8/8/8/8/8/QQQQK3 w - - 0 1", maxGamePhase},
+	}
+
+	for _, test := range tests {
+		game := NewGameFromFEN(test.fen)
+		if phase := gamePhase(game.position); phase != test.phase {
+			t.Errorf("Game phase of %s should be %d, %d was returned instead", test.fen, test.phase, phase)
+		}
+	}
+}
+
+func TestTaper(t *testing.T) {
+	score := TaperedScore{100, 300}
+
+	if value := score.Taper(maxGamePhase); value != 100 {
+		t.Errorf("Tapered score in the middle game should be 100, %d was returned instead", value)
+	}
+	if value := score.Taper(0); value != 300 {
+		t.Errorf("Tapered score in the end game should be 300, %d was returned instead", value)
+	}
+	if value := score.Taper(maxGamePhase / 2); value != 200 {
+		t.Errorf("Tapered score halfway through the game should be 200, %d was returned instead", value)
+	}
+}
+
+func TestStaticEvaluationSymmetry(t *testing.T) {
+	tests := []struct{ fen, mirroredFen string }{
+		{"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3", "rnbqkb1r/pppp1ppp/5n2/4p3/4P3/2N5/PPPP1PPP/R1BQKBNR b KQkq - 2 3"},
+		{"8/5k2/3p4/8/2P5/8/5K2/8 w - - 0 1", "8/5k2/8/2p5/8/3P4/5K2/8 b - - 0 1"},
+	}
+
+	for _, test := range tests {
+		game := NewGameFromFEN(test.fen)
+		mirroredGame := NewGameFromFEN(test.mirroredFen)
+
+		score := NewBruteForceEngine(&game).StaticEvaluation()
+		mirroredScore := NewBruteForceEngine(&mirroredGame).StaticEvaluation()
+		if score != mirroredScore {
+			t.Errorf("Static evaluation of %s should be equal to the mirrored one %d, %d was returned instead", test.fen, mirroredScore, score)
+		}
+	}
+}
+
+func TestEndGameKingCentralization(t *testing.T) {
+	// In the end game a centralized king is better than one hiding in the corner
+	centralGame := NewGameFromFEN("7k/8/8/8/4K3/8/P7/8 w - - 0 1")
+	cornerGame := NewGameFromFEN("7k/8/8/8/8/8/P7/7K w - - 0 1")
+
+	// The pawn can't be caught, so the specialised KPK evaluation doesn't depend on the king
+	centralEngine, cornerEngine := NewBruteForceEngine(&centralGame), NewBruteForceEngine(&cornerGame)
+	centralEngine.EndgameEval = false
+	cornerEngine.EndgameEval = false
+
+	centralScore := centralEngine.StaticEvaluation()
+	cornerScore := cornerEngine.StaticEvaluation()
+	if centralScore <= cornerScore {
+		t.Errorf("Centralized king evaluation should be greater than %d, %d was returned instead", cornerScore, centralScore)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	game := NewGameFromFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 2 3")
+	eng := NewBruteForceEngine(&game)
+
+	trace := eng.Evaluate(game.Position())
+	if len(trace.Terms) != 20 {
+		t.Errorf("Trace should contain 20 terms, %d were returned instead", len(trace.Terms))
+	}
+
+	total := TaperedScore{}
+	for _, term := range trace.Terms {
+		white := TaperedScore{term.White.MiddleGame, term.White.EndGame}
+		black := TaperedScore{term.Black.MiddleGame, term.Black.EndGame}
+		total = total.add(white).sub(black)
+
+		if term.Score != white.sub(black).Taper(trace.Phase) {
+			t.Errorf("%s score should be the tapered difference of the sides %d, %d was returned instead", term.Name, white.sub(black).Taper(trace.Phase), term.Score)
+		}
+	}
+	if trace.Tempo != -defaultParams.TempoBonus {
+		t.Errorf("Tempo should favour black %d, %d was returned instead", -defaultParams.TempoBonus, trace.Tempo)
+	}
+	if expected := total.Taper(trace.Phase) + trace.Tempo; trace.Total != expected {
+		t.Errorf("Trace total should be %d, %d was returned instead", expected, trace.Total)
+	}
+	if score := eng.StaticEvaluation(); score != -trace.Total {
+		t.Errorf("Static evaluation should be the trace total from black's point of view %d, %d was returned instead", -trace.Total, score)
+	}
+
+	// Both sides have the same material
+	if material := trace.Terms[0]; material.Name != "Material" || material.White != material.Black || material.Score != 0 {
+		t.Errorf("Material should be equal for both sides, %+v was returned instead", material)
+	}
+
+	// Positions other than the tracked one can be evaluated
+	other := NewGameFromFEN("4k3/8/8/8/8/8/8/2B1KB2 w - - 0 1")
+	for _, term := range eng.Evaluate(other.Position()).Terms {
+		if term.Name == "Bishop pair" && (term.White.MiddleGame != defaultParams.BishopPairBonus.MiddleGame || term.Black.MiddleGame != 0) {
+			t.Errorf("Bishop pair should be given to white only, %+v was returned instead", term)
+		}
+	}
+
+	data, err := json.Marshal(trace)
+	if err != nil || !strings.Contains(string(data), `"name":"Material","white":{"middleGame":`) {
+		t.Errorf("Trace should be serialized to JSON, %s was returned instead", data)
+	}
+	if table := trace.String(); strings.Count(table, "\n") != len(trace.Terms)+2 {
+		t.Errorf("Trace table should have a row for each term, %s was returned instead", table)
+	}
+}
+
+func TestPieceEvaluationTerms(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		evaluate func(game *Game) TaperedScore
+		expected TaperedScore
+	}{
+		{"Bishop pair", "4k3/8/8/8/8/8/8/2B1KB2 w - - 0 1",
+			func(game *Game) TaperedScore { return difference(bishopPair(game.position, defaultParams)) }, defaultParams.BishopPairBonus},
+		{"Bishops on the same color", "4k3/8/8/8/8/8/8/1B2KB2 w - - 0 1",
+			func(game *Game) TaperedScore { return difference(bishopPair(game.position, defaultParams)) }, TaperedScore{}},
+		{"Rook on open file", "4k3/p7/8/8/8/8/P7/3RK3 w - - 0 1",
+			func(game *Game) TaperedScore { return difference(rookFiles(game.position, defaultParams)) }, defaultParams.RookOpenFileBonus},
+		{"Rook on semi-open file", "4k3/3p4/8/8/8/8/8/3RK3 w - - 0 1",
+			func(game *Game) TaperedScore { return difference(rookFiles(game.position, defaultParams)) }, defaultParams.RookSemiOpenFileBonus},
+		{"Rook on seventh rank", "4k3/R1P5/8/8/8/8/2p5/4K3 w - - 0 1",
+			func(game *Game) TaperedScore { return difference(rookFiles(game.position, defaultParams)) }, defaultParams.RookOpenFileBonus.add(defaultParams.RookSeventhRankBonus)},
+		{"Black knight outpost", "4k3/8/8/2p5/3n4/8/8/4K3 w - - 0 1",
+			func(game *Game) TaperedScore {
+				return difference(knightOutposts(game.position, game.precomputedData, defaultParams))
+			}, TaperedScore{}.sub(defaultParams.KnightOutpostBonus)},
+		{"Knight attackable by pawns", "4k3/2p5/8/3N4/4P3/8/8/4K3 w - - 0 1",
+			func(game *Game) TaperedScore {
+				return difference(knightOutposts(game.position, game.precomputedData, defaultParams))
+			}, TaperedScore{}},
+		{"Hanging knight", "4k3/8/8/3n4/8/8/8/3RK3 w - - 0 1",
+			func(game *Game) TaperedScore {
+				white, black := computeAttacks(game.position, game.precomputedData, defaultParams)
+				return difference(hangingPieces(game.position, &white, &black, defaultParams))
+			}, TaperedScore{}.sub(defaultParams.HangingPiecePenalty)},
+		{"Queen attacked by pawn", "4k3/8/8/3q4/4P3/8/8/4K3 w - - 0 1",
+			func(game *Game) TaperedScore {
+				white, black := computeAttacks(game.position, game.precomputedData, defaultParams)
+				return difference(threats(game.position, &white, &black, defaultParams))
+			}, TaperedScore{}.sub(defaultParams.PawnThreatPenalty)},
+	}
+
+	for _, test := range tests {
+		game := NewGameFromFEN(test.fen)
+		if score := test.evaluate(&game); score != test.expected {
+			t.Errorf("%s should be scored %v, %v was returned instead", test.name, test.expected, score)
+		}
+	}
+}
+
+func TestMobility(t *testing.T) {
+	// A centralized knight is more mobile than one in the corner
+	game := NewGameFromFEN("4k3/8/8/8/3N4/8/8/n3K3 w - - 0 1")
+	white, black := computeAttacks(game.position, game.precomputedData, defaultParams)
+
+	if white.mobility.MiddleGame <= black.mobility.MiddleGame {
+		t.Errorf("Centralized knight mobility should be greater than %d, %d was returned instead", black.mobility.MiddleGame, white.mobility.MiddleGame)
+	}
+}
+
+func TestKingSafety(t *testing.T) {
+	// The king behind its pawns is safer than the one which pushed them
+	game := NewGameFromFEN("6k1/8/5ppp/8/8/8/5PPP/6K1 w - - 0 1")
+	white, black := computeAttacks(game.position, game.precomputedData, defaultParams)
+
+	if score := difference(kingSafety(game.position, &white, &black, defaultParams)); score.MiddleGame <= 0 {
+		t.Errorf("King safety should favour white, %d was returned instead", score.MiddleGame)
+	}
+}
+
+func TestPawnStructure(t *testing.T) {
+	tests := []struct {
+		name      string
+		fen       string
+		structure TaperedScore
+		passed    TaperedScore
+	}{
+		{"Passed pawn", "4k3/8/8/3P4/8/8/8/4K3 w - - 0 1",
+			TaperedScore{}, defaultParams.PassedPawnRankBonus[4]},
+		{"Backward pawn", "4k3/8/8/2p1p3/2P1P3/3P4/8/4K3 w - - 0 1",
+			defaultParams.SupportedPawnBonus.mul(2).add(defaultParams.BackwardPawnPenalty).sub(defaultParams.PawnIslandPenalty), TaperedScore{}},
+		{"Phalanx", "4k3/3pp3/8/8/8/8/8/4K3 w - - 0 1",
+			TaperedScore{}.sub(defaultParams.PhalanxPawnBonus.mul(2)), TaperedScore{}.sub(defaultParams.PassedPawnRankBonus[1].mul(2))},
+		{"Candidate passer", "4k3/p7/8/8/8/8/PP6/4K3 w - - 0 1",
+			defaultParams.CandidatePasserBonus.add(defaultParams.PhalanxPawnBonus.mul(2)), TaperedScore{}},
+		{"Pawn islands", "4k3/8/8/8/8/8/P1P1P3/4K3 w - - 0 1",
+			defaultParams.PawnIslandPenalty.mul(2), defaultParams.PassedPawnRankBonus[1].mul(3)},
+	}
+
+	for _, test := range tests {
+		game := NewGameFromFEN(test.fen)
+		entry := evaluatePawns(game.position, game.precomputedData, defaultParams)
+
+		if structure := entry.white.structure.sub(entry.black.structure); structure != test.structure {
+			t.Errorf("%s structure should be scored %v, %v was returned instead", test.name, test.structure, structure)
+		}
+		if passed := entry.white.passed.sub(entry.black.passed); passed != test.passed {
+			t.Errorf("%s passed pawns should be scored %v, %v was returned instead", test.name, test.passed, passed)
+		}
+	}
+}
+
+func TestPassedPawnKingProximity(t *testing.T) {
+	// The passed pawn is stronger when the enemy king is far from it
+	nearGame := NewGameFromFEN("8/8/3k4/8/3P4/8/8/4K3 w - - 0 1")
+	farGame := NewGameFromFEN("7k/8/8/8/3P4/8/8/4K3 w - - 0 1")
+
+	nearEntry := evaluatePawns(nearGame.position, nearGame.precomputedData, defaultParams)
+	farEntry := evaluatePawns(farGame.position, farGame.precomputedData, defaultParams)
+	nearScore, _ := passedPawnsBonuses(nearGame.position, &nearEntry, defaultParams)
+	farScore, _ := passedPawnsBonuses(farGame.position, &farEntry, defaultParams)
+
+	if farScore.EndGame <= nearScore.EndGame {
+		t.Errorf("Passed pawn far from the enemy king should be scored more than %d, %d was returned instead", nearScore.EndGame, farScore.EndGame)
+	}
+}
+
+func TestPawnTable(t *testing.T) {
+	game := NewGame()
+	eng := NewBruteForceEngine(&game)
+
+	// The table is filled while playing, the cached evaluations must match the computed ones
+	for _, uciMove := range []string{"e2e4", "d7d5", "e4d5", "c7c6", "d5c6", "b7c6", "d2d4"} {
+		move, err := eng.game.ParseUCIMove(uciMove)
+		if err != nil {
+			t.Fatal(err)
+		}
+		eng.game.Move(move)
+
+		// The first call fills the entry, the second one reads it from the table
+		eng.pawnTableEntry(eng.game.position, eng.game.precomputedData)
+		cached := eng.pawnTableEntry(eng.game.position, eng.game.precomputedData)
+		if computed := evaluatePawns(eng.game.position, eng.game.precomputedData, defaultParams); cached != computed {
+			t.Errorf("Cached pawn evaluation after %s should be %v, %v was returned instead", uciMove, computed, cached)
+		}
+	}
+}
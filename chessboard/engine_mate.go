@@ -0,0 +1,88 @@
+package chessboard
+
+// FindMate looks for a forced checkmate in at most maxMoves moves for the side to move in the
+// tracked game. Only checking moves are considered for the attacker, which keeps the tree small
+// enough to prove mates several moves deep. Returns whether a mate was found and its main line
+func (eng *BruteForceEngine) FindMate(maxMoves int) (bool, []*Move) {
+	eng.game = eng.trackedGame.Clone()
+	eng.rootPly = len(eng.game.moves)
+	eng.stats = searchStats{}
+	eng.stop = nil
+
+	return eng.findMate(maxMoves)
+}
+
+// findMate looks for a forced checkmate from the search game until it is found or the search
+// is stopped, use StartMateSearch to run it in the background
+func (eng *BruteForceEngine) findMate(maxMoves int) (bool, []*Move) {
+	// Searching with increasing depth guarantees that the shortest mate is found
+	for moves := 1; moves <= maxMoves && !eng.stopped(); moves++ {
+		if found, line := eng.attackerMates(2*moves - 1); found {
+			eng.lastSearch = SearchInfo{Depth: 2*moves - 1, Nodes: eng.stats.nodes, ThreadNodes: []int{eng.stats.nodes}}
+			return true, line
+		}
+	}
+
+	eng.lastSearch = SearchInfo{Depth: 2*maxMoves - 1, Nodes: eng.stats.nodes, ThreadNodes: []int{eng.stats.nodes}}
+	return false, nil
+}
+
+// attackerMates returns whether the side to move can deliver checkmate within the passed
+// number of plies by giving check with every move
+func (eng *BruteForceEngine) attackerMates(plies int) (bool, []*Move) {
+	if eng.stopped() {
+		return false, nil
+	}
+	eng.stats.nodes++
+
+	legalMoves := eng.game.LegalMoves()
+	for i := 0; i < len(legalMoves); i++ {
+		eng.game.Move(legalMoves[i])
+
+		if !eng.game.position.inCheck {
+			eng.game.UndoMove()
+			continue
+		}
+
+		switch eng.game.Result() {
+		case Checkmate:
+			eng.game.UndoMove()
+			return true, []*Move{legalMoves[i]}
+		case NoResult:
+			if plies > 1 {
+				if found, line := eng.defenderIsMated(plies - 1); found {
+					eng.game.UndoMove()
+					return true, append([]*Move{legalMoves[i]}, line...)
+				}
+			}
+		}
+
+		eng.game.UndoMove()
+	}
+
+	return false, nil
+}
+
+// defenderIsMated returns whether every move of the side to move allows the opponent to deliver
+// checkmate within the passed number of plies, the main line follows the longest defence
+func (eng *BruteForceEngine) defenderIsMated(plies int) (bool, []*Move) {
+	eng.stats.nodes++
+
+	var longestLine []*Move
+	legalMoves := eng.game.LegalMoves()
+	for i := 0; i < len(legalMoves); i++ {
+		eng.game.Move(legalMoves[i])
+		found, line := eng.attackerMates(plies - 1)
+		eng.game.UndoMove()
+
+		if !found {
+			return false, nil
+		}
+
+		if longestLine == nil || len(line)+1 > len(longestLine) {
+			longestLine = append([]*Move{legalMoves[i]}, line...)
+		}
+	}
+
+	return true, longestLine
+}
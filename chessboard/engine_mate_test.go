@@ -0,0 +1,135 @@
+package chessboard
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var mateProblems = []struct {
+	name  string
+	fen   string
+	moves int
+	line  string
+}{
+	{"Legal mate in 2", "r2qkb1r/pp2nppp/3p4/2pNN1B1/2BnP3/3P4/PPP2PPP/R2bK2R w KQkq - 1 1", 2, "d5f6 g7f6 c4f7"},
+	{"Bishop sacrifice mate in 3", "r1b1kb1r/pppp1ppp/5q2/4n3/3KP3/2N3PN/PPP4P/R1BQ1B1R b kq - 0 1", 3, "f8c5 d4c5 f6b6 c5d5 b6d6"},
+	{"Knight and queen mate in 3", "r4b1k/6pp/8/6N1/8/1Q6/8/6K1 w - - 0 1", 3, "g5f7 h8g8 f7h6 g8h8 b3g8"},
+	{"Philidor smothered mate in 4", "3Nr2k/6pp/5b2/K7/2Q5/8/1n6/8 w - - 0 1", 4, "d8f7 h8g8 f7h6 g8h8 c4g8 e8g8 h6f7"},
+	{"Rook lift mate in 4", "5r1k/6pp/3N4/3Q4/8/3R4/8/K6n w - - 0 1", 4, "d6f7 h8g8 f7e5 g8h8 e5g6 h7g6 d3h3"},
+}
+
+func uciLine(moves []*Move) string {
+	notations := make([]string, len(moves))
+	for i, move := range moves {
+		notations[i] = move.UCI()
+	}
+
+	return strings.Join(notations, " ")
+}
+
+func TestFindMate(t *testing.T) {
+	for _, problem := range mateProblems {
+		t.Run(problem.name, func(t *testing.T) {
+			game := NewGameFromFEN(problem.fen)
+			eng := NewBruteForceEngine(&game)
+
+			found, line := eng.FindMate(problem.moves)
+			if !found {
+				t.Fatalf("Mate in %d should be found", problem.moves)
+			}
+			if len(line) != 2*problem.moves-1 {
+				t.Errorf("Mate should take %d plies, %s was returned instead", 2*problem.moves-1, uciLine(line))
+			}
+			if problem.line != "" && uciLine(line) != problem.line {
+				t.Errorf("Main line should be %s, %s was returned instead", problem.line, uciLine(line))
+			}
+
+			for _, move := range line {
+				game.Move(move)
+			}
+			if game.Result() != Checkmate {
+				t.Errorf("Main line %s should end in checkmate", uciLine(line))
+			}
+		})
+	}
+}
+
+func TestFindMateTooShort(t *testing.T) {
+	game := NewGameFromFEN("3Nr2k/6pp/5b2/K7/2Q5/8/1n6/8 w - - 0 1")
+	eng := NewBruteForceEngine(&game)
+
+	if found, line := eng.FindMate(3); found {
+		t.Errorf("No mate in 3 should be found, %s was returned instead", uciLine(line))
+	}
+}
+
+func TestStartMateSearch(t *testing.T) {
+	problem := mateProblems[2]
+	game := NewGameFromFEN(problem.fen)
+	eng := NewBruteForceEngine(&game)
+
+	eng.StartMateSearch(problem.moves, 60)
+	lines := eng.Wait()
+	if uciLine(lines[0].PV) != problem.line {
+		t.Errorf("Main line should be %s, %s was returned instead", problem.line, uciLine(lines[0].PV))
+	}
+	if mate, ok := MateIn(lines[0].Score); !ok || mate != problem.moves {
+		t.Errorf("Mate search should report mate in %d, score %d was returned instead", problem.moves, lines[0].Score)
+	}
+}
+
+func TestStopMateSearch(t *testing.T) {
+	// There is no forced mate in the starting position, so the mate search only ends when stopped
+	game := NewGame()
+	eng := NewBruteForceEngine(&game)
+
+	eng.StartMateSearch(50, 60)
+	time.Sleep(100 * time.Millisecond)
+	start := time.Now()
+	eng.Stop()
+	lines := eng.Wait()
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Stopped mate search should end immediately, it took %v instead", elapsed)
+	}
+	if len(lines) == 0 || lines[0].Move == nil {
+		t.Errorf("Stopped mate search should still return a move")
+	}
+}
+
+func TestSearchMateScore(t *testing.T) {
+	// The full width search is too slow for the deeper problems
+	for _, i := range []int{0, 2} {
+		problem := mateProblems[i]
+		t.Run(problem.name, func(t *testing.T) {
+			game := NewGameFromFEN(problem.fen)
+			eng := NewBruteForceEngine(&game)
+			eng.MaxDepth = 2*problem.moves - 1
+
+			lines := eng.Analyze(60)
+			mate, ok := MateIn(lines[0].Score)
+			if !ok || mate != problem.moves {
+				t.Errorf("Search should report mate in %d, score %d was returned instead", problem.moves, lines[0].Score)
+			}
+			if firstMove := strings.Fields(problem.line)[0]; lines[0].Move.UCI() != firstMove {
+				t.Errorf("Search should play the mating move %s, %s was returned instead", firstMove, lines[0].Move.UCI())
+			}
+		})
+	}
+}
+
+func TestMateIn(t *testing.T) {
+	if mate, ok := MateIn(MateScore - 1); !ok || mate != 1 {
+		t.Errorf("Mate in 1 ply should be mate in 1 move, %d was returned instead", mate)
+	}
+	if mate, ok := MateIn(MateScore - 5); !ok || mate != 3 {
+		t.Errorf("Mate in 5 plies should be mate in 3 moves, %d was returned instead", mate)
+	}
+	if mate, ok := MateIn(CheckmateScore + 4); !ok || mate != -2 {
+		t.Errorf("Getting mated in 4 plies should be mate in -2 moves, %d was returned instead", mate)
+	}
+	if _, ok := MateIn(1200); ok {
+		t.Errorf("Score 1200 should not be a mate score")
+	}
+}
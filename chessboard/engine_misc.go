@@ -3,14 +3,18 @@ package chessboard
 import "fmt"
 
 // PositionAnalysisString returns a string containing all the factors of the positional analysis
+// of the tracked game position, use Evaluate for a structured version
 func (eng *BruteForceEngine) PositionAnalysisString() string {
-	return fmt.Sprintf("Material difference: %d, Position difference: %d, Center control: %d, Doubled and Isolated Pawns: %d, Passed Pawns: %d",
-		materialDifference(eng.trackedGame.position),
-		positionDifference(eng.trackedGame.position),
-		centerControl(eng.trackedGame.position),
-		doubledOrIsolatedPawnsPenalties(eng.trackedGame.position, &eng.trackedGame.precomputedData),
-		passedPawnsBonuses(eng.trackedGame.position, &eng.trackedGame.precomputedData),
-	)
+	trace := eng.Evaluate(*eng.trackedGame.position)
+
+	str := fmt.Sprintf("Game phase: %d/%d", trace.Phase, trace.MaxPhase)
+	for _, term := range trace.Terms {
+		str += fmt.Sprintf(", %s: %d (white %d/%d, black %d/%d)", term.Name, term.Score,
+			term.White.MiddleGame, term.White.EndGame, term.Black.MiddleGame, term.Black.EndGame)
+	}
+	str += fmt.Sprintf(", Tempo: %d", trace.Tempo)
+
+	return str
 }
 
 func (eng *BruteForceEngine) sortMoves(moves []*Move, evaluationTable *ZobristTable) []*Move {
@@ -21,7 +25,7 @@ func (eng *BruteForceEngine) sortMoves(moves []*Move, evaluationTable *ZobristTa
 	for i := 0; i < N; i++ {
 		eng.game.Move(moves[i])
 
-		got, hash := evaluationTable.Get(eng.game.position.hash)
+		got, hash := eng.cacheGet(evaluationTable, eng.game.position.hash)
 		if got {
 			scores[i] = hash.Evaluation()
 		}
@@ -48,3 +52,15 @@ func (eng *BruteForceEngine) sortMoves(moves []*Move, evaluationTable *ZobristTa
 
 	return moves
 }
+
+// cacheGet reads a position from the table and updates the cache statistics of the thread
+func (eng *BruteForceEngine) cacheGet(table *ZobristTable, hash ZobristHash) (bool, ZobristHash) {
+	found, value := table.Get(hash)
+	if found {
+		eng.stats.cacheHits++
+	} else {
+		eng.stats.cacheMisses++
+	}
+
+	return found, value
+}
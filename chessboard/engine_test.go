@@ -2,7 +2,9 @@ package chessboard
 
 import (
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 )
 
 func BenchmarkRandomSelfPlay(b *testing.B) {
@@ -118,3 +120,249 @@ func BenchmarkAnalyzeStart5PlyWithAllPruning(b *testing.B) {
 		eng.BestMove(600000)
 	}
 }
+
+func isLegalMove(game *Game, move *Move) bool {
+	for _, legalMove := range game.LegalMoves() {
+		if legalMove.From() == move.From() && legalMove.To() == move.To() && legalMove.Promotion() == move.Promotion() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestLazySMPBestMove(t *testing.T) {
+	game := NewGameFromFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	eng := NewBruteForceEngine(&game)
+	eng.MaxDepth = 3
+	eng.Threads = 4
+
+	move := eng.BestMove(60)
+	if !isLegalMove(&game, move) {
+		t.Errorf("BestMove with 4 threads returned the illegal move %s", move)
+	}
+
+	info := eng.SearchInfo()
+	if len(info.ThreadNodes) != 4 {
+		t.Errorf("SearchInfo should contain the nodes of 4 threads, %d were returned instead", len(info.ThreadNodes))
+	}
+
+	total := 0
+	for _, nodes := range info.ThreadNodes {
+		total += nodes
+	}
+	if total != info.Nodes {
+		t.Errorf("Total nodes should be the sum of the threads nodes %d, %d was returned instead", total, info.Nodes)
+	}
+}
+
+func TestLazySMPSharesTranspositionTable(t *testing.T) {
+	fen := "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"
+
+	game := NewGameFromFEN(fen)
+	single := NewBruteForceEngine(&game)
+	single.MaxDepth = 3
+	single.TranspositionTableEnabled = true
+	single.BestMove(60)
+
+	// The transposition table is used with more threads even when it is disabled, the entries
+	// stored by the helpers let the main thread reach the same depth exploring fewer nodes
+	game = NewGameFromFEN(fen)
+	parallel := NewBruteForceEngine(&game)
+	parallel.MaxDepth = 3
+	parallel.Threads = 4
+	parallel.BestMove(60)
+
+	singleNodes, mainNodes := single.SearchInfo().Nodes, parallel.SearchInfo().ThreadNodes[0]
+	if mainNodes >= singleNodes {
+		t.Errorf("The main thread of a 4 threads search should explore less than the %d nodes of a single thread, %d were explored instead", singleNodes, mainNodes)
+	}
+}
+
+func TestEngineSetGame(t *testing.T) {
+	game := NewGameFromFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	eng := NewBruteForceEngine(&game)
+	eng.MaxDepth = 3
+	eng.BestMove(60)
+	evaluations := eng.evaluations
+
+	// The engine searches the new game reusing the transposition tables
+	other := NewGameFromFEN("6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1")
+	eng.SetGame(&other)
+	if move := eng.BestMove(60); move.UCI() != "a1a8" {
+		t.Errorf("The engine should find the mate a1a8 in the new game, %s was returned instead", move.UCI())
+	}
+	if eng.evaluations != evaluations {
+		t.Errorf("The transposition tables should be reused by the following searches")
+	}
+}
+
+func TestConcurrentEngines(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3",
+		"8/8/4k3/8/2P5/8/4K3/8 w - - 0 1",
+	}
+
+	var wg sync.WaitGroup
+	for _, fen := range fens {
+		wg.Add(1)
+		go func(fen string) {
+			defer wg.Done()
+
+			game := NewGameFromFEN(fen)
+			eng := NewBruteForceEngine(&game)
+			eng.MaxDepth = 3
+
+			move := eng.BestMove(60)
+			if !isLegalMove(&game, move) {
+				t.Errorf("BestMove for %s returned the illegal move %s", fen, move)
+			}
+		}(fen)
+	}
+	wg.Wait()
+}
+
+func TestMultiPVAnalysis(t *testing.T) {
+	game := NewGameFromFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	eng := NewBruteForceEngine(&game)
+	eng.MaxDepth = 3
+	eng.MultiPV = 4
+
+	lines := eng.Analyze(60)
+	if len(lines) != 4 {
+		t.Fatalf("Analyze with MultiPV 4 should return 4 lines, %d were returned instead", len(lines))
+	}
+
+	seen := map[string]bool{}
+	for i, line := range lines {
+		if seen[line.Move.UCI()] {
+			t.Errorf("Move %s appears in more than one line", line.Move.UCI())
+		}
+		seen[line.Move.UCI()] = true
+
+		if line.Depth != 3 {
+			t.Errorf("Line %d should be searched at depth 3, %d was returned instead", i+1, line.Depth)
+		}
+		if len(line.PV) == 0 || line.PV[0] != line.Move {
+			t.Errorf("The main line of line %d should start with its move", i+1)
+		}
+		if i > 0 && line.Score > lines[i-1].Score {
+			t.Errorf("Line %d has score %d, better than the previous line's %d", i+1, line.Score, lines[i-1].Score)
+		}
+	}
+}
+
+func TestStopAnalysis(t *testing.T) {
+	game := NewGame()
+	eng := NewBruteForceEngine(&game)
+
+	// Without Stop the search would last more than 10 minutes
+	eng.StartAnalysis(24_000)
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	eng.Stop()
+	lines := eng.Wait()
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Search should stop promptly, it took %s instead", elapsed)
+	}
+	if !isLegalMove(&game, lines[0].Move) {
+		t.Errorf("Stopped search returned the illegal move %s", lines[0].Move)
+	}
+}
+
+func TestAnalyzeWithoutLegalMoves(t *testing.T) {
+	positions := []struct {
+		name string
+		fen  string
+	}{
+		{"Checkmate", "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3"},
+		{"Stalemate", "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1"},
+	}
+
+	for _, position := range positions {
+		t.Run(position.name, func(t *testing.T) {
+			game := NewGameFromFEN(position.fen)
+			eng := NewBruteForceEngine(&game)
+			eng.Threads = 2
+
+			if lines := eng.Analyze(60); len(lines) != 0 {
+				t.Errorf("Analysis should return no lines, %d were returned instead", len(lines))
+			}
+			if move := eng.BestMove(60); move != nil {
+				t.Errorf("Best move should be nil, %s was returned instead", move)
+			}
+		})
+	}
+}
+
+func TestPonderHit(t *testing.T) {
+	game := NewGame()
+	eng := NewBruteForceEngine(&game)
+	eng.MaxDepth = 3
+
+	game.Move(eng.BestMove(60))
+	expectedMove := eng.PonderMove()
+	if expectedMove == nil {
+		t.Fatalf("The main line should contain the expected reply")
+	}
+
+	eng.MaxDepth = -1
+	eng.Ponder(expectedMove)
+	time.Sleep(100 * time.Millisecond)
+
+	// The opponent plays the expected move, the engine has 1/40th of 4 seconds for its move
+	// including the time already spent pondering
+	game.Move(expectedMove)
+	start := time.Now()
+	eng.PonderHit(4)
+	lines := eng.Wait()
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Search should end shortly after the ponder hit, it took %s instead", elapsed)
+	}
+	if !isLegalMove(&game, lines[0].Move) {
+		t.Errorf("Ponder search returned the illegal move %s", lines[0].Move)
+	}
+}
+
+func TestPonderMiss(t *testing.T) {
+	game := NewGame()
+	eng := NewBruteForceEngine(&game)
+	eng.Ponder(nil)
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	eng.PonderMiss()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Ponder search should be aborted promptly, it took %s instead", elapsed)
+	}
+}
+
+func TestCheckExtensions(t *testing.T) {
+	// Mate in 3 made of checks and forced replies, found within a nominal depth of 3 plies
+	game := NewGameFromFEN("r4b1k/6pp/8/6N1/8/1Q6/8/6K1 w - - 0 1")
+	eng := NewBruteForceEngine(&game)
+	eng.MaxDepth = 3
+
+	lines := eng.Analyze(60)
+	if mate, ok := MateIn(lines[0].Score); !ok || mate != 3 {
+		t.Errorf("Search with extensions should report mate in 3, score %d was returned instead", lines[0].Score)
+	}
+	if lines[0].Move.UCI() != "g5f7" {
+		t.Errorf("Search with extensions should play g5f7, %s was returned instead", lines[0].Move.UCI())
+	}
+	if info := eng.SearchInfo(); info.SelDepth <= info.Depth {
+		t.Errorf("Selective depth should be greater than the depth %d, %d was returned instead", info.Depth, info.SelDepth)
+	}
+
+	eng.CheckExtensionsEnabled = false
+	eng.RecaptureExtensionsEnabled = false
+	eng.SingleReplyExtensionsEnabled = false
+	lines = eng.Analyze(60)
+	if _, ok := MateIn(lines[0].Score); ok {
+		t.Errorf("Search without extensions should not see the mate, score %d was returned instead", lines[0].Score)
+	}
+}
@@ -0,0 +1,255 @@
+package chessboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// EvalParams contains all the weights used by the static evaluation, in 256th of a pawn.
+// The parameters are read only during the search, so an instance can be shared between engines
+type EvalParams struct {
+	// Material values, pawns are worth more in the end game where they can promote
+	PawnValue   TaperedScore
+	KnightValue TaperedScore
+	BishopValue TaperedScore
+	RookValue   TaperedScore
+	QueenValue  TaperedScore
+	// PieceRatioWeight rewards trading pieces when ahead in material
+	PieceRatioWeight int
+
+	// Piece-square tables from white's point of view, A1 is the first square
+	PawnMiddleGameSquareValue   [64]int
+	KnightMiddleGameSquareValue [64]int
+	BishopMiddleGameSquareValue [64]int
+	RookMiddleGameSquareValue   [64]int
+	QueenMiddleGameSquareValue  [64]int
+	KingMiddleGameSquareValue   [64]int
+	PawnEndGameSquareValue      [64]int
+	KnightEndGameSquareValue    [64]int
+	BishopEndGameSquareValue    [64]int
+	RookEndGameSquareValue      [64]int
+	QueenEndGameSquareValue     [64]int
+	KingEndGameSquareValue      [64]int
+
+	// Pawn structure
+	CenterControlBonus         TaperedScore
+	DoubledIsolatedPawnPenalty TaperedScore
+	IsolatedPawnPenalty        TaperedScore
+	DoubledPawnPenalty         TaperedScore
+	BackwardPawnPenalty        TaperedScore
+	SupportedPawnBonus         TaperedScore
+	PhalanxPawnBonus           TaperedScore
+	CandidatePasserBonus       TaperedScore
+	PawnIslandPenalty          TaperedScore
+	// PassedPawnRankBonus is indexed by the rank of the passed pawn from its side's point of view
+	PassedPawnRankBonus [8]TaperedScore
+	// Weights of the distance of the kings from the square in front of a passed pawn,
+	// multiplied by how advanced the pawn is
+	PassedPawnEnemyKingDistance int
+	PassedPawnOwnKingDistance   int
+	// BlockedPassedPawnPercent is the percentage of the rank bonus lost by a blocked passed pawn
+	BlockedPassedPawnPercent int
+
+	// Mobility weights per safe square reachable by the piece
+	KnightMobility TaperedScore
+	BishopMobility TaperedScore
+	RookMobility   TaperedScore
+	QueenMobility  TaperedScore
+	// Number of safe squares for which the mobility of the piece is neither a bonus nor a penalty
+	KnightMobilityBaseline int
+	BishopMobilityBaseline int
+	RookMobilityBaseline   int
+	QueenMobilityBaseline  int
+
+	// King safety, the attacks on the king zone are weighted by the attacking piece and
+	// the middle game penalty grows with the square of their sum
+	KnightKingAttackWeight     int
+	BishopKingAttackWeight     int
+	RookKingAttackWeight       int
+	QueenKingAttackWeight      int
+	KingAttackMiddleGameWeight int
+	MaxKingAttackPenalty       int
+	KingAttackEndGameWeight    int
+	PawnShieldBonus            TaperedScore
+	FarPawnShieldBonus         TaperedScore
+	PawnStormPenalty           TaperedScore
+
+	// Piece specific terms
+	BishopPairBonus       TaperedScore
+	RookOpenFileBonus     TaperedScore
+	RookSemiOpenFileBonus TaperedScore
+	RookSeventhRankBonus  TaperedScore
+	KnightOutpostBonus    TaperedScore
+	HangingPiecePenalty   TaperedScore
+	TrappedPiecePenalty   TaperedScore
+	PawnThreatPenalty     TaperedScore
+	MinorThreatPenalty    TaperedScore
+	RookThreatPenalty     TaperedScore
+
+	// TempoBonus stabilizes fluctuations between even and odd depth evaluations
+	TempoBonus int
+}
+
+// DefaultEvalParams returns a new copy of the default evaluation parameters
+func DefaultEvalParams() *EvalParams {
+	return &EvalParams{
+		PawnValue:        TaperedScore{256, 307},
+		KnightValue:      TaperedScore{832, 768},
+		BishopValue:      TaperedScore{896, 870},
+		RookValue:        TaperedScore{1280, 1372},
+		QueenValue:       TaperedScore{2496, 2560},
+		PieceRatioWeight: 100,
+
+		// Middle game values from https://www.chessprogramming.org/Simplified_Evaluation_Function,
+		// end game values give more weight to centralization and to advanced pawns
+		PawnMiddleGameSquareValue:   [64]int{0, 0, 0, 0, 0, 0, 0, 0, 12, 25, 25, -51, -51, 25, 25, 12, 12, -12, -25, 0, 0, -25, -12, 12, 0, 0, 0, 51, 51, 0, 0, 0, 12, 12, 25, 64, 64, 25, 12, 12, 25, 25, 51, 76, 76, 51, 25, 25, 128, 128, 128, 128, 128, 128, 128, 128, 0, 0, 0, 0, 0, 0, 0, 0},
+		KnightMiddleGameSquareValue: [64]int{-128, -102, -76, -76, -76, -76, -102, -128, -102, -51, 0, 12, 12, 0, -51, -102, -76, 12, 25, 38, 38, 25, 12, -76, -76, 0, 38, 51, 51, 38, 0, -76, -76, 12, 38, 51, 51, 38, 12, -76, -76, 0, 25, 38, 38, 25, 0, -76, -102, -51, 0, 0, 0, 0, -51, -102, -128, -102, -76, -76, -76, -76, -102, -128},
+		BishopMiddleGameSquareValue: [64]int{-51, -25, -25, -25, -25, -25, -25, -51, -25, 12, 0, 0, 0, 0, 12, -25, -25, 25, 25, 25, 25, 25, 25, -25, -25, 0, 25, 25, 25, 25, 0, -25, -25, 12, 12, 25, 25, 12, 12, -25, -25, 0, 12, 25, 25, 12, 0, -25, -25, 0, 0, 0, 0, 0, 0, -25, -51, -25, -25, -25, -25, -25, -25, -51},
+		RookMiddleGameSquareValue:   [64]int{0, 0, 0, 12, 12, 0, 0, 0, -12, 0, 0, 0, 0, 0, 0, -12, -12, 0, 0, 0, 0, 0, 0, -12, -12, 0, 0, 0, 0, 0, 0, -12, -12, 0, 0, 0, 0, 0, 0, -12, -12, 0, 0, 0, 0, 0, 0, -12, 12, 25, 25, 25, 25, 25, 25, 12, 0, 0, 0, 0, 0, 0, 0, 0},
+		QueenMiddleGameSquareValue:  [64]int{-51, -25, -25, -12, -12, -25, -25, -51, -25, 0, 12, 0, 0, 0, 0, -25, -25, 12, 12, 12, 12, 12, 0, -25, 0, 0, 12, 12, 12, 12, 0, -12, -12, 0, 12, 12, 12, 12, 0, -12, -25, 0, 12, 12, 12, 12, 0, -25, -25, 0, 0, 0, 0, 0, 0, -25, -51, -25, -25, -12, -12, -25, -25, -51},
+		KingMiddleGameSquareValue:   [64]int{51, 76, 25, 0, 0, 25, 76, 51, 51, 51, 0, 0, 0, 0, 51, 51, -25, -51, -51, -51, -51, -51, -51, -25, -51, -76, -76, -102, -102, -76, -76, -51, -76, -102, -102, -128, -128, -102, -102, -76, -76, -102, -102, -128, -128, -102, -102, -76, -76, -102, -102, -128, -128, -102, -102, -76, -76, -102, -102, -128, -128, -102, -102, -76},
+		PawnEndGameSquareValue:      [64]int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 12, 12, 12, 12, 12, 12, 12, 12, 25, 25, 25, 25, 25, 25, 25, 25, 51, 51, 51, 51, 51, 51, 51, 51, 102, 102, 102, 102, 102, 102, 102, 102, 179, 179, 179, 179, 179, 179, 179, 179, 0, 0, 0, 0, 0, 0, 0, 0},
+		KnightEndGameSquareValue:    [64]int{-76, -76, -76, -76, -76, -76, -76, -76, -76, -25, -25, -25, -25, -25, -25, -76, -76, -25, 12, 12, 12, 12, -25, -76, -76, -25, 12, 25, 25, 12, -25, -76, -76, -25, 12, 25, 25, 12, -25, -76, -76, -25, 12, 12, 12, 12, -25, -76, -76, -25, -25, -25, -25, -25, -25, -76, -76, -76, -76, -76, -76, -76, -76, -76},
+		BishopEndGameSquareValue:    [64]int{-25, -25, -25, -25, -25, -25, -25, -25, -25, -12, -12, -12, -12, -12, -12, -25, -25, -12, 6, 6, 6, 6, -12, -25, -25, -12, 6, 12, 12, 6, -12, -25, -25, -12, 6, 12, 12, 6, -12, -25, -25, -12, 6, 6, 6, 6, -12, -25, -25, -12, -12, -12, -12, -12, -12, -25, -25, -25, -25, -25, -25, -25, -25, -25},
+		RookEndGameSquareValue:      [64]int{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 12, 12, 12, 12, 12, 12, 12, 12, 0, 0, 0, 0, 0, 0, 0, 0},
+		QueenEndGameSquareValue:     [64]int{-51, -51, -51, -51, -51, -51, -51, -51, -51, -12, -12, -12, -12, -12, -12, -51, -51, -12, 12, 12, 12, 12, -12, -51, -51, -12, 12, 25, 25, 12, -12, -51, -51, -12, 12, 25, 25, 12, -12, -51, -51, -12, 12, 12, 12, 12, -12, -51, -51, -12, -12, -12, -12, -12, -12, -51, -51, -51, -51, -51, -51, -51, -51, -51},
+		KingEndGameSquareValue:      [64]int{-128, -76, -76, -76, -76, -76, -76, -128, -76, -76, 0, 0, 0, 0, -76, -76, -76, -25, 51, 76, 76, 51, -25, -76, -76, -25, 76, 102, 102, 76, -25, -76, -76, -25, 76, 102, 102, 76, -25, -76, -76, -25, 51, 76, 76, 51, -25, -76, -76, -51, -25, 0, 0, -25, -51, -76, -128, -102, -76, -51, -51, -76, -102, -128},
+
+		CenterControlBonus:          TaperedScore{70, 0},
+		DoubledIsolatedPawnPenalty:  TaperedScore{-90, -120},
+		IsolatedPawnPenalty:         TaperedScore{-45, -60},
+		DoubledPawnPenalty:          TaperedScore{-45, -60},
+		BackwardPawnPenalty:         TaperedScore{-25, -40},
+		SupportedPawnBonus:          TaperedScore{20, 15},
+		PhalanxPawnBonus:            TaperedScore{15, 10},
+		CandidatePasserBonus:        TaperedScore{20, 40},
+		PawnIslandPenalty:           TaperedScore{-20, -30},
+		PassedPawnRankBonus:         [8]TaperedScore{{0, 0}, {10, 20}, {10, 25}, {25, 50}, {60, 100}, {110, 170}, {180, 270}, {0, 0}},
+		PassedPawnEnemyKingDistance: 12,
+		PassedPawnOwnKingDistance:   5,
+		BlockedPassedPawnPercent:    50,
+
+		KnightMobility: TaperedScore{16, 20},
+		BishopMobility: TaperedScore{14, 20},
+		RookMobility:   TaperedScore{8, 16},
+		QueenMobility:  TaperedScore{4, 8},
+
+		KnightMobilityBaseline: 4,
+		BishopMobilityBaseline: 7,
+		RookMobilityBaseline:   7,
+		QueenMobilityBaseline:  14,
+
+		KnightKingAttackWeight:     2,
+		BishopKingAttackWeight:     2,
+		RookKingAttackWeight:       3,
+		QueenKingAttackWeight:      5,
+		KingAttackMiddleGameWeight: 1,
+		MaxKingAttackPenalty:       1024,
+		KingAttackEndGameWeight:    4,
+		PawnShieldBonus:            TaperedScore{38, 0},
+		FarPawnShieldBonus:         TaperedScore{19, 0},
+		PawnStormPenalty:           TaperedScore{-25, 0},
+
+		BishopPairBonus:       TaperedScore{128, 180},
+		RookOpenFileBonus:     TaperedScore{110, 50},
+		RookSemiOpenFileBonus: TaperedScore{50, 25},
+		RookSeventhRankBonus:  TaperedScore{50, 100},
+		KnightOutpostBonus:    TaperedScore{80, 40},
+		HangingPiecePenalty:   TaperedScore{-64, -64},
+		TrappedPiecePenalty:   TaperedScore{-100, -60},
+		PawnThreatPenalty:     TaperedScore{-128, -100},
+		MinorThreatPenalty:    TaperedScore{-90, -70},
+		RookThreatPenalty:     TaperedScore{-90, -70},
+
+		TempoBonus: 15,
+	}
+}
+
+// LoadEvalParams reads the evaluation parameters from a JSON or TOML file, the format is chosen
+// by the file extension. The parameters missing from the file keep their default value
+func LoadEvalParams(path string) (*EvalParams, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// TOML files are converted to the equivalent JSON document, so both formats
+	// share the decoding and the validation of the fields
+	switch filepath.Ext(path) {
+	case ".json":
+	case ".toml":
+		data, err = tomlToJSON(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported evaluation parameters format, use .json or .toml", path)
+	}
+
+	if err := checkArrayLengths(data); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	params := DefaultEvalParams()
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(params); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return params, nil
+}
+
+// checkArrayLengths returns an error if an array of the JSON document doesn't contain a value
+// for each element of the parameter, because the decoder would zero the missing elements
+// and drop the extra ones
+func checkArrayLengths(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		// The decoding of the parameters reports the malformed documents
+		return nil
+	}
+
+	paramsType := reflect.TypeOf(EvalParams{})
+	for name, raw := range fields {
+		for i := 0; i < paramsType.NumField(); i++ {
+			field := paramsType.Field(i)
+			if field.Type.Kind() != reflect.Array || !strings.EqualFold(field.Name, name) {
+				continue
+			}
+
+			var values []json.RawMessage
+			if err := json.Unmarshal(raw, &values); err == nil && len(values) != field.Type.Len() {
+				return fmt.Errorf("%s should contain %d values, %d were found", field.Name, field.Type.Len(), len(values))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Save writes the evaluation parameters to a JSON or TOML file, the format is chosen
+// by the file extension
+func (params *EvalParams) Save(path string) error {
+	var data []byte
+	var err error
+
+	switch filepath.Ext(path) {
+	case ".json":
+		data, err = json.MarshalIndent(params, "", "  ")
+	case ".toml":
+		data = params.toml()
+	default:
+		return fmt.Errorf("%s: unsupported evaluation parameters format, use .json or .toml", path)
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,109 @@
+package chessboard
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEvalParamsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	params := DefaultEvalParams()
+	params.BishopPairBonus = TaperedScore{-7, 1234}
+	params.PawnMiddleGameSquareValue[12] = -99
+	params.PassedPawnRankBonus[6] = TaperedScore{300, 400}
+	params.TempoBonus = 3
+
+	for _, name := range []string{"params.json", "params.toml"} {
+		path := filepath.Join(dir, name)
+		if err := params.Save(path); err != nil {
+			t.Fatalf("Saving %s should succeed, %v was returned instead", name, err)
+		}
+
+		loaded, err := LoadEvalParams(path)
+		if err != nil {
+			t.Fatalf("Loading %s should succeed, %v was returned instead", name, err)
+		}
+		if !reflect.DeepEqual(loaded, params) {
+			t.Errorf("Parameters loaded from %s should be equal to the saved ones, %+v was returned instead", name, loaded)
+		}
+	}
+}
+
+func TestLoadPartialEvalParams(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"params.json": `{"PawnValue": {"MiddleGame": 200, "EndGame": 300}, "TempoBonus": 0}`,
+		"params.toml": "# Custom pawn value\nPawnValue = { MiddleGame = 200, EndGame = 300 }\nTempoBonus = 0 # no tempo\n",
+	}
+
+	expected := DefaultEvalParams()
+	expected.PawnValue = TaperedScore{200, 300}
+	expected.TempoBonus = 0
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		loaded, err := LoadEvalParams(path)
+		if err != nil {
+			t.Fatalf("Loading %s should succeed, %v was returned instead", name, err)
+		}
+		if !reflect.DeepEqual(loaded, expected) {
+			t.Errorf("Parameters missing from %s should keep the default value, %+v was returned instead", name, loaded)
+		}
+	}
+}
+
+func TestLoadInvalidEvalParams(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"unknown.json":   `{"PawnVa///": 3}`,
+		"unknown.toml":   "PawnVal = 3\n",
+		"syntax.toml":    "PawnValue = { MiddleGame = 200 EndGame = 300 }\n",
+		"array.toml":     "PawnMiddleGameSquareValue = [1, 2\n",
+		"format.yaml":    "PawnValue: 3\n",
+		"wrongtype.json": `{"TempoBonus": {"MiddleGame": 1}}`,
+		// The arrays must set all the values of the parameter
+		"short.json": `{"PawnMiddleGameSquareValue": [1, 2, 3]}`,
+		"short.toml": "PassedPawnRankBonus = [{ MiddleGame = 10, EndGame = 20 }]\n",
+		"long.toml":  "KingEndGameSquareValue = [" + strings.Repeat("1, ", 64) + "1]\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadEvalParams(path); err == nil {
+			t.Errorf("Loading %s should fail, no error was returned instead", name)
+		}
+	}
+}
+
+func TestEnginesWithDifferentParams(t *testing.T) {
+	game := NewGameFromFEN("4k3/8/8/8/8/8/8/2B1KB2 w - - 0 1")
+
+	defaultEngine := NewBruteForceEngine(&game)
+	tunedEngine := NewBruteForceEngine(&game)
+	tunedEngine.EvalParams = DefaultEvalParams()
+	tunedEngine.EvalParams.TempoBonus = 0
+
+	// The specialised KXK evaluation doesn't use the tempo bonus
+	defaultEngine.EndgameEval = false
+	tunedEngine.EndgameEval = false
+
+	difference := defaultEngine.StaticEvaluation() - tunedEngine.StaticEvaluation()
+	if difference != defaultParams.TempoBonus {
+		t.Errorf("Evaluation difference should be the tempo bonus %d, %d was returned instead", defaultParams.TempoBonus, difference)
+	}
+	if defaultEngine.EvalParams.TempoBonus != defaultParams.TempoBonus {
+		t.Errorf("Default engine parameters should not be modified, tempo bonus %d was returned instead", defaultEngine.EvalParams.TempoBonus)
+	}
+}
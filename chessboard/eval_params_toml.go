@@ -0,0 +1,243 @@
+package chessboard
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The evaluation parameters use only a small subset of TOML: integers, inline tables
+// and arrays of them assigned to top level keys, which is what is implemented here
+
+// toml returns the parameters in TOML format, one key for each field of the struct
+func (params *EvalParams) toml() []byte {
+	var builder strings.Builder
+	builder.WriteString("# Evaluation parameters, scores are in 256th of a pawn\n")
+
+	value := reflect.ValueOf(params).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		builder.WriteString(value.Type().Field(i).Name)
+		builder.WriteString(" = ")
+		writeTOMLValue(&builder, value.Field(i))
+		builder.WriteString("\n")
+	}
+
+	return []byte(builder.String())
+}
+
+func writeTOMLValue(builder *strings.Builder, value reflect.Value) {
+	switch value.Kind() {
+	case reflect.Int:
+		builder.WriteString(strconv.FormatInt(value.Int(), 10))
+	case reflect.Struct:
+		builder.WriteString("{ ")
+		for i := 0; i < value.NumField(); i++ {
+			if i > 0 {
+				builder.WriteString(", ")
+			}
+			builder.WriteString(value.Type().Field(i).Name)
+			builder.WriteString(" = ")
+			writeTOMLValue(builder, value.Field(i))
+		}
+		builder.WriteString(" }")
+	case reflect.Array:
+		// Integer arrays are piece-square tables and are written one rank per line
+		perLine := 1
+		if value.Type().Elem().Kind() == reflect.Int {
+			perLine = 8
+		}
+
+		builder.WriteString("[")
+		for i := 0; i < value.Len(); i++ {
+			if i%perLine == 0 {
+				builder.WriteString("\n    ")
+			} else {
+				builder.WriteString(" ")
+			}
+			writeTOMLValue(builder, value.Index(i))
+			builder.WriteString(",")
+		}
+		builder.WriteString("\n]")
+	default:
+		panic(fmt.Sprintf("Unsupported evaluation parameter kind %s", value.Kind()))
+	}
+}
+
+// tomlParser parses the TOML subset used by the evaluation parameters
+type tomlParser struct {
+	data []byte
+	pos  int
+	line int
+}
+
+// tomlToJSON converts a TOML document to the equivalent JSON document
+func tomlToJSON(data []byte) ([]byte, error) {
+	parser := tomlParser{data: data, line: 1}
+
+	document, err := parser.parseDocument()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(document)
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// skipSpaces skips spaces and comments, newlines are skipped too when multiline is true
+func (p *tomlParser) skipSpaces(multiline bool) {
+	for p.pos < len(p.data) {
+		switch c := p.data[p.pos]; {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '\n' && multiline:
+			p.line++
+			p.pos++
+		case c == '#':
+			for p.pos < len(p.data) && p.data[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) parseDocument() (map[string]interface{}, error) {
+	document := map[string]interface{}{}
+
+	for {
+		p.skipSpaces(true)
+		if p.pos == len(p.data) {
+			return document, nil
+		}
+
+		key, value, err := p.parseKeyValue()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := document[key]; ok {
+			return nil, p.errorf("duplicate key %s", key)
+		}
+		document[key] = value
+
+		p.skipSpaces(false)
+		if p.pos < len(p.data) && p.data[p.pos] != '\n' {
+			return nil, p.errorf("expected a new line after the value of %s", key)
+		}
+	}
+}
+
+func (p *tomlParser) parseKeyValue() (string, interface{}, error) {
+	start := p.pos
+	for p.pos < len(p.data) && isTOMLKeyChar(p.data[p.pos]) {
+		p.pos++
+	}
+	key := string(p.data[start:p.pos])
+	if key == "" {
+		return "", nil, p.errorf("expected a key")
+	}
+
+	p.skipSpaces(false)
+	if p.pos == len(p.data) || p.data[p.pos] != '=' {
+		return "", nil, p.errorf("expected = after %s", key)
+	}
+	p.pos++
+	p.skipSpaces(false)
+
+	value, err := p.parseValue()
+	return key, value, err
+}
+
+func isTOMLKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) parseValue() (interface{}, error) {
+	if p.pos == len(p.data) {
+		return nil, p.errorf("expected a value")
+	}
+
+	switch p.data[p.pos] {
+	case '{':
+		return p.parseInlineTable()
+	case '[':
+		return p.parseArray()
+	default:
+		return p.parseInteger()
+	}
+}
+
+func (p *tomlParser) parseInteger() (interface{}, error) {
+	start := p.pos
+	if p.pos < len(p.data) && (p.data[p.pos] == '-' || p.data[p.pos] == '+') {
+		p.pos++
+	}
+	for p.pos < len(p.data) && (p.data[p.pos] >= '0' && p.data[p.pos] <= '9' || p.data[p.pos] == '_') {
+		p.pos++
+	}
+
+	value, err := strconv.ParseInt(strings.ReplaceAll(string(p.data[start:p.pos]), "_", ""), 10, 64)
+	if err != nil {
+		return nil, p.errorf("invalid integer %q", p.data[start:p.pos])
+	}
+
+	return value, nil
+}
+
+func (p *tomlParser) parseInlineTable() (interface{}, error) {
+	table := map[string]interface{}{}
+	p.pos++
+
+	for {
+		p.skipSpaces(false)
+		if p.pos < len(p.data) && p.data[p.pos] == '}' {
+			p.pos++
+			return table, nil
+		}
+		if len(table) > 0 {
+			if p.pos == len(p.data) || p.data[p.pos] != ',' {
+				return nil, p.errorf("expected , or } in inline table")
+			}
+			p.pos++
+			p.skipSpaces(false)
+		}
+
+		key, value, err := p.parseKeyValue()
+		if err != nil {
+			return nil, err
+		}
+		table[key] = value
+	}
+}
+
+func (p *tomlParser) parseArray() (interface{}, error) {
+	array := []interface{}{}
+	p.pos++
+
+	for {
+		p.skipSpaces(true)
+		if p.pos < len(p.data) && p.data[p.pos] == ']' {
+			p.pos++
+			return array, nil
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+
+		// Trailing commas are allowed in arrays
+		p.skipSpaces(true)
+		if p.pos < len(p.data) && p.data[p.pos] == ',' {
+			p.pos++
+		} else if p.pos == len(p.data) || p.data[p.pos] != ']' {
+			return nil, p.errorf("expected , or ] in array")
+		}
+	}
+}
@@ -1,22 +1,24 @@
 package chessboard
 
 import (
-	_ "embed"
-	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
-//go:embed precomputed.json
-var rawPrecomputedData []byte
-
 type Result int
 
 const (
 	NoResult Result = iota
 	Draw
 	Checkmate
+	// VariantWin is a win for the side to move decided by the rules of a variant,
+	// e.g. having no moves left in antichess
+	VariantWin
+	// VariantLoss is a loss for the side to move decided by the rules of a variant,
+	// e.g. the opponent's king reaching the center in king of the hill
+	VariantLoss
 )
 
 func (res Result) String() string {
@@ -27,35 +29,26 @@ func (res Result) String() string {
 		return "Draw"
 	case Checkmate:
 		return "Checkmate"
+	case VariantWin:
+		return "VariantWin"
+	case VariantLoss:
+		return "VariantLoss"
 	default:
 		panic("Unknown result")
 	}
 }
 
-// PrecomputedData contains all the precalculated bitboards used in move generation
-type PrecomputedData struct {
-	KingMoves               [64]Bitboard
-	KnightMoves             [64]Bitboard
-	RookMagics              [64]uint64
-	RookIndexBits           [64]int
-	RookMasks               [64]Bitboard
-	RookMoves               [64][4096]Bitboard
-	BishopMagics            [64]uint64
-	BishopIndexBits         [64]int
-	BishopMasks             [64]Bitboard
-	BishopMoves             [64][1024]Bitboard
-	DoublePawnsForwardMasks [64]Bitboard
-	DoublePawnsSidesMasks   [64]Bitboard
-	PassedPawnWhiteMasks    [64]Bitboard
-	PassedPawnBlackMasks    [64]Bitboard
-}
-
 // Game contains all information about the game
 type Game struct {
-	precomputedData  PrecomputedData
+	precomputedData  *PrecomputedData
 	position         *Position
 	positionsHistory []*Position
 	moves            []*Move
+	// variant contains the rules of the game
+	variant Variant
+	// accumulators contains the network accumulators of the positions, nil when
+	// the network evaluation is not used
+	accumulators *accumulatorStack
 }
 
 func (game Game) String() string {
@@ -72,6 +65,11 @@ func (game Game) String() string {
 
 // Result returns the result of the current game
 func (game *Game) Result() Result {
+	return game.variant.result(game)
+}
+
+// standardResult returns the result of the current game with the standard rules
+func (game *Game) standardResult() Result {
 	// Check draws by insufficient material
 	if game.position.board.bbWhitePawn == 0 && game.position.board.bbBlackPawn == 0 &&
 		game.position.board.bbWhiteRook == 0 && game.position.board.bbBlackRook == 0 &&
@@ -89,8 +87,8 @@ func (game *Game) Result() Result {
 		if knights.PopCount() == 0 &&
 			game.position.board.bbWhiteBishop.PopCount() == 1 &&
 			game.position.board.bbBlackBishop.PopCount() == 1 {
-			whiteBishopSquare := square(game.position.board.bbWhiteBishop.LeastSignificant1Bit())
-			blackBishopSquare := square(game.position.board.bbBlackBishop.LeastSignificant1Bit())
+			whiteBishopSquare := Square(game.position.board.bbWhiteBishop.LeastSignificant1Bit())
+			blackBishopSquare := Square(game.position.board.bbBlackBishop.LeastSignificant1Bit())
 
 			if whiteBishopSquare.Color() == blackBishopSquare.Color() {
 				return Draw
@@ -98,6 +96,11 @@ func (game *Game) Result() Result {
 		}
 	}
 
+	return game.movesResult()
+}
+
+// movesResult returns the result of the game decided by the 75 moves rule, checkmate or stalemate
+func (game *Game) movesResult() Result {
 	// Draw by 75 moves rule
 	if game.position.halfMoveClock >= 75 {
 		return Draw
@@ -121,17 +124,13 @@ func (game *Game) Move(move *Move) {
 	pos := game.position.Move(move)
 
 	// update in check status
-	var kingSquare square
-	if pos.turn == WhiteColor {
-		kingSquare = pos.board.whiteKingSquare
-	} else {
-		kingSquare = pos.board.blackKingSquare
+	pos.inCheck = game.variant.royalKing() && pos.isKingAttacked(game.precomputedData)
+	if pos.inCheck && pos.countChecks {
+		pos.addCheck(pos.turn.Other())
 	}
 
-	if pos.board.IsUnderAttack(&game.precomputedData, pos.turn, kingSquare) {
-		pos.inCheck = true
-	} else {
-		pos.inCheck = false
+	if game.accumulators != nil {
+		game.accumulators.push(&game.position.board, &pos.board)
 	}
 
 	game.position = &pos
@@ -139,11 +138,55 @@ func (game *Game) Move(move *Move) {
 	game.moves = append(game.moves, move)
 }
 
+// lastMove returns the last move played in the game, nil if no move was played
+func (game *Game) lastMove() *Move {
+	if len(game.moves) == 0 {
+		return nil
+	}
+
+	return game.moves[len(game.moves)-1]
+}
+
+// ParseUCIMove returns the legal move corresponding to the passed UCI notation (e.g. e7e8q).
+// Castling moves can also be written as the king capturing its own rook (e.g. e1h1), which is
+// the notation used in Chess960
+func (game *Game) ParseUCIMove(uciMove string) (*Move, error) {
+	// In Chess960 the destination of a castling king can also be reached by a normal king move,
+	// so the normal moves are matched first
+	for _, move := range game.LegalMoves() {
+		if !move.IsCastle() && move.UCI() == uciMove {
+			return move, nil
+		}
+	}
+	for _, move := range game.LegalMoves() {
+		if move.IsCastle() && (move.UCI() == uciMove || move.UCIChess960() == uciMove) {
+			return move, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s is not a legal move", uciMove)
+}
+
 // UndoMove undoes the last move
 func (game *Game) UndoMove() {
 	game.moves = game.moves[:len(game.moves)-1]
 	game.positionsHistory = game.positionsHistory[:len(game.positionsHistory)-1]
 	game.position = game.positionsHistory[len(game.positionsHistory)-1]
+
+	if game.accumulators != nil {
+		game.accumulators.pop()
+	}
+}
+
+// setNetwork makes the game update the accumulators of the network while moves are played,
+// a nil network stops the updates
+func (game *Game) setNetwork(net *Network) {
+	if net == nil {
+		game.accumulators = nil
+		return
+	}
+
+	game.accumulators = newAccumulatorStack(net, &game.position.board)
 }
 
 // Position returns the current position in the game
@@ -151,110 +194,278 @@ func (game *Game) Position() Position {
 	return *game.position
 }
 
-// LoadPrecomputedData loads all the precomputed data for fast move generation
-func (game *Game) LoadPrecomputedData(path string) {
-	// jsonBytes, err := ioutil.ReadFile(path)
-	// if err != nil {
-	// 	panic(err)
-	// }
-
-	var data PrecomputedData
-	err := json.Unmarshal(rawPrecomputedData, &data)
-	if err != nil {
-		panic(err)
+// Clone returns a copy of the game that can be played on independently from the original,
+// the cached legal moves are dropped because the search reorders them in place and the
+// network accumulators are dropped because they belong to a single search thread
+func (game *Game) Clone() Game {
+	clone := Game{precomputedData: game.precomputedData, variant: game.variant}
+
+	clone.positionsHistory = make([]*Position, len(game.positionsHistory), cap(game.positionsHistory))
+	for i, pos := range game.positionsHistory {
+		copiedPosition := *pos
+		copiedPosition.legalMoves = nil
+		clone.positionsHistory[i] = &copiedPosition
 	}
+	clone.position = clone.positionsHistory[len(clone.positionsHistory)-1]
+
+	clone.moves = make([]*Move, len(game.moves), cap(game.moves))
+	copy(clone.moves, game.moves)
+
+	return clone
+}
 
-	game.precomputedData = data
+// LoadPrecomputedData loads all the precomputed data for fast move generation
+//
+// Deprecated: the games share the precomputed data, which is loaded when the first game is
+// created, so the method only makes the game use the shared tables and ignores the path.
+// Use UsePrecomputedData to replace the tables with the ones of a file
+func (game *Game) LoadPrecomputedData(path string) {
+	game.precomputedData = sharedPrecomputedData()
 }
 
+// startingPositionFEN is the fen of the standard starting position
+const startingPositionFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
 // NewGame initializes a new game
 func NewGame() Game {
-	startingPositionFEN := "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
 	return NewGameFromFEN(startingPositionFEN)
 }
 
 // NewGameFromFEN initializes a game from a fen string
 func NewGameFromFEN(fen string) Game {
-	initializeZobristHashes()
+	return NewVariantGameFromFEN(Standard, fen)
+}
 
-	game := Game{}
+// NewVariantGame initializes a new game of the variant from its starting position
+func NewVariantGame(variant Variant) Game {
+	return NewVariantGameFromFEN(variant, variant.StartFEN())
+}
+
+// NewVariantGameFromFEN initializes a game of the variant from a fen string
+func NewVariantGameFromFEN(variant Variant, fen string) Game {
+	initializeZobristHashes()
 
-	game.LoadPrecomputedData("precomputed.json")
-	game.positionsHistory = make([]*Position, 0, 40)
+	game := Game{precomputedData: sharedPrecomputedData(), variant: variant}
 	game.moves = make([]*Move, 0, 40)
-	pos := Position{}
+
+	pos := parseFEN(fen, game.precomputedData)
+	variant.setup(&pos)
+	game.position = &pos
+	game.positionsHistory = make([]*Position, 1, 40)
+	game.positionsHistory[0] = game.position
+
+	return game
+}
+
+// setPosition replaces the game with one starting from the passed position
+func (game *Game) setPosition(pos Position) {
+	pos.legalMoves = nil
 	game.position = &pos
+	game.positionsHistory = make([]*Position, 1, 40)
+	game.positionsHistory[0] = game.position
+	game.moves = make([]*Move, 0, 40)
+
+	if game.accumulators != nil {
+		game.accumulators.reset(&game.position.board)
+	}
+}
+
+// parseFEN returns the position described by a fen string, it panics if the fen is invalid
+func parseFEN(fen string, precomputedData *PrecomputedData) Position {
+	initializeZobristHashes()
+
+	pos := Position{precomputedData: precomputedData}
 
 	fen = strings.TrimSpace(fen)
 	pieces := strings.Split(fen, " ")
+	// Three-check fens have a seventh field with the checks, either the remaining checks
+	// after the en passant square (e.g. 3+3) or the given checks at the end (e.g. +0+0)
+	checks := ""
+	if len(pieces) == 7 {
+		if strings.HasPrefix(pieces[6], "+") {
+			checks, pieces = pieces[6], pieces[:6]
+		} else {
+			checks, pieces = pieces[4], append(pieces[:4:4], pieces[5:]...)
+		}
+	}
 	if len(pieces) != 6 {
 		panic("Invalid fen passed: it should have 6 pieces")
 	}
 
+	// Crazyhouse fens have the pockets in brackets after the board and mark the promoted pieces with ~
+	rawBoard, rawPockets := pieces[0], ""
+	if start := strings.IndexByte(rawBoard, '['); start != -1 {
+		if !strings.HasSuffix(rawBoard, "]") {
+			panic("Invalid fen pockets")
+		}
+		rawBoard, rawPockets = rawBoard[:start], rawBoard[start+1:len(rawBoard)-1]
+		pos.hasPockets = true
+	}
+	rawBoard, pos.promoted = parseFenPromoted(rawBoard)
+
 	var hash ZobristHash
-	game.position.board, hash = parseFenBoard(pieces[0])
-	game.position.hash ^= hash
+	pos.board, hash = parseFenBoard(rawBoard)
+	pos.hash ^= hash
+	pos.pawnHash = pos.board.pawnHash()
 
 	switch pieces[1] {
 	case "w":
-		game.position.turn = WhiteColor
+		pos.turn = WhiteColor
 	case "b":
-		game.position.turn = BlackColor
-		game.position.hash ^= zobristHashBlackTurn
+		pos.turn = BlackColor
+		pos.hash ^= zobristHashBlackTurn
 	default:
 		panic("Invalid fen turn string")
 	}
 
-	game.position.castleRights, hash = parseCastleRights(pieces[2])
-	game.position.hash ^= hash
+	pos.castleRights, pos.castlingRooks, hash = parseCastleRights(pieces[2], &pos.board)
+	pos.hash ^= hash
 	enPassantSquare, ok := stringToSquare[pieces[3]]
 	if !ok {
 		panic("Unrecognized en passant square")
 	}
-	game.position.enPassantSquare = enPassantSquare
+	pos.enPassantSquare = enPassantSquare
 	if enPassantSquare != NoSquare {
-		game.position.hash ^= zobristHashEnPassant[enPassantSquare%8]
+		pos.hash ^= zobristHashEnPassant[enPassantSquare%8]
 	}
 
 	halfMoveClock, err := strconv.Atoi(pieces[4])
 	if err != nil || halfMoveClock < 0 {
 		panic("Half move clock should be a non negative number")
 	}
-	game.position.halfMoveClock = halfMoveClock
+	pos.halfMoveClock = halfMoveClock
 
 	moveCount, err := strconv.Atoi(pieces[5])
 	if err != nil || moveCount < 1 {
 		panic("Move count should be a positive number")
 	}
-	game.position.moveCount = moveCount
+	pos.moveCount = moveCount
 
-	game.positionsHistory = []*Position{game.position}
-	// TODO: update in check status
+	if checks != "" {
+		pos.parseChecks(checks)
+	}
+	if pos.hasPockets {
+		pos.parsePockets(rawPockets)
+	}
 
-	return game
+	pos.inCheck = pos.isKingAttacked(precomputedData)
+
+	return pos
 }
 
-func parseCastleRights(rawRights string) (CastleRights, ZobristHash) {
-	hash := ZobristHash(0)
-	rights := CastleRights{}
-	if strings.Contains(rawRights, "K") {
-		rights.WhiteKingSide = true
-		hash ^= zobristHashWhiteKingCastle
+// parseChecks parses the three-check counters in the remaining checks (3+3)
+// or given checks (+0+0) notation and starts counting the checks
+func (pos *Position) parseChecks(raw string) {
+	given := strings.HasPrefix(raw, "+")
+	counters := strings.Split(strings.TrimPrefix(raw, "+"), "+")
+	if len(counters) != 2 {
+		panic("Invalid fen checks field")
 	}
-	if strings.Contains(rawRights, "Q") {
-		rights.WhiteQueenSide = true
-		hash ^= zobristHashWhiteQueenCastle
+
+	pos.countChecks = true
+	for i, counter := range counters {
+		count, err := strconv.Atoi(counter)
+		if err != nil || count < 0 || count > maxChecks {
+			panic("Invalid fen checks field")
+		}
+		if !given {
+			count = maxChecks - count
+		}
+
+		for ; count > 0; count-- {
+			pos.addCheck([2]Color{WhiteColor, BlackColor}[i])
+		}
 	}
-	if strings.Contains(rawRights, "k") {
-		rights.BlackKingSide = true
-		hash ^= zobristHashBlackKingCastle
+}
+
+// parsePockets parses the pieces in the crazyhouse pockets, uppercase for white and lowercase for black
+func (pos *Position) parsePockets(raw string) {
+	for _, char := range raw {
+		kind := strings.IndexRune("KQRBNP", unicode.ToUpper(char))
+		if kind <= 0 {
+			panic("Invalid fen pockets")
+		}
+
+		color := WhiteColor
+		if unicode.IsLower(char) {
+			color = BlackColor
+		}
+		pos.changePocket(color, Piece(kind+1), 1)
 	}
-	if strings.Contains(rawRights, "q") {
-		rights.BlackQueenSide = true
-		hash ^= zobristHashBlackQueenCastle
+}
+
+// parseFenPromoted removes the ~ marking the promoted pieces from a fen board,
+// returns the board without them and the squares of the promoted pieces
+func parseFenPromoted(rawBoard string) (string, Bitboard) {
+	var promoted Bitboard
+	currentSquare := A8
+
+	board := ""
+	for _, char := range rawBoard {
+		switch {
+		case char == '~':
+			if currentSquare == A8 {
+				panic("Invalid promoted piece in fen")
+			}
+			promoted |= (currentSquare - 1).Bitboard()
+			continue
+		case char == '/':
+			currentSquare -= 16
+		case char >= '1' && char <= '8':
+			currentSquare += Square(char - '0')
+		default:
+			currentSquare++
+		}
+
+		board += string(char)
+	}
+
+	return board, promoted
+}
+
+// parseCastleRights parses the castle rights in the standard, X-FEN or Shredder-FEN notation:
+// KQkq are the outermost rooks on each side of the king, while the letters of the files
+// choose the rook on that file, as needed in some Chess960 positions.
+// Returns the rights, the starting squares of the castling rooks and the hash of the rights
+func parseCastleRights(rawRights string, board *Board) (CastleRights, [4]Square, ZobristHash) {
+	hash := ZobristHash(0)
+	rights := [4]bool{}
+	rooks := standardCastlingRooks
+	if rawRights == "-" {
+		return CastleRights{}, rooks, hash
+	}
+
+	for _, char := range rawRights {
+		color, backRank, offset := WhiteColor, 0, 0
+		if unicode.IsLower(char) {
+			color, backRank, offset = BlackColor, 7, 2
+		}
+
+		var rook Square
+		switch upper := unicode.ToUpper(char); {
+		case upper == 'K' || upper == 'Q':
+			rook = outermostRook(board, color, upper == 'K')
+			if rook == NoSquare {
+				rook = standardCastlingRooks[offset+strings.IndexRune("KQ", upper)]
+			}
+		case upper >= 'A' && upper <= 'H':
+			rook = SquareFromFileRank(int(upper-'A'), backRank)
+		default:
+			panic("Invalid fen castle rights")
+		}
+
+		index := offset
+		if rook%8 < board.kingSquare(color)%8 {
+			index++
+		}
+		if !rights[index] {
+			rights[index] = true
+			rooks[index] = rook
+			hash ^= castleRightHash(index)
+		}
 	}
 
-	return rights, hash
+	return CastleRights{rights[0], rights[1], rights[2], rights[3]}, rooks, hash
 }
 
 // example string: rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR
@@ -308,15 +519,15 @@ func parseFenBoard(rawBoard string) (Board, ZobristHash) {
 			currentSquare++
 		case 'b':
 			board.bbBlackBishop |= currentSquare.Bitboard()
-			hash ^= zobristHashMoves[WhiteBishop-1][currentSquare]
+			hash ^= zobristHashMoves[BlackBishop-1][currentSquare]
 			currentSquare++
 		case 'n':
 			board.bbBlackKnight |= currentSquare.Bitboard()
-			hash ^= zobristHashMoves[WhiteKnight-1][currentSquare]
+			hash ^= zobristHashMoves[BlackKnight-1][currentSquare]
 			currentSquare++
 		case 'p':
 			board.bbBlackPawn |= currentSquare.Bitboard()
-			hash ^= zobristHashMoves[WhitePawn-1][currentSquare]
+			hash ^= zobristHashMoves[BlackPawn-1][currentSquare]
 			currentSquare++
 		case '/':
 			currentSquare -= 16
@@ -326,7 +537,7 @@ func parseFenBoard(rawBoard string) (Board, ZobristHash) {
 				panic("Unknown character in FEN board")
 			}
 
-			currentSquare += square(jump)
+			currentSquare += Square(jump)
 		}
 
 		index++
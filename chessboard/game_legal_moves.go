@@ -24,11 +24,14 @@ func (game *Game) LegalMoves() []*Move {
 	computeQueenMoves(game, &pseudolegalMoves, &ownPieces)
 	computePawnMoves(game, &pseudolegalMoves)
 
+	// When the king isn't royal, as in antichess, all the pseudolegal moves are legal
+	royalKing := game.variant.royalKing()
 	legalMoves := make([]*Move, 0, len(pseudolegalMoves))
 	for i := 0; i < len(pseudolegalMoves); i++ {
-		if checkMoveLegality(pseudolegalMoves[i], game) {
+		if !royalKing || checkMoveLegality(pseudolegalMoves[i], game) {
 			// Captures should reset the half move clock
-			if pseudolegalMoves[i].To().Bitboard()&game.position.board.emptySquares == 0 {
+			// Castling moves are excluded because in Chess960 the king can move to the square of the rook
+			if !pseudolegalMoves[i].IsCastle() && pseudolegalMoves[i].To().Bitboard()&game.position.board.emptySquares == 0 {
 				*pseudolegalMoves[i] |= ResetHalfMoveClockFlag
 				*pseudolegalMoves[i] |= IsCaptureFlag
 			}
@@ -37,6 +40,12 @@ func (game *Game) LegalMoves() []*Move {
 		}
 	}
 
+	if game.position.hasPockets {
+		computeDropMoves(game, &legalMoves)
+	}
+
+	legalMoves = game.variant.filterMoves(game.position, legalMoves)
+
 	game.position.legalMoves = legalMoves
 	return legalMoves
 }
@@ -46,81 +55,155 @@ func checkMoveLegality(move *Move, game *Game) bool {
 	simulationBoard := game.position.board
 	simulationBoard.Move(move)
 
-	var kingSquare square
+	var kingSquare Square
+	var kings, enemyKings Bitboard
 	if game.position.turn == WhiteColor {
-		kingSquare = simulationBoard.whiteKingSquare
+		kingSquare, kings, enemyKings = simulationBoard.whiteKingSquare, simulationBoard.bbWhiteKing, simulationBoard.bbBlackKing
 	} else {
-		kingSquare = simulationBoard.blackKingSquare
+		kingSquare, kings, enemyKings = simulationBoard.blackKingSquare, simulationBoard.bbBlackKing, simulationBoard.bbWhiteKing
+	}
+
+	// In horde white has no king that can be left in check, while in atomic chess
+	// the moves exploding the own king are illegal
+	if kings == 0 {
+		return game.position.board.sidePieces(game.position.turn).king == 0
+	}
+	// Exploding the enemy king wins in atomic chess, even leaving the own king in check
+	if enemyKings == 0 && game.position.board.sidePieces(game.position.turn.Other()).king != 0 {
+		return true
 	}
 
-	return !simulationBoard.IsUnderAttack(&game.precomputedData, game.position.turn, kingSquare)
+	return !simulationBoard.IsUnderAttack(game.precomputedData, game.position.turn, kingSquare)
 }
 
-// Bitboards for the squares that must be empty in order to castle
-const InBetweenWhiteKingCastle = Bitboard(96)
-const InBetweenWhiteQueenCastle = Bitboard(14)
-const InBetweenBlackKingCastle = Bitboard(6917529027641081856)
-const InBetweenBlackQueenCastle = Bitboard(1008806316530991104)
+// computeDropMoves adds the legal drops of the pieces in the pocket of the side to move to the moves.
+// Pawns can't be dropped on the first and last rank
+func computeDropMoves(game *Game, moves *[]*Move) {
+	pos := game.position
+	index := 0
+	if pos.turn == BlackColor {
+		index = 1
+	}
+
+	for kind, count := range pos.pockets[index] {
+		if count == 0 {
+			continue
+		}
+
+		piece := Piece(kind + 1)
+		if pos.turn == BlackColor {
+			piece += 6
+		}
+
+		targets := pos.board.emptySquares
+		if kind == 5 {
+			targets &^= rankBitboard(0) | rankBitboard(7)
+		}
+
+		for ; targets != 0; targets.ClearLeastSignificant1Bit() {
+			move := newDropMove(piece, Square(targets.LeastSignificant1Bit()))
+
+			// A drop can't leave the king in check unless it was already in check
+			if !pos.inCheck || checkMoveLegality(move, game) {
+				*moves = append(*moves, move)
+			}
+		}
+	}
+}
 
 func computeKingMoves(game *Game, moves *[]*Move, ownPieces *Bitboard) {
-	var kingSquare square
+	// There can be any number of kings in antichess and no kings in horde
+	var kings Bitboard
+	var kingSquare Square
 	if game.position.turn == WhiteColor {
-		kingSquare = game.position.board.whiteKingSquare
+		kings, kingSquare = game.position.board.bbWhiteKing, game.position.board.whiteKingSquare
 	} else {
-		kingSquare = game.position.board.blackKingSquare
+		kings, kingSquare = game.position.board.bbBlackKing, game.position.board.blackKingSquare
 	}
 
-	// Get precomputed king moves for that square and remove self-captures
-	kingMovesBB := game.precomputedData.KingMoves[kingSquare]
-	kingMovesBB &^= *ownPieces
+	for kings != 0 {
+		fromSquare := Square(kings.LeastSignificant1Bit())
+		kings.ClearLeastSignificant1Bit()
 
-	// Iterating target squares in the bitboard and add moves to the list
-	fromSquare := kingSquare
-	for kingMovesBB != 0 {
-		toSquare := square(kingMovesBB.LeastSignificant1Bit())
-		*moves = append(*moves, NewMove(fromSquare, toSquare, NoPiece, NoFlag))
+		// Get precomputed king moves for that square and remove self-captures
+		kingMovesBB := game.precomputedData.KingMoves[fromSquare]
+		kingMovesBB &^= *ownPieces
+
+		// Iterating target squares in the bitboard and add moves to the list
+		for kingMovesBB != 0 {
+			toSquare := Square(kingMovesBB.LeastSignificant1Bit())
+			*moves = append(*moves, NewMove(fromSquare, toSquare, NoPiece, NoFlag))
 
-		kingMovesBB.ClearLeastSignificant1Bit()
+			kingMovesBB.ClearLeastSignificant1Bit()
+		}
 	}
 
-	// Check castling conditions: still have rights to castle, squares between rook and king are free,
-	// not under check, king will not move in or through an attacked position
-	if game.position.turn == WhiteColor {
-		if game.position.castleRights.WhiteKingSide &&
-			(InBetweenWhiteKingCastle&game.position.board.emptySquares == InBetweenWhiteKingCastle) &&
-			!game.position.board.IsUnderAttack(&game.precomputedData, game.position.turn, E1) &&
-			!game.position.board.IsUnderAttack(&game.precomputedData, game.position.turn, F1) &&
-			!game.position.board.IsUnderAttack(&game.precomputedData, game.position.turn, G1) {
-			*moves = append(*moves, NewMove(E1, G1, NoPiece, WhiteKingCastleFlag))
+	computeCastlingMoves(game, moves, kingSquare)
+}
+
+// computeCastlingMoves adds the castling moves to the list. The rules are the Chess960 ones, which
+// include the standard ones: the player still has the right to castle, the squares crossed by the king
+// and the rook are empty, the king isn't in check and doesn't move through or to an attacked square.
+// The king ends on the G or C file and the rook next to it on the F or D file
+func computeCastlingMoves(game *Game, moves *[]*Move, kingSquare Square) {
+	pos := game.position
+	rights := [2]bool{pos.castleRights.WhiteKingSide, pos.castleRights.WhiteQueenSide}
+	flags := [2]MoveFlags{WhiteKingCastleFlag, WhiteQueenCastleFlag}
+	offset, backRank := 0, 0
+	if pos.turn == BlackColor {
+		rights = [2]bool{pos.castleRights.BlackKingSide, pos.castleRights.BlackQueenSide}
+		flags = [2]MoveFlags{BlackKingCastleFlag, BlackQueenCastleFlag}
+		offset, backRank = 2, 7
+	}
+
+	if pos.board.sidePieces(pos.turn).king&kingSquare.Bitboard() == 0 {
+		return
+	}
+
+	for side, right := range rights {
+		rookSquare := pos.castlingRooks[offset+side]
+		if !right || pos.board.sidePieces(pos.turn).rooks&rookSquare.Bitboard() == 0 {
+			continue
 		}
 
-		if game.position.castleRights.WhiteQueenSide &&
-			(InBetweenWhiteQueenCastle&game.position.board.emptySquares == InBetweenWhiteQueenCastle) &&
-			!game.position.board.IsUnderAttack(&game.precomputedData, game.position.turn, E1) &&
-			!game.position.board.IsUnderAttack(&game.precomputedData, game.position.turn, D1) &&
-			!game.position.board.IsUnderAttack(&game.precomputedData, game.position.turn, C1) {
-			*moves = append(*moves, NewMove(E1, C1, NoPiece, WhiteQueenCastleFlag))
+		kingTo, rookTo := SquareFromFileRank(6, backRank), SquareFromFileRank(5, backRank)
+		if side == 1 {
+			kingTo, rookTo = SquareFromFileRank(2, backRank), SquareFromFileRank(3, backRank)
 		}
-	} else {
-		if game.position.castleRights.BlackKingSide &&
-			(InBetweenBlackKingCastle&game.position.board.emptySquares == InBetweenBlackKingCastle) &&
-			!game.position.board.IsUnderAttack(&game.precomputedData, game.position.turn, E8) &&
-			!game.position.board.IsUnderAttack(&game.precomputedData, game.position.turn, F8) &&
-			!game.position.board.IsUnderAttack(&game.precomputedData, game.position.turn, G8) {
-			*moves = append(*moves, NewMove(E8, G8, NoPiece, BlackKingCastleFlag))
+
+		path := rankSegment(kingSquare, kingTo) | rankSegment(rookSquare, rookTo)
+		path &^= kingSquare.Bitboard() | rookSquare.Bitboard()
+		if path&^pos.board.emptySquares != 0 {
+			continue
 		}
 
-		if game.position.castleRights.BlackQueenSide &&
-			(InBetweenBlackQueenCastle&game.position.board.emptySquares == InBetweenBlackQueenCastle) &&
-			!game.position.board.IsUnderAttack(&game.precomputedData, game.position.turn, E8) &&
-			!game.position.board.IsUnderAttack(&game.precomputedData, game.position.turn, D8) &&
-			!game.position.board.IsUnderAttack(&game.precomputedData, game.position.turn, C8) {
-			*moves = append(*moves, NewMove(E8, C8, NoPiece, BlackQueenCastleFlag))
+		// The castling rook is removed when looking for attacks to the destination of the king,
+		// because in Chess960 it can hide an attack on the back rank
+		board := pos.board
+		board.removeCastlingRook(rookSquare)
+		attacked := board.IsUnderAttack(game.precomputedData, pos.turn, kingTo)
+		kingPath := rankSegment(kingSquare, kingTo) &^ kingTo.Bitboard()
+		for kingPath != 0 && !attacked {
+			sq := Square(kingPath.LeastSignificant1Bit())
+			kingPath.ClearLeastSignificant1Bit()
+			attacked = pos.board.IsUnderAttack(game.precomputedData, pos.turn, sq)
 		}
 
+		if !attacked {
+			*moves = append(*moves, newCastleMove(kingSquare, kingTo, rookSquare, flags[side]))
+		}
 	}
 }
 
+// rankSegment returns the squares between a and b on the same rank, both included
+func rankSegment(a Square, b Square) Bitboard {
+	if a > b {
+		a, b = b, a
+	}
+
+	return Bitboard(1)<<(b+1) - Bitboard(1)<<a
+}
+
 func computeKnightMoves(game *Game, moves *[]*Move, ownPieces *Bitboard) {
 	var knights Bitboard
 	if game.position.turn == WhiteColor {
@@ -130,7 +213,7 @@ func computeKnightMoves(game *Game, moves *[]*Move, ownPieces *Bitboard) {
 	}
 
 	for knights != 0 {
-		fromSquare := square(knights.LeastSignificant1Bit())
+		fromSquare := Square(knights.LeastSignificant1Bit())
 		knights.ClearLeastSignificant1Bit()
 
 		// Get precomputed king moves for that square and remove self-captures
@@ -138,7 +221,7 @@ func computeKnightMoves(game *Game, moves *[]*Move, ownPieces *Bitboard) {
 		knightMovesBB &^= *ownPieces
 
 		for knightMovesBB != 0 {
-			toSquare := square(knightMovesBB.LeastSignificant1Bit())
+			toSquare := Square(knightMovesBB.LeastSignificant1Bit())
 			knightMovesBB.ClearLeastSignificant1Bit()
 
 			*moves = append(*moves, NewMove(fromSquare, toSquare, NoPiece, NoFlag))
@@ -152,17 +235,17 @@ func computePawnMoves(game *Game, moves *[]*Move) {
 
 		// iterate all white pawns on the board
 		for pawns != 0 {
-			fromSquare := square(pawns.LeastSignificant1Bit())
+			fromSquare := Square(pawns.LeastSignificant1Bit())
 			pawns.ClearLeastSignificant1Bit()
 
 			// Move forward
-			forwardSquare := square(fromSquare + 8)
+			forwardSquare := Square(fromSquare + 8)
 			if game.position.board.emptySquares.IsSquareOccupied(forwardSquare) {
 				appendPawnMove(fromSquare, forwardSquare, moves)
 
 				// Move forward by two squares
 				if fromSquare < A3 {
-					forwardSquare := square(fromSquare + 16)
+					forwardSquare := Square(fromSquare + 16)
 					if game.position.board.emptySquares.IsSquareOccupied(forwardSquare) {
 						*moves = append(*moves, NewMove(
 							fromSquare,
@@ -175,7 +258,7 @@ func computePawnMoves(game *Game, moves *[]*Move) {
 
 			// Capture to the left
 			if int(fromSquare)%8 != 0 {
-				captureSquare := square(fromSquare + 7)
+				captureSquare := Square(fromSquare + 7)
 				if game.position.board.blackSquares.IsSquareOccupied(captureSquare) {
 					appendPawnMove(fromSquare, captureSquare, moves)
 				} else if game.position.enPassantSquare == captureSquare {
@@ -189,7 +272,7 @@ func computePawnMoves(game *Game, moves *[]*Move) {
 
 			// Capture to the right
 			if int(fromSquare)%8 != 7 {
-				captureSquare := square(fromSquare + 9)
+				captureSquare := Square(fromSquare + 9)
 				if game.position.board.blackSquares.IsSquareOccupied(captureSquare) {
 					appendPawnMove(fromSquare, captureSquare, moves)
 				} else if game.position.enPassantSquare == captureSquare {
@@ -206,17 +289,17 @@ func computePawnMoves(game *Game, moves *[]*Move) {
 
 		// iterate all black pawns on the board
 		for pawns != 0 {
-			fromSquare := square(pawns.LeastSignificant1Bit())
+			fromSquare := Square(pawns.LeastSignificant1Bit())
 			pawns.ClearLeastSignificant1Bit()
 
 			// Move forward
-			forwardSquare := square(fromSquare - 8)
+			forwardSquare := Square(fromSquare - 8)
 			if game.position.board.emptySquares.IsSquareOccupied(forwardSquare) {
 				appendPawnMove(fromSquare, forwardSquare, moves)
 
 				// Move forward by two squares
 				if fromSquare > H6 {
-					forwardSquare := square(fromSquare - 16)
+					forwardSquare := Square(fromSquare - 16)
 					if game.position.board.emptySquares.IsSquareOccupied(forwardSquare) {
 						*moves = append(*moves, NewMove(
 							fromSquare,
@@ -229,7 +312,7 @@ func computePawnMoves(game *Game, moves *[]*Move) {
 
 			// Capture to the left
 			if int(fromSquare)%8 != 0 {
-				captureSquare := square(fromSquare - 9)
+				captureSquare := Square(fromSquare - 9)
 				if game.position.board.whiteSquares.IsSquareOccupied(captureSquare) {
 					appendPawnMove(fromSquare, captureSquare, moves)
 				} else if game.position.enPassantSquare == captureSquare {
@@ -243,7 +326,7 @@ func computePawnMoves(game *Game, moves *[]*Move) {
 
 			// Capture to the right
 			if int(fromSquare)%8 != 7 {
-				captureSquare := square(fromSquare - 7)
+				captureSquare := Square(fromSquare - 7)
 				if game.position.board.whiteSquares.IsSquareOccupied(captureSquare) {
 					appendPawnMove(fromSquare, captureSquare, moves)
 				} else if game.position.enPassantSquare == captureSquare {
@@ -258,7 +341,7 @@ func computePawnMoves(game *Game, moves *[]*Move) {
 	}
 }
 
-func appendPawnMove(from square, to square, moves *[]*Move) {
+func appendPawnMove(from Square, to Square, moves *[]*Move) {
 	if to > H7 {
 		*moves = append(*moves, NewMove(from, to, WhiteBishop, ResetHalfMoveClockFlag|IsCaptureFlag))
 		*moves = append(*moves, NewMove(from, to, WhiteKnight, ResetHalfMoveClockFlag|IsCaptureFlag))
@@ -283,21 +366,17 @@ func computeRookMoves(game *Game, moves *[]*Move, ownPieces *Bitboard) {
 	}
 
 	for rooks != 0 {
-		fromSquare := square(rooks.LeastSignificant1Bit())
+		fromSquare := Square(rooks.LeastSignificant1Bit())
 		rooks.ClearLeastSignificant1Bit()
 
-		blockers := (^game.position.board.emptySquares) & game.precomputedData.RookMasks[fromSquare]
-
-		key := (uint64(blockers) * game.precomputedData.RookMagics[fromSquare]) >> (64 - game.precomputedData.RookIndexBits[fromSquare])
-
 		// Return the preinitialized attack set bitboard from the table
-		rookMovesBB := game.precomputedData.RookMoves[fromSquare][key]
+		rookMovesBB := rookAttacks(game.precomputedData, fromSquare, ^game.position.board.emptySquares)
 
 		// Remove self-captures
 		rookMovesBB &^= *ownPieces
 
 		for rookMovesBB != 0 {
-			toSquare := square(rookMovesBB.LeastSignificant1Bit())
+			toSquare := Square(rookMovesBB.LeastSignificant1Bit())
 			rookMovesBB.ClearLeastSignificant1Bit()
 
 			*moves = append(*moves, NewMove(fromSquare, toSquare, NoPiece, NoFlag))
@@ -314,21 +393,17 @@ func computeBishopMoves(game *Game, moves *[]*Move, ownPieces *Bitboard) {
 	}
 
 	for bishops != 0 {
-		fromSquare := square(bishops.LeastSignificant1Bit())
+		fromSquare := Square(bishops.LeastSignificant1Bit())
 		bishops.ClearLeastSignificant1Bit()
 
-		blockers := (^game.position.board.emptySquares) & game.precomputedData.BishopMasks[fromSquare]
-
-		key := (uint64(blockers) * game.precomputedData.BishopMagics[fromSquare]) >> (64 - game.precomputedData.BishopIndexBits[fromSquare])
-
 		// Return the preinitialized attack set bitboard from the table
-		bishopMovesBB := game.precomputedData.BishopMoves[fromSquare][key]
+		bishopMovesBB := bishopAttacks(game.precomputedData, fromSquare, ^game.position.board.emptySquares)
 
 		// Remove self-captures
 		bishopMovesBB &^= *ownPieces
 
 		for bishopMovesBB != 0 {
-			toSquare := square(bishopMovesBB.LeastSignificant1Bit())
+			toSquare := Square(bishopMovesBB.LeastSignificant1Bit())
 			bishopMovesBB.ClearLeastSignificant1Bit()
 
 			*moves = append(*moves, NewMove(fromSquare, toSquare, NoPiece, NoFlag))
@@ -346,18 +421,13 @@ func computeQueenMoves(game *Game, moves *[]*Move, ownPieces *Bitboard) {
 
 	// Compute the moves of each queen by considering both rook and bishop moves
 	for queens != 0 {
-		fromSquare := square(queens.LeastSignificant1Bit())
+		fromSquare := Square(queens.LeastSignificant1Bit())
 		queens.ClearLeastSignificant1Bit()
 
-		blockersBishop := (^game.position.board.emptySquares) & game.precomputedData.BishopMasks[fromSquare]
-		blockersRook := (^game.position.board.emptySquares) & game.precomputedData.RookMasks[fromSquare]
-
-		keyBishop := (uint64(blockersBishop) * game.precomputedData.BishopMagics[fromSquare]) >> (64 - game.precomputedData.BishopIndexBits[fromSquare])
-		keyRook := (uint64(blockersRook) * game.precomputedData.RookMagics[fromSquare]) >> (64 - game.precomputedData.RookIndexBits[fromSquare])
-
 		// Return the preinitialized attack set bitboard from the table
-		bishopMovesBB := game.precomputedData.BishopMoves[fromSquare][keyBishop]
-		rookMovesBB := game.precomputedData.RookMoves[fromSquare][keyRook]
+		occupied := ^game.position.board.emptySquares
+		bishopMovesBB := bishopAttacks(game.precomputedData, fromSquare, occupied)
+		rookMovesBB := rookAttacks(game.precomputedData, fromSquare, occupied)
 
 		queenMovesBB := bishopMovesBB | rookMovesBB
 
@@ -365,7 +435,7 @@ func computeQueenMoves(game *Game, moves *[]*Move, ownPieces *Bitboard) {
 		queenMovesBB &^= *ownPieces
 
 		for queenMovesBB != 0 {
-			toSquare := square(queenMovesBB.LeastSignificant1Bit())
+			toSquare := Square(queenMovesBB.LeastSignificant1Bit())
 			queenMovesBB.ClearLeastSignificant1Bit()
 
 			*moves = append(*moves, NewMove(fromSquare, toSquare, NoPiece, NoFlag))
@@ -0,0 +1,172 @@
+package chessboard
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sanPieceLetters are the letters of the pieces in the standard algebraic notation, indexed by piece type
+const sanPieceLetters = "KQRBNP"
+
+// SAN returns the move in the standard algebraic notation used by PGN, e.g. Nbd7, exd5, e8=Q+ or O-O.
+// The move must be legal in the current position
+func (game *Game) SAN(move *Move) string {
+	san := game.sanWithoutCheck(move)
+
+	// The check and mate markers need the position after the move
+	game.Move(move)
+	if game.position.inCheck {
+		if len(game.LegalMoves()) == 0 {
+			san += "#"
+		} else {
+			san += "+"
+		}
+	}
+	game.UndoMove()
+
+	return san
+}
+
+// sanWithoutCheck returns the move in the standard algebraic notation without the check and mate markers
+func (game *Game) sanWithoutCheck(move *Move) string {
+	switch {
+	case move.IsDrop():
+		return move.UCI()
+	case move.IsCastle():
+		if *move&(WhiteKingCastleFlag|BlackKingCastleFlag) != 0 {
+			return "O-O"
+		}
+		return "O-O-O"
+	}
+
+	board := &game.position.board
+	piece := board.Piece(move.From())
+	from, to := move.From(), move.To()
+
+	if piece == WhitePawn || piece == BlackPawn {
+		san := ""
+		// Pawns change file only when capturing, en passant included
+		if from%8 != to%8 {
+			san = from.String()[:1] + "x"
+		}
+		san += to.String()
+		if promotion := move.Promotion(); promotion != NoPiece {
+			san += "=" + string(sanPieceLetters[(promotion-1)%6])
+		}
+
+		return san
+	}
+
+	san := string(sanPieceLetters[(piece-1)%6])
+
+	// The starting file, rank or both are added when another piece of the same type can reach the square
+	ambiguous, sameFile, sameRank := false, false, false
+	for _, other := range game.LegalMoves() {
+		if other.IsCastle() || other.IsDrop() || other.To() != to || other.From() == from || board.Piece(other.From()) != piece {
+			continue
+		}
+
+		ambiguous = true
+		sameFile = sameFile || other.From()%8 == from%8
+		sameRank = sameRank || other.From()/8 == from/8
+	}
+	switch {
+	case ambiguous && !sameFile:
+		san += from.String()[:1]
+	case ambiguous && !sameRank:
+		san += from.String()[1:]
+	case ambiguous:
+		san += from.String()
+	}
+
+	if board.Piece(to) != NoPiece {
+		san += "x"
+	}
+
+	return san + to.String()
+}
+
+// ParseSANMove returns the legal move corresponding to the passed standard algebraic notation (e.g. Nbd7).
+// The check markers and the annotations (e.g. ! or ?!) are ignored, castling can also be written with zeros
+// and the pawn drops of crazyhouse without the piece letter (e.g. @e4). The non canonical notations used
+// by some programs are accepted too: the capture marker and the = before the promotion can be omitted
+// and the starting square can be given even if the move isn't ambiguous (e.g. Ngf3, Ng1-f3 or e8Q)
+func (game *Game) ParseSANMove(san string) (*Move, error) {
+	cleaned := strings.TrimRight(san, "+#!?")
+	cleaned = strings.ReplaceAll(cleaned, "0", "O")
+	if strings.HasPrefix(cleaned, "@") {
+		cleaned = "P" + cleaned
+	}
+
+	// Castling and drops have a single notation
+	if strings.HasPrefix(cleaned, "O-O") || strings.Contains(cleaned, "@") {
+		for _, move := range game.LegalMoves() {
+			if (move.IsCastle() || move.IsDrop()) && game.sanWithoutCheck(move) == cleaned {
+				return move, nil
+			}
+		}
+
+		return nil, fmt.Errorf("%s is not a legal move", san)
+	}
+
+	cleaned = strings.NewReplacer("x", "", "-", "", "=", "").Replace(cleaned)
+
+	// The piece letter is uppercase, so it can't be confused with the b file
+	pieceLetter := byte('P')
+	if len(cleaned) > 0 && strings.IndexByte(sanPieceLetters, cleaned[0]) != -1 {
+		pieceLetter, cleaned = cleaned[0], cleaned[1:]
+	}
+	promotionLetter := byte(0)
+	if len(cleaned) > 0 && strings.IndexByte("QRBN", cleaned[len(cleaned)-1]) != -1 {
+		promotionLetter, cleaned = cleaned[len(cleaned)-1], cleaned[:len(cleaned)-1]
+	}
+	if len(cleaned) < 2 || len(cleaned) > 4 {
+		return nil, fmt.Errorf("%s is not a valid SAN move", san)
+	}
+	to, err := ParseSquare(cleaned[len(cleaned)-2:])
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid SAN move", san)
+	}
+
+	// The remaining characters are the optional file and rank of the starting square
+	fromFile, fromRank := -1, -1
+	for _, c := range cleaned[:len(cleaned)-2] {
+		switch {
+		case c >= 'a' && c <= 'h' && fromFile == -1 && fromRank == -1:
+			fromFile = int(c - 'a')
+		case c >= '1' && c <= '8' && fromRank == -1:
+			fromRank = int(c - '1')
+		default:
+			return nil, fmt.Errorf("%s is not a valid SAN move", san)
+		}
+	}
+
+	var found *Move
+	for _, move := range game.LegalMoves() {
+		from := move.From()
+		if move.IsCastle() || move.IsDrop() || move.To() != to ||
+			sanPieceLetters[(game.position.board.Piece(from)-1)%6] != pieceLetter ||
+			(fromFile != -1 && int(from%8) != fromFile) || (fromRank != -1 && int(from/8) != fromRank) {
+			continue
+		}
+
+		promotion := byte(0)
+		if move.Promotion() != NoPiece {
+			promotion = sanPieceLetters[(move.Promotion()-1)%6]
+		}
+		if promotion != promotionLetter {
+			continue
+		}
+
+		if found != nil {
+			return nil, fmt.Errorf("%s is ambiguous", san)
+		}
+		found = move
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("%s is not a legal move", san)
+	}
+
+	return found, nil
+}
@@ -1,6 +1,7 @@
 package chessboard
 
 import (
+	"math/rand"
 	"testing"
 )
 
@@ -45,6 +46,33 @@ func TestMoveGenerationOther5Ply(t *testing.T) {
 	}
 }
 
+func TestPrecomputedData(t *testing.T) {
+	first, second := NewGame(), NewGameFromFEN("4k3/8/8/8/8/8/8/4K3 w - - 0 1")
+	if first.precomputedData != second.precomputedData {
+		t.Errorf("The precomputed data should be shared by all the games")
+	}
+
+	// The magic lookups give the same attacks as walking the rays on random boards
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		sq := Square(random.Intn(64))
+		occupied := Bitboard(random.Uint64() & random.Uint64())
+
+		if attacks := rookAttacks(first.precomputedData, sq, occupied); attacks != slidingAttacks(sq, occupied, rookDirections) {
+			t.Fatalf("The rook attacks from %s with %v occupied are wrong, %v was returned instead", sq, occupied, attacks)
+		}
+		if attacks := bishopAttacks(first.precomputedData, sq, occupied); attacks != slidingAttacks(sq, occupied, bishopDirections) {
+			t.Fatalf("The bishop attacks from %s with %v occupied are wrong, %v was returned instead", sq, occupied, attacks)
+		}
+	}
+}
+
+func BenchmarkNewGame(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		NewGameFromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	}
+}
+
 func BenchmarkMoveGeneration6Ply(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		game := NewGame()
@@ -82,18 +110,13 @@ func BenchmarkStaticQueenMovesGeneration(b *testing.B) {
 
 		// Compute the moves of each queen by considering both rook and bishop moves
 		for queens != 0 {
-			fromSquare := square(queens.LeastSignificant1Bit())
+			fromSquare := Square(queens.LeastSignificant1Bit())
 			queens.ClearLeastSignificant1Bit()
 
-			blockersBishop := (^game.position.board.emptySquares) & game.precomputedData.BishopMasks[fromSquare]
-			blockersRook := (^game.position.board.emptySquares) & game.precomputedData.RookMasks[fromSquare]
-
-			keyBishop := (uint64(blockersBishop) * game.precomputedData.BishopMagics[fromSquare]) >> (64 - game.precomputedData.BishopIndexBits[fromSquare])
-			keyRook := (uint64(blockersRook) * game.precomputedData.RookMagics[fromSquare]) >> (64 - game.precomputedData.RookIndexBits[fromSquare])
-
 			// Return the preinitialized attack set bitboard from the table
-			bishopMovesBB := game.precomputedData.BishopMoves[fromSquare][keyBishop]
-			rookMovesBB := game.precomputedData.RookMoves[fromSquare][keyRook]
+			occupied := ^game.position.board.emptySquares
+			bishopMovesBB := bishopAttacks(game.precomputedData, fromSquare, occupied)
+			rookMovesBB := rookAttacks(game.precomputedData, fromSquare, occupied)
 
 			queenMovesBB := bishopMovesBB | rookMovesBB
 
@@ -101,7 +124,7 @@ func BenchmarkStaticQueenMovesGeneration(b *testing.B) {
 			queenMovesBB &^= *ownPieces
 
 			for queenMovesBB != 0 {
-				toSquare := square(queenMovesBB.LeastSignificant1Bit())
+				toSquare := Square(queenMovesBB.LeastSignificant1Bit())
 				queenMovesBB.ClearLeastSignificant1Bit()
 
 				*moves = append(*moves, *NewMove(fromSquare, toSquare, NoPiece, NoFlag))
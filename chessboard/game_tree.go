@@ -0,0 +1,217 @@
+package chessboard
+
+// GameTreeNode is a node of a game tree, it contains the position reached by playing
+// its move from the parent node and the lines which continue from it
+type GameTreeNode struct {
+	// Move is the move leading from the parent to the node, nil for the root
+	Move *Move
+	// Comment is the comment after the move, StartingComment is the one before it,
+	// which is used at the beginning of the variations
+	Comment         string
+	StartingComment string
+	// NAGs are the numeric annotation glyphs of the move, e.g. 1 for ! and 2 for ?
+	NAGs []int
+	// Parent is nil for the root. The first child continues the line of the node,
+	// the others are the alternative variations
+	Parent   *GameTreeNode
+	Children []*GameTreeNode
+
+	position Position
+}
+
+// Position returns the position reached in the node
+func (node *GameTreeNode) Position() Position {
+	return node.position
+}
+
+// IsMainLine returns whether the node is on the main line of the game,
+// i.e. all its ancestors are the first child of their parent
+func (node *GameTreeNode) IsMainLine() bool {
+	for ; node.Parent != nil; node = node.Parent {
+		if node.Parent.Children[0] != node {
+			return false
+		}
+	}
+
+	return true
+}
+
+// child returns the child of the node reached with the passed move, nil if there is none
+func (node *GameTreeNode) child(move *Move) *GameTreeNode {
+	for _, child := range node.Children {
+		if *child.Move == *move {
+			return child
+		}
+	}
+
+	return nil
+}
+
+// siblingIndex returns the position of the node among the children of its parent
+func (node *GameTreeNode) siblingIndex() int {
+	for i, sibling := range node.Parent.Children {
+		if sibling == node {
+			return i
+		}
+	}
+
+	panic("The node is not a child of its parent")
+}
+
+// GameTree contains a game with all its variations, comments and annotations.
+// The tree is explored moving the current node, the game at the current node
+// is kept up to date to generate the legal moves and evaluate the position
+type GameTree struct {
+	// Tags are the PGN tags of the game, e.g. Event, White, Black and Result
+	Tags map[string]string
+	Root *GameTreeNode
+
+	current *GameTreeNode
+	game    Game
+}
+
+// NewGameTree returns a game tree starting from the current position of the game,
+// the previous moves of the game are kept to detect the repetitions
+func NewGameTree(game *Game) *GameTree {
+	root := &GameTreeNode{position: *game.position}
+	root.position.legalMoves = nil
+
+	return &GameTree{
+		Tags:    map[string]string{},
+		Root:    root,
+		current: root,
+		game:    game.Clone(),
+	}
+}
+
+// Current returns the current node of the tree
+func (tree *GameTree) Current() *GameTreeNode {
+	return tree.current
+}
+
+// Game returns the game at the current node, it's changed when moving in the tree
+// and must not be modified directly
+func (tree *GameTree) Game() *Game {
+	return &tree.game
+}
+
+// AddMove plays a legal move from the current node, which becomes the child reached with the move.
+// A new variation is added when the move wasn't already played from the current node
+func (tree *GameTree) AddMove(move *Move) *GameTreeNode {
+	node := tree.current.child(move)
+	if node == nil {
+		copiedMove := *move
+		node = &GameTreeNode{Move: &copiedMove, Parent: tree.current}
+		tree.current.Children = append(tree.current.Children, node)
+	}
+
+	tree.game.Move(node.Move)
+	node.position = *tree.game.position
+	node.position.legalMoves = nil
+	tree.current = node
+
+	return node
+}
+
+// Forward moves to the first child of the current node, returns false when there are no children
+func (tree *GameTree) Forward() bool {
+	if len(tree.current.Children) == 0 {
+		return false
+	}
+
+	tree.game.Move(tree.current.Children[0].Move)
+	tree.current = tree.current.Children[0]
+	return true
+}
+
+// Back moves to the parent of the current node, returns false at the root
+func (tree *GameTree) Back() bool {
+	if tree.current.Parent == nil {
+		return false
+	}
+
+	tree.game.UndoMove()
+	tree.current = tree.current.Parent
+	return true
+}
+
+// GoTo jumps to a node of the tree, it panics if the node belongs to another tree
+func (tree *GameTree) GoTo(node *GameTreeNode) {
+	currentPath := map[*GameTreeNode]bool{}
+	for n := tree.current; n != nil; n = n.Parent {
+		currentPath[n] = true
+	}
+
+	// Go back to the closest common ancestor and then follow the path to the node
+	path := []*GameTreeNode{}
+	ancestor := node
+	for !currentPath[ancestor] {
+		path = append(path, ancestor)
+		ancestor = ancestor.Parent
+		if ancestor == nil {
+			panic("The node doesn't belong to the game tree")
+		}
+	}
+
+	for tree.current != ancestor {
+		tree.Back()
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		tree.game.Move(path[i].Move)
+		tree.current = path[i]
+	}
+}
+
+// PromoteVariation moves the variation starting with the node one place up among its siblings,
+// the first variation becomes the main continuation
+func (tree *GameTree) PromoteVariation(node *GameTreeNode) {
+	if node.Parent == nil {
+		panic("The root can't be promoted")
+	}
+
+	siblings := node.Parent.Children
+	if i := node.siblingIndex(); i > 0 {
+		siblings[i-1], siblings[i] = siblings[i], siblings[i-1]
+	}
+}
+
+// PromoteToMainLine makes the variation starting with the node the main continuation of its parent
+func (tree *GameTree) PromoteToMainLine(node *GameTreeNode) {
+	if node.Parent == nil {
+		panic("The root can't be promoted")
+	}
+
+	siblings := node.Parent.Children
+	i := node.siblingIndex()
+	copy(siblings[1:i+1], siblings[:i])
+	siblings[0] = node
+}
+
+// DeleteVariation removes the node and all the moves after it, when the current node
+// is removed the parent of the node becomes the current node
+func (tree *GameTree) DeleteVariation(node *GameTreeNode) {
+	if node.Parent == nil {
+		panic("The root can't be deleted")
+	}
+
+	for n := tree.current; n != nil; n = n.Parent {
+		if n == node {
+			tree.GoTo(node.Parent)
+			break
+		}
+	}
+
+	i := node.siblingIndex()
+	node.Parent.Children = append(node.Parent.Children[:i], node.Parent.Children[i+1:]...)
+	node.Parent = nil
+}
+
+// MainLine returns the moves of the main line of the game
+func (tree *GameTree) MainLine() []*Move {
+	moves := []*Move{}
+	for node := tree.Root; len(node.Children) > 0; node = node.Children[0] {
+		moves = append(moves, node.Children[0].Move)
+	}
+
+	return moves
+}
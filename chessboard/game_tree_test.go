@@ -0,0 +1,76 @@
+package chessboard
+
+import "testing"
+
+// playSAN plays the moves in SAN notation from the current node of the tree
+func playSAN(t *testing.T, tree *GameTree, moves ...string) *GameTreeNode {
+	for _, san := range moves {
+		move, err := tree.Game().ParseSANMove(san)
+		if err != nil {
+			t.Fatalf("%s should be a legal move in %s", san, tree.Game().position.FEN())
+		}
+		tree.AddMove(move)
+	}
+
+	return tree.Current()
+}
+
+// checkTreeGame checks that the game of the tree is in the position of the current node
+func checkTreeGame(t *testing.T, tree *GameTree) {
+	game := tree.Game()
+	expected := tree.Current().Position()
+	if fen := game.position.FEN(); fen != expected.FEN() {
+		t.Errorf("The game of the tree should be in %s, %s was returned instead", expected.FEN(), fen)
+	}
+}
+
+func TestGameTreeNavigation(t *testing.T) {
+	game := NewGame()
+	tree := NewGameTree(&game)
+
+	nf3 := playSAN(t, tree, "e4", "e5", "Nf3")
+	tree.Back()
+	tree.Back()
+	c5 := playSAN(t, tree, "c5")
+	checkTreeGame(t, tree)
+
+	e4 := tree.Root.Children[0]
+	if len(e4.Children) != 2 || e4.Children[1] != c5 || c5.IsMainLine() || !nf3.IsMainLine() {
+		t.Errorf("c5 should be a variation after e4")
+	}
+
+	// Playing a move already in the tree follows it instead of adding a variation
+	tree.GoTo(e4)
+	if node := playSAN(t, tree, "e5"); node != e4.Children[0] || len(e4.Children) != 2 {
+		t.Errorf("Playing e5 again should follow the main line")
+	}
+
+	tree.GoTo(nf3)
+	checkTreeGame(t, tree)
+	tree.GoTo(c5)
+	checkTreeGame(t, tree)
+	if tree.Forward() {
+		t.Errorf("Forward should fail at the end of a variation")
+	}
+	tree.GoTo(tree.Root)
+	if tree.Back() || !tree.Forward() || tree.Current() != e4 {
+		t.Errorf("Back should fail at the root and Forward should follow the main line")
+	}
+	checkTreeGame(t, tree)
+
+	tree.PromoteToMainLine(c5)
+	if moves := tree.MainLine(); len(moves) != 2 || moves[1].UCI() != "c7c5" {
+		t.Errorf("The main line should be e4 c5 after the promotion")
+	}
+	tree.PromoteVariation(e4.Children[1])
+	if e4.Children[0] == c5 {
+		t.Errorf("e5 should be promoted back to the main line")
+	}
+
+	tree.GoTo(nf3)
+	tree.DeleteVariation(e4.Children[0])
+	if tree.Current() != e4 || len(e4.Children) != 1 || e4.Children[0] != c5 {
+		t.Errorf("Deleting the current variation should move to its parent")
+	}
+	checkTreeGame(t, tree)
+}
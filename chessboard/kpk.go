@@ -0,0 +1,47 @@
+package chessboard
+
+// kpkBitbaseSize is the number of words of the KPK bitbase: a bit for each position with the white
+// pawn on files A-D and ranks 2-7, the kings on any square and either side to move
+const kpkBitbaseSize = 2 * 24 * 64 * 64 / 64
+
+// kpkIndex returns the index in the bitbase of a position with white as the side with the pawn
+// on files A-D, turn is 0 when white is to move and 1 when black is to move
+func kpkIndex(turn int, blackKing Square, whiteKing Square, pawn Square) int {
+	return int(whiteKing) | int(blackKing)<<6 | turn<<12 | int(pawn%8)<<13 | (6-int(pawn/8))<<15
+}
+
+// ProbeKPK returns whether the side with the pawn wins the king and pawn against king position
+// with correct play. Panics if the position has other pieces
+func ProbeKPK(pos Position) (win bool) {
+	board := &pos.board
+	strong := WhiteColor
+	if board.bbBlackPawn != 0 {
+		strong = BlackColor
+	}
+
+	pieces := board.sidePieces(strong)
+	if pieces.pawns.PopCount() != 1 || pieces.all != pieces.king|pieces.pawns ||
+		board.sidePieces(strong.Other()).all != board.kingSquare(strong.Other()).Bitboard() {
+		panic("ProbeKPK called on a position which is not king and pawn against king")
+	}
+
+	strongKing, weakKing := board.kingSquare(strong), board.kingSquare(strong.Other())
+	pawn := Square(pieces.pawns.LeastSignificant1Bit())
+
+	// The bitbase contains the positions with the white pawn on the queen side,
+	// the other ones are flipped and mirrored
+	if strong == BlackColor {
+		strongKing, weakKing, pawn = strongKing^56, weakKing^56, pawn^56
+	}
+	if pawn%8 >= 4 {
+		strongKing, weakKing, pawn = strongKing^7, weakKing^7, pawn^7
+	}
+
+	turn := 0
+	if pos.turn != strong {
+		turn = 1
+	}
+
+	idx := kpkIndex(turn, weakKing, strongKing, pawn)
+	return sharedPrecomputedData().KPKBitbase[idx/64]&(1<<(idx%64)) != 0
+}
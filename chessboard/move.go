@@ -18,6 +18,10 @@ const (
 	IsCaptureFlag
 )
 
+// DropFlag marks the crazyhouse moves placing a piece from the pocket on the board,
+// it's stored in the last bit after the castle rook square
+const DropFlag MoveFlags = 1 << 31
+
 // CastlesFlag is a mask to check whether any of the castles flags is set
 const CastlesMask = WhiteKingCastleFlag | WhiteQueenCastleFlag | BlackKingCastleFlag | BlackQueenCastleFlag
 const EnPassantMask = WhiteEnPassantFlag | BlackEnPassantFlag
@@ -28,13 +32,18 @@ const EnPassantMask = WhiteEnPassantFlag | BlackEnPassantFlag
 // - 6 bit: to
 // - 4 bit: promotion
 // - 9 bit: flags
+// - 6 bit: starting square of the rook in castling moves
+// - 1 bit: drop flag
+// Drops have the same from and to square and store the dropped piece in the promotion bits
 type Move uint32
 
 const fromMask = 0b111111
 const toMask = 0b111111000000
 const promotionMask = 0b1111000000000000
+const castleRookShift = 25
+const castleRookMask = 0b111111 << castleRookShift
 
-func NewMove(from square, to square, promotion Piece, flags MoveFlags) *Move {
+func NewMove(from Square, to Square, promotion Piece, flags MoveFlags) *Move {
 	m := Move(from)
 	m |= Move(to) << 6
 	m |= Move(promotion) << 12
@@ -43,19 +52,54 @@ func NewMove(from square, to square, promotion Piece, flags MoveFlags) *Move {
 	return &m
 }
 
-func (m Move) From() square {
-	return square(m & fromMask)
+// newCastleMove returns a castling move of the king to the passed square with the rook starting in
+// rookFrom, the rook is stored in the move because in Chess960 it can start on any file
+func newCastleMove(from Square, to Square, rookFrom Square, flag MoveFlags) *Move {
+	m := NewMove(from, to, NoPiece, flag)
+	*m |= Move(rookFrom) << castleRookShift
+
+	return m
+}
+
+// newDropMove returns a crazyhouse move placing the piece from the pocket in the passed square
+func newDropMove(piece Piece, to Square) *Move {
+	return NewMove(to, to, piece, DropFlag)
 }
 
-func (m Move) To() square {
-	return square((m & toMask) >> 6)
+func (m Move) From() Square {
+	return Square(m & fromMask)
+}
+
+func (m Move) To() Square {
+	return Square((m & toMask) >> 6)
 }
 
 func (m Move) Promotion() Piece {
+	if m.IsDrop() {
+		return NoPiece
+	}
+
+	return Piece((m & promotionMask) >> 12)
+}
+
+// DropPiece returns the piece placed on the board by a drop, NoPiece for the other moves
+func (m Move) DropPiece() Piece {
+	if !m.IsDrop() {
+		return NoPiece
+	}
+
 	return Piece((m & promotionMask) >> 12)
 }
 
+// CastleRook returns the starting square of the rook in castling moves
+func (m Move) CastleRook() Square {
+	return Square((m & castleRookMask) >> castleRookShift)
+}
+
 func (m Move) String() string {
+	if m.IsDrop() {
+		return m.UCI()
+	}
 	if m.Promotion() != NoPiece {
 		return fmt.Sprintf("%s%s%s", m.From(), m.To(), m.Promotion())
 	}
@@ -63,6 +107,40 @@ func (m Move) String() string {
 	return fmt.Sprintf("%s%s", m.From(), m.To())
 }
 
+// UCI returns the move in the long algebraic notation used by the UCI protocol, e.g. e7e8q
+func (m Move) UCI() string {
+	if m.IsDrop() {
+		// Drops are written with the uppercase piece letter of both colors, e.g. P@e4
+		return fmt.Sprintf("%c@%s", "KQRBNP"[(m.DropPiece()-1)%6], m.To())
+	}
+
+	switch m.Promotion() {
+	case WhiteQueen, BlackQueen:
+		return fmt.Sprintf("%s%sq", m.From(), m.To())
+	case WhiteRook, BlackRook:
+		return fmt.Sprintf("%s%sr", m.From(), m.To())
+	case WhiteBishop, BlackBishop:
+		return fmt.Sprintf("%s%sb", m.From(), m.To())
+	case WhiteKnight, BlackKnight:
+		return fmt.Sprintf("%s%sn", m.From(), m.To())
+	case WhiteKing, BlackKing:
+		// Promotions to king are allowed in antichess
+		return fmt.Sprintf("%s%sk", m.From(), m.To())
+	default:
+		return fmt.Sprintf("%s%s", m.From(), m.To())
+	}
+}
+
+// UCIChess960 returns the move in the notation used by the UCI protocol for Chess960,
+// where castling moves are written as the king capturing its own rook, e.g. e1h1
+func (m Move) UCIChess960() string {
+	if m.IsCastle() {
+		return fmt.Sprintf("%s%s", m.From(), m.CastleRook())
+	}
+
+	return m.UCI()
+}
+
 func (m *Move) ShouldResetHalfMoveClock() bool {
 	return uint32(*m)&uint32(ResetHalfMoveClockFlag) != 0
 }
@@ -78,5 +156,8 @@ func (m *Move) IsDoublePawnPush() bool {
 func (m *Move) IsCapture() bool {
 	return uint32(*m)&uint32(IsCaptureFlag) != 0
 }
+func (m Move) IsDrop() bool {
+	return uint32(m)&uint32(DropFlag) != 0
+}
 
 var NullMove = 0
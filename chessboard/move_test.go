@@ -25,3 +25,15 @@ func TestNewMove(t *testing.T) {
 		t.Errorf("Move promotion should be -, %s was returned instead", m.Promotion())
 	}
 }
+
+func TestMoveUCI(t *testing.T) {
+	m := NewMove(E2, E4, NoPiece, DoublePawnPushFlag)
+	if m.UCI() != "e2e4" {
+		t.Errorf("UCI notation should be e2e4, %s was returned instead", m.UCI())
+	}
+
+	m = NewMove(B2, A1, BlackKnight, IsCaptureFlag)
+	if m.UCI() != "b2a1n" {
+		t.Errorf("UCI notation should be b2a1n, %s was returned instead", m.UCI())
+	}
+}
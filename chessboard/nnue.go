@@ -0,0 +1,475 @@
+package chessboard
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// FeatureSet identifies the input features of a network. The features are the pieces on
+// the board relative to the king of the side whose point of view is taken (perspective),
+// so each position has two sets of active features, one for each side
+type FeatureSet uint32
+
+const (
+	// HalfKP features are all the pieces except the kings, indexed by the king square of the perspective
+	HalfKP FeatureSet = iota
+	// HalfKA features are all the pieces including the kings, indexed by the king square of the perspective
+	HalfKA
+)
+
+func (features FeatureSet) String() string {
+	switch features {
+	case HalfKP:
+		return "HalfKP"
+	case HalfKA:
+		return "HalfKA"
+	default:
+		return fmt.Sprintf("FeatureSet(%d)", uint32(features))
+	}
+}
+
+// pieceTypes returns the number of piece types used as features, the king is the last one
+func (features FeatureSet) pieceTypes() int {
+	if features == HalfKP {
+		return 5
+	}
+
+	return 6
+}
+
+// Inputs returns the number of input features: king square, piece color, piece type and piece square
+func (features FeatureSet) Inputs() int {
+	return 64 * 2 * features.pieceTypes() * 64
+}
+
+// Quantisation scales of the network: the feature transformer weights are multiplied by
+// networkFeatureScale and the clipped ReLU activations are in [0, networkFeatureScale],
+// the output weights are multiplied by networkOutputScale
+const (
+	networkFeatureScale = 255
+	networkOutputScale  = 64
+)
+
+// networkMagic starts every network file, followed by the format version
+const networkMagic = "GCNN"
+const networkVersion = 1
+
+// maxNetworkHiddenSize bounds the hidden size read from the network files, so that a corrupted
+// header can't allocate more than about 100MB of weights
+const maxNetworkHiddenSize = 1024
+
+// Network is an efficiently updatable neural network (NNUE) evaluating positions. The feature
+// transformer maps the active features of each perspective to an accumulator of HiddenSize
+// values, the accumulators of the side to move and of the other side pass through a clipped
+// ReLU and are combined by the output layer into the evaluation.
+//
+// Adding or removing a piece changes the accumulators by a single row of weights, so they are
+// updated incrementally while moves are played instead of being computed from scratch
+type Network struct {
+	Features   FeatureSet
+	HiddenSize int
+	// Scale converts the dequantised output of the network to centipawns
+	Scale int
+
+	// featureWeights contains a row of HiddenSize weights for each input feature
+	featureWeights []int16
+	featureBiases  []int16
+	// outputWeights contains the weights of the side to move accumulator followed by the other one
+	outputWeights []int16
+	outputBias    int32
+}
+
+// NewNetwork returns a network with all the weights set to zero
+func NewNetwork(features FeatureSet, hiddenSize int) *Network {
+	if features != HalfKP && features != HalfKA {
+		panic("Unknown network feature set")
+	}
+	if hiddenSize <= 0 {
+		panic("The hidden size of the network should be positive")
+	}
+
+	return &Network{
+		Features:       features,
+		HiddenSize:     hiddenSize,
+		Scale:          400,
+		featureWeights: make([]int16, features.Inputs()*hiddenSize),
+		featureBiases:  make([]int16, hiddenSize),
+		outputWeights:  make([]int16, 2*hiddenSize),
+	}
+}
+
+// networkHeader is stored at the beginning of the network files, all the values are little endian
+type networkHeader struct {
+	Magic      [4]byte
+	Version    uint32
+	Features   FeatureSet
+	HiddenSize uint32
+	Scale      uint32
+}
+
+// weightsSize returns the size in bytes of the weights following the header
+func (header *networkHeader) weightsSize() int64 {
+	hiddenSize := int64(header.HiddenSize)
+	return 2*(int64(header.Features.Inputs())*hiddenSize+hiddenSize+2*hiddenSize) + 4
+}
+
+// LoadNetwork reads a network from a file. The file contains the header followed by the
+// feature weights, the feature biases and the output weights as int16 and the output bias as int32
+func LoadNetwork(path string) (*Network, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	net, err := readNetwork(bufio.NewReader(file), info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return net, nil
+}
+
+// ReadNetwork reads a network in the format described in LoadNetwork
+func ReadNetwork(r io.Reader) (*Network, error) {
+	size := int64(-1)
+	if sized, ok := r.(interface{ Len() int }); ok {
+		size = int64(sized.Len())
+	}
+
+	return readNetwork(r, size)
+}
+
+// readNetwork reads a network from a stream of the passed size, -1 if it is unknown.
+// The header is validated before allocating the weights it describes
+func readNetwork(r io.Reader, size int64) (*Network, error) {
+	var header networkHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("invalid network header: %w", err)
+	}
+
+	if string(header.Magic[:]) != networkMagic {
+		return nil, errors.New("not a network file")
+	}
+	if header.Version != networkVersion {
+		return nil, fmt.Errorf("unsupported network version %d", header.Version)
+	}
+	if header.Features != HalfKP && header.Features != HalfKA {
+		return nil, fmt.Errorf("unknown feature set %d", uint32(header.Features))
+	}
+	if header.HiddenSize == 0 || header.HiddenSize > maxNetworkHiddenSize {
+		return nil, fmt.Errorf("invalid hidden size %d", header.HiddenSize)
+	}
+	if remaining := size - int64(binary.Size(header)); size >= 0 && remaining != header.weightsSize() {
+		return nil, fmt.Errorf("the header describes %d bytes of weights, %d were found", header.weightsSize(), remaining)
+	}
+
+	net := NewNetwork(header.Features, int(header.HiddenSize))
+	net.Scale = int(header.Scale)
+
+	for _, data := range []interface{}{net.featureWeights, net.featureBiases, net.outputWeights, &net.outputBias} {
+		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
+			return nil, fmt.Errorf("truncated network weights: %w", err)
+		}
+	}
+
+	// Trailing data means that the header doesn't describe the weights
+	if n, _ := r.Read(make([]byte, 1)); n != 0 {
+		return nil, errors.New("unexpected data after the network weights")
+	}
+
+	return net, nil
+}
+
+// Save writes the network to a file in the format described in LoadNetwork
+func (net *Network) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	if err := net.Write(writer); err != nil {
+		file.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Write writes the network in the format described in LoadNetwork
+func (net *Network) Write(w io.Writer) error {
+	header := networkHeader{
+		Version:    networkVersion,
+		Features:   net.Features,
+		HiddenSize: uint32(net.HiddenSize),
+		Scale:      uint32(net.Scale),
+	}
+	copy(header.Magic[:], networkMagic)
+
+	for _, data := range []interface{}{&header, net.featureWeights, net.featureBiases, net.outputWeights, net.outputBias} {
+		if err := binary.Write(w, binary.LittleEndian, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Evaluate returns the evaluation of the position from the point of view of the side to move,
+// in 256th of a pawn, computing the accumulators from scratch
+func (net *Network) Evaluate(pos Position) int {
+	acc := net.newAccumulator()
+	net.refresh(&acc, &pos.board, WhiteColor)
+	net.refresh(&acc, &pos.board, BlackColor)
+
+	return net.output(&acc, pos.turn)
+}
+
+// accumulator contains the output of the feature transformer for the white (0)
+// and black (1) perspectives
+type accumulator [2][]int16
+
+func (net *Network) newAccumulator() accumulator {
+	return accumulator{make([]int16, net.HiddenSize), make([]int16, net.HiddenSize)}
+}
+
+func perspectiveIndex(perspective Color) int {
+	if perspective == WhiteColor {
+		return 0
+	}
+
+	return 1
+}
+
+// pieceBitboards returns the bitboards of the pieces ordered as pawn, knight, bishop, rook,
+// queen and king, first the white ones and then the black ones
+func (b *Board) pieceBitboards() [12]Bitboard {
+	return [12]Bitboard{
+		b.bbWhitePawn, b.bbWhiteKnight, b.bbWhiteBishop, b.bbWhiteRook, b.bbWhiteQueen, b.bbWhiteKing,
+		b.bbBlackPawn, b.bbBlackKnight, b.bbBlackBishop, b.bbBlackRook, b.bbBlackQueen, b.bbBlackKing,
+	}
+}
+
+// featureIndex returns the input feature of a piece (index in pieceBitboards) on a square.
+// The black perspective sees the board flipped, so both sides share the same weights
+func (net *Network) featureIndex(perspective Color, king Square, piece int, sq Square) int {
+	color := piece / 6
+	pieceType := piece % 6
+	if perspective == BlackColor {
+		king ^= 56
+		sq ^= 56
+		color ^= 1
+	}
+
+	return ((int(king)*2+color)*net.Features.pieceTypes()+pieceType)*64 + int(sq)
+}
+
+// featureRow returns the weights of an input feature
+func (net *Network) featureRow(feature int) []int16 {
+	return net.featureWeights[feature*net.HiddenSize : (feature+1)*net.HiddenSize]
+}
+
+// refresh computes the accumulator of a perspective from scratch
+func (net *Network) refresh(acc *accumulator, board *Board, perspective Color) {
+	values := acc[perspectiveIndex(perspective)]
+	copy(values, net.featureBiases)
+
+	king := board.kingSquare(perspective)
+	pieces := board.pieceBitboards()
+	for piece, bb := range pieces {
+		if piece%6 >= net.Features.pieceTypes() {
+			continue
+		}
+
+		for bb != 0 {
+			sq := Square(bb.LeastSignificant1Bit())
+			bb.ClearLeastSignificant1Bit()
+
+			addWeights(values, net.featureRow(net.featureIndex(perspective, king, piece, sq)))
+		}
+	}
+}
+
+// update computes the accumulator of the next board from the one of the previous board, only
+// the pieces which changed are added or removed. When the king of the perspective moves all
+// the features change, so the accumulator is computed from scratch
+func (net *Network) update(acc *accumulator, previousAcc *accumulator, previous *Board, next *Board, perspective Color) {
+	king := next.kingSquare(perspective)
+	if king != previous.kingSquare(perspective) {
+		net.refresh(acc, next, perspective)
+		return
+	}
+
+	index := perspectiveIndex(perspective)
+	values := acc[index]
+	copy(values, previousAcc[index])
+
+	previousPieces := previous.pieceBitboards()
+	nextPieces := next.pieceBitboards()
+	for piece := range nextPieces {
+		if piece%6 >= net.Features.pieceTypes() {
+			continue
+		}
+
+		removed := previousPieces[piece] &^ nextPieces[piece]
+		for removed != 0 {
+			sq := Square(removed.LeastSignificant1Bit())
+			removed.ClearLeastSignificant1Bit()
+
+			subWeights(values, net.featureRow(net.featureIndex(perspective, king, piece, sq)))
+		}
+
+		added := nextPieces[piece] &^ previousPieces[piece]
+		for added != 0 {
+			sq := Square(added.LeastSignificant1Bit())
+			added.ClearLeastSignificant1Bit()
+
+			addWeights(values, net.featureRow(net.featureIndex(perspective, king, piece, sq)))
+		}
+	}
+}
+
+// output runs the output layer on the accumulators, the result is from the point of view
+// of the side to move in 256th of a pawn. It is clamped below the mate scores, so that large
+// weights can't be mistaken for a mate and the score fits the transposition table entries
+func (net *Network) output(acc *accumulator, turn Color) int {
+	us := acc[perspectiveIndex(turn)]
+	them := acc[perspectiveIndex(turn.Other())]
+
+	sum := int(net.outputBias) +
+		clippedReLUDot(us, net.outputWeights[:net.HiddenSize]) +
+		clippedReLUDot(them, net.outputWeights[net.HiddenSize:])
+
+	// Larger sums are clamped anyway, bounding them first keeps the product within an int
+	// with any scale
+	sumLimit := MateScore * networkFeatureScale * networkOutputScale
+	centipawns := clamp(sum, -sumLimit, sumLimit) * net.Scale / (networkFeatureScale * networkOutputScale)
+
+	maxScore := MateScore - MaxPly - 1
+	return clamp(centipawns*256/100, -maxScore, maxScore)
+}
+
+// The vector loops below work on slices resliced to the same length, which lets the compiler
+// drop the bounds checks, and handle 8 values per iteration to keep the pipeline busy
+
+func addWeights(values []int16, weights []int16) {
+	weights = weights[:len(values)]
+
+	i := 0
+	for ; i+8 <= len(values); i += 8 {
+		v := values[i : i+8 : i+8]
+		w := weights[i : i+8 : i+8]
+		v[0] += w[0]
+		v[1] += w[1]
+		v[2] += w[2]
+		v[3] += w[3]
+		v[4] += w[4]
+		v[5] += w[5]
+		v[6] += w[6]
+		v[7] += w[7]
+	}
+	for ; i < len(values); i++ {
+		values[i] += weights[i]
+	}
+}
+
+func subWeights(values []int16, weights []int16) {
+	weights = weights[:len(values)]
+
+	i := 0
+	for ; i+8 <= len(values); i += 8 {
+		v := values[i : i+8 : i+8]
+		w := weights[i : i+8 : i+8]
+		v[0] -= w[0]
+		v[1] -= w[1]
+		v[2] -= w[2]
+		v[3] -= w[3]
+		v[4] -= w[4]
+		v[5] -= w[5]
+		v[6] -= w[6]
+		v[7] -= w[7]
+	}
+	for ; i < len(values); i++ {
+		values[i] -= weights[i]
+	}
+}
+
+// clippedReLUDot returns the dot product between the values clipped to [0, networkFeatureScale] and the weights
+func clippedReLUDot(values []int16, weights []int16) int {
+	weights = weights[:len(values)]
+
+	sum := 0
+	for i, value := range values {
+		if value <= 0 {
+			continue
+		}
+		if value > networkFeatureScale {
+			value = networkFeatureScale
+		}
+
+		sum += int(value) * int(weights[i])
+	}
+
+	return sum
+}
+
+// accumulatorStack contains the accumulators of the positions played in a game,
+// the accumulator of each position is computed from the one of the previous position
+type accumulatorStack struct {
+	network      *Network
+	accumulators []accumulator
+	// current is the index of the accumulator of the current position, the following
+	// ones are kept allocated and reused by the next moves
+	current int
+}
+
+func newAccumulatorStack(net *Network, board *Board) *accumulatorStack {
+	stack := accumulatorStack{network: net, accumulators: []accumulator{net.newAccumulator()}}
+	stack.reset(board)
+
+	return &stack
+}
+
+// reset computes the accumulator of the board from scratch, dropping the previous ones
+func (stack *accumulatorStack) reset(board *Board) {
+	stack.current = 0
+	stack.network.refresh(&stack.accumulators[0], board, WhiteColor)
+	stack.network.refresh(&stack.accumulators[0], board, BlackColor)
+}
+
+// push adds the accumulator of the next board, reached with a move from the previous board
+func (stack *accumulatorStack) push(previous *Board, next *Board) {
+	stack.current++
+	if stack.current == len(stack.accumulators) {
+		stack.accumulators = append(stack.accumulators, stack.network.newAccumulator())
+	}
+
+	acc := &stack.accumulators[stack.current]
+	previousAcc := &stack.accumulators[stack.current-1]
+	stack.network.update(acc, previousAcc, previous, next, WhiteColor)
+	stack.network.update(acc, previousAcc, previous, next, BlackColor)
+}
+
+// pop goes back to the accumulator of the previous position
+func (stack *accumulatorStack) pop() {
+	stack.current--
+}
+
+// evaluate returns the network evaluation of the current position from the point of view of the side to move
+func (stack *accumulatorStack) evaluate(turn Color) int {
+	return stack.network.output(&stack.accumulators[stack.current], turn)
+}
@@ -0,0 +1,198 @@
+package chessboard
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// randomNetwork returns a network with small random weights
+func randomNetwork(features FeatureSet, hiddenSize int, seed int64) *Network {
+	random := rand.New(rand.NewSource(seed))
+	net := NewNetwork(features, hiddenSize)
+
+	for _, weights := range [][]int16{net.featureWeights, net.featureBiases, net.outputWeights} {
+		for i := range weights {
+			weights[i] = int16(random.Intn(129) - 64)
+		}
+	}
+	net.outputBias = int32(random.Intn(2001) - 1000)
+
+	return net
+}
+
+func TestNetworkRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "network.nnue")
+
+	for _, features := range []FeatureSet{HalfKP, HalfKA} {
+		net := randomNetwork(features, 12, 1)
+		net.Scale = 350
+		if err := net.Save(path); err != nil {
+			t.Fatalf("Saving the %v network should succeed, %v was returned instead", features, err)
+		}
+
+		loaded, err := LoadNetwork(path)
+		if err != nil {
+			t.Fatalf("Loading the %v network should succeed, %v was returned instead", features, err)
+		}
+		if !reflect.DeepEqual(loaded, net) {
+			t.Errorf("The loaded %v network should be equal to the saved one", features)
+		}
+	}
+}
+
+func TestLoadInvalidNetwork(t *testing.T) {
+	var valid bytes.Buffer
+	if err := randomNetwork(HalfKP, 8, 1).Write(&valid); err != nil {
+		t.Fatal(err)
+	}
+	data := valid.Bytes()
+
+	wrongMagic := append([]byte("NNUE"), data[4:]...)
+	wrongVersion := append(append([]byte{}, data[:4]...), append([]byte{2, 0, 0, 0}, data[8:]...)...)
+	wrongHeader := func(offset int, value uint32) []byte {
+		content := append([]byte{}, data...)
+		binary.LittleEndian.PutUint32(content[offset:], value)
+		return content
+	}
+	tests := map[string][]byte{
+		"empty":         {},
+		"wrong magic":   wrongMagic,
+		"wrong version": wrongVersion,
+		"truncated":     data[:len(data)-1],
+		"trailing data": append(append([]byte{}, data...), 0),
+		// The header is checked before allocating the weights
+		"unknown features":        wrongHeader(8, 7),
+		"huge hidden size":        wrongHeader(12, 1<<30),
+		"hidden size beyond file": wrongHeader(12, 1024),
+	}
+
+	dir := t.TempDir()
+	for name, content := range tests {
+		path := filepath.Join(dir, "network.nnue")
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadNetwork(path); err == nil {
+			t.Errorf("Loading a network file with %s should fail, no error was returned instead", name)
+		}
+		if _, err := ReadNetwork(bytes.NewReader(content)); err == nil {
+			t.Errorf("Reading a network with %s should fail, no error was returned instead", name)
+		}
+	}
+}
+
+func TestAccumulatorUpdates(t *testing.T) {
+	// The moves include castling, en passant, captures, promotions and king moves
+	games := []struct {
+		fen   string
+		moves []string
+	}{
+		{"r3k2r/pppq1ppp/2npbn2/2b1p3/2B1P3/2NPBN2/PPPQ1PPP/R3K2R w KQkq - 0 1",
+			[]string{"e1g1", "e8c8", "c3d5", "f6d5", "e4d5", "e6d5", "c4d5", "c6d4"}},
+		{"4k3/1P6/8/3pP3/8/8/6p1/4K2R w K d6 0 1",
+			[]string{"e5d6", "g2h1q", "e1d2", "e8d7", "b7b8n", "d7d6"}},
+	}
+
+	for _, features := range []FeatureSet{HalfKP, HalfKA} {
+		net := randomNetwork(features, 16, 2)
+
+		for _, test := range games {
+			game := NewGameFromFEN(test.fen)
+			game.setNetwork(net)
+
+			check := func(description string) {
+				expected := net.Evaluate(*game.position)
+				if score := game.accumulators.evaluate(game.position.turn); score != expected {
+					t.Errorf("%v evaluation %s should be %d, %d was returned instead", features, description, expected, score)
+				}
+			}
+
+			for _, uciMove := range test.moves {
+				move, err := game.ParseUCIMove(uciMove)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				game.Move(move)
+				check("after " + uciMove)
+			}
+
+			for range test.moves {
+				game.UndoMove()
+				check("after undoing a move")
+			}
+		}
+	}
+}
+
+func TestNetworkSymmetry(t *testing.T) {
+	// The two perspectives share the weights, so mirroring the position doesn't change the evaluation
+	net := randomNetwork(HalfKA, 16, 3)
+	game := NewGameFromFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3")
+	mirroredGame := NewGameFromFEN("rnbqkb1r/pppp1ppp/5n2/4p3/4P3/2N5/PPPP1PPP/R1BQKBNR b KQkq - 2 3")
+
+	score := net.Evaluate(game.Position())
+	if mirroredScore := net.Evaluate(mirroredGame.Position()); score != mirroredScore {
+		t.Errorf("The network evaluation should be equal to the mirrored one %d, %d was returned instead", mirroredScore, score)
+	}
+}
+
+func TestNetworkOutputBounds(t *testing.T) {
+	// The largest weights and scale saturate the output, which must stay below the mate scores
+	for _, sign := range []int{1, -1} {
+		net := NewNetwork(HalfKP, 8)
+		for i := range net.featureBiases {
+			net.featureBiases[i] = math.MaxInt16
+		}
+		for i := range net.outputWeights {
+			net.outputWeights[i] = int16(sign * math.MaxInt16)
+		}
+		net.outputBias = int32(sign * math.MaxInt32)
+		net.Scale = math.MaxUint32
+
+		game := NewGame()
+		score := net.Evaluate(game.Position())
+		if expected := sign * (MateScore - MaxPly - 1); score != expected {
+			t.Errorf("The saturated network evaluation should be %d, %d was returned instead", expected, score)
+		}
+		if _, mate := MateIn(score); mate {
+			t.Errorf("The network evaluation %d should not be a mate score", score)
+		}
+	}
+}
+
+func TestNetworkEvaluationMode(t *testing.T) {
+	game := NewGame()
+	eng := NewBruteForceEngine(&game)
+
+	// With zero weights the network evaluation is the output bias
+	net := NewNetwork(HalfKP, 8)
+	net.outputBias = networkFeatureScale * networkOutputScale
+	eng.EvalMode = NetworkEvaluation
+	eng.Network = net
+
+	if score := eng.StaticEvaluation(); score != net.Scale*256/100 {
+		t.Errorf("Network static evaluation should be %d, %d was returned instead", net.Scale*256/100, score)
+	}
+
+	// The search keeps the accumulators updated on its own game
+	eng.Network = randomNetwork(HalfKP, 8, 4)
+	eng.MaxDepth = 3
+	eng.LogOutput = &bytes.Buffer{}
+	if move := eng.BestMove(60); move == nil {
+		t.Errorf("The search with the network evaluation should return a move")
+	}
+	if eng.game.accumulators == nil || eng.game.accumulators.current != 0 {
+		t.Errorf("The search should use the accumulators and return to the root position")
+	}
+	if game.accumulators != nil {
+		t.Errorf("The tracked game should not be modified by the search")
+	}
+}
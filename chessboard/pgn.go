@@ -0,0 +1,438 @@
+package chessboard
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// pgnTagRoster are the tags which are written first, in this order, by the PGN export format
+var pgnTagRoster = []string{"Event", "Site", "Date", "Round", "White", "Black", "Result"}
+
+// pgnSuffixNAGs are the NAGs of the move suffix annotations
+var pgnSuffixNAGs = map[string]int{"!": 1, "?": 2, "!!": 3, "??": 4, "!?": 5, "?!": 6}
+
+// pgnResults are the tokens terminating the moves of a game
+var pgnResults = map[string]bool{"1-0": true, "0-1": true, "1/2-1/2": true, "*": true}
+
+// pgnLineLength is the maximum length of the lines of the moves
+const pgnLineLength = 80
+
+// PGN returns the game tree in the PGN format with its variations, comments and NAGs.
+// The FEN and Variant tags are added when the game doesn't start from the standard position
+func (tree *GameTree) PGN() string {
+	tags := map[string]string{}
+	for name, value := range tree.Tags {
+		tags[name] = value
+	}
+	for _, name := range pgnTagRoster {
+		if _, ok := tags[name]; !ok {
+			tags[name] = "?"
+		}
+	}
+	if tree.Tags["Result"] == "" {
+		tags["Result"] = "*"
+	}
+
+	variant := tree.game.variant
+	if variant != Standard {
+		tags["Variant"] = variant.Name()
+	}
+	if fen := tree.Root.position.FEN(); fen != variant.StartFEN() {
+		tags["SetUp"] = "1"
+		tags["FEN"] = fen
+	}
+
+	var sb strings.Builder
+	for _, name := range sortedPGNTags(tags) {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(tags[name])
+		fmt.Fprintf(&sb, "[%s \"%s\"]\n", name, value)
+	}
+	sb.WriteString("\n")
+
+	// The moves are written walking the tree from the root, then the current node is restored
+	current := tree.current
+	tree.GoTo(tree.Root)
+
+	tokens := []string{}
+	if tree.Root.Comment != "" {
+		tokens = append(tokens, "{"+tree.Root.Comment+"}")
+	}
+	tree.writeLine(tree.Root, &tokens, true)
+	tokens = append(tokens, tags["Result"])
+
+	tree.GoTo(current)
+
+	sb.WriteString(wrapPGNTokens(tokens))
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// sortedPGNTags returns the names of the tags in the order of the export format:
+// the seven tag roster first and then the others alphabetically
+func sortedPGNTags(tags map[string]string) []string {
+	others := []string{}
+	for name := range tags {
+		isRoster := false
+		for _, rosterName := range pgnTagRoster {
+			isRoster = isRoster || name == rosterName
+		}
+		if !isRoster {
+			others = append(others, name)
+		}
+	}
+	sort.Strings(others)
+
+	return append(append([]string{}, pgnTagRoster...), others...)
+}
+
+// writeLine appends the tokens of the line continuing from the node, with the variations
+// of each move in brackets after it. The tree must be at the node
+func (tree *GameTree) writeLine(node *GameTreeNode, tokens *[]string, forceNumber bool) {
+	for len(node.Children) > 0 {
+		main := node.Children[0]
+		tree.writeMove(main, tokens, forceNumber)
+		forceNumber = main.Comment != ""
+
+		for _, variation := range node.Children[1:] {
+			*tokens = append(*tokens, "(")
+			tree.writeMove(variation, tokens, true)
+			tree.GoTo(variation)
+			tree.writeLine(variation, tokens, variation.Comment != "")
+			tree.GoTo(node)
+			*tokens = append(*tokens, ")")
+
+			// The moves after a variation need the move number again
+			forceNumber = true
+		}
+
+		tree.GoTo(main)
+		node = main
+	}
+}
+
+// writeMove appends the tokens of a child of the current node: the move number, the move
+// in SAN notation, its NAGs and comments
+func (tree *GameTree) writeMove(node *GameTreeNode, tokens *[]string, forceNumber bool) {
+	if node.StartingComment != "" {
+		*tokens = append(*tokens, "{"+node.StartingComment+"}")
+		forceNumber = true
+	}
+
+	number := tree.moveNumber()
+	if tree.game.position.turn == WhiteColor {
+		*tokens = append(*tokens, fmt.Sprintf("%d.", number))
+	} else if forceNumber {
+		*tokens = append(*tokens, fmt.Sprintf("%d...", number))
+	}
+
+	*tokens = append(*tokens, tree.game.SAN(node.Move))
+	for _, nag := range node.NAGs {
+		*tokens = append(*tokens, fmt.Sprintf("$%d", nag))
+	}
+	if node.Comment != "" {
+		*tokens = append(*tokens, "{"+node.Comment+"}")
+	}
+}
+
+// moveNumber returns the full move number of the current node, counting the moves from the
+// number in the position of the root
+func (tree *GameTree) moveNumber() int {
+	plies := 0
+	for node := tree.current; node.Parent != nil; node = node.Parent {
+		plies++
+	}
+	if tree.Root.position.turn == BlackColor {
+		plies++
+	}
+
+	return tree.Root.position.moveCount + plies/2
+}
+
+// wrapPGNTokens joins the tokens of the moves in lines no longer than pgnLineLength,
+// the brackets of the variations are attached to the nearby tokens
+func wrapPGNTokens(tokens []string) string {
+	words := []string{}
+	attach := false
+	for _, token := range tokens {
+		switch {
+		case token == ")" && len(words) > 0:
+			words[len(words)-1] += token
+		case attach:
+			words[len(words)-1] += token
+		default:
+			words = append(words, token)
+		}
+		attach = token == "("
+	}
+
+	lines := []string{}
+	line := ""
+	for _, word := range strings.Fields(strings.Join(words, " ")) {
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) > pgnLineLength:
+			lines = append(lines, line)
+			line = word
+		default:
+			line += " " + word
+		}
+	}
+	lines = append(lines, line)
+
+	return strings.Join(lines, "\n")
+}
+
+// pgnToken is a token of the PGN format, its kind is one of: tag, comment, nag, symbol, ( and )
+type pgnToken struct {
+	kind  string
+	value string
+	// name is the name of the tags
+	name string
+}
+
+// tokenizePGN splits a PGN game in its tokens, the move numbers are skipped
+func tokenizePGN(pgn string) ([]pgnToken, error) {
+	tokens := []pgnToken{}
+	runes := []rune(pgn)
+
+	for i := 0; i < len(runes); i++ {
+		char := runes[i]
+		switch {
+		case unicode.IsSpace(char):
+		case char == '%' && (i == 0 || runes[i-1] == '\n'):
+			// Escaped lines are ignored
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case char == ';':
+			end := i + 1
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+			tokens = append(tokens, pgnToken{kind: "comment", value: string(runes[i+1 : end])})
+			i = end
+		case char == '{':
+			end := i + 1
+			for end < len(runes) && runes[end] != '}' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			tokens = append(tokens, pgnToken{kind: "comment", value: string(runes[i+1 : end])})
+			i = end
+		case char == '[':
+			end := i + 1
+			inString, escaped := false, false
+			for ; end < len(runes) && (inString || runes[end] != ']'); end++ {
+				switch {
+				case escaped:
+					escaped = false
+				case runes[end] == '\\':
+					escaped = true
+				case runes[end] == '"':
+					inString = !inString
+				}
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("unterminated tag")
+			}
+
+			name, value, err := parsePGNTag(string(runes[i+1 : end]))
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, pgnToken{kind: "tag", name: name, value: value})
+			i = end
+		case char == '(' || char == ')':
+			tokens = append(tokens, pgnToken{kind: string(char)})
+		default:
+			end := i + 1
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune("(){};[$", runes[end]) {
+				end++
+			}
+			symbol := string(runes[i:end])
+			i = end - 1
+
+			if char == '$' {
+				tokens = append(tokens, pgnToken{kind: "nag", value: symbol[1:]})
+				continue
+			}
+
+			// Move numbers are skipped, they can be attached to the move (e.g. 1.e4 or 12...Nf6)
+			if !pgnResults[symbol] {
+				digits := strings.TrimLeft(symbol, "0123456789")
+				if len(digits) < len(symbol) && strings.HasPrefix(digits, ".") {
+					symbol = strings.TrimLeft(digits, ".")
+				}
+			}
+			if symbol != "" {
+				tokens = append(tokens, pgnToken{kind: "symbol", value: symbol})
+			}
+		}
+	}
+
+	return tokens, nil
+}
+
+// firstPGNGame returns the tokens of the first game, which ends with the result outside of the variations
+func firstPGNGame(tokens []pgnToken) []pgnToken {
+	depth := 0
+	for i, token := range tokens {
+		switch {
+		case token.kind == "(":
+			depth++
+		case token.kind == ")":
+			depth--
+		case token.kind == "symbol" && depth == 0 && pgnResults[token.value]:
+			return tokens[:i+1]
+		}
+	}
+
+	return tokens
+}
+
+// parsePGNTag parses the content of a tag pair, e.g. Event "Casual game"
+func parsePGNTag(raw string) (string, string, error) {
+	raw = strings.TrimSpace(raw)
+	space := strings.IndexFunc(raw, unicode.IsSpace)
+	if space == -1 {
+		return "", "", fmt.Errorf("invalid tag [%s]", raw)
+	}
+
+	name, value := raw[:space], strings.TrimSpace(raw[space:])
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return "", "", fmt.Errorf("invalid value of the tag %s", name)
+	}
+	value = strings.NewReplacer(`\\`, `\`, `\"`, `"`).Replace(value[1 : len(value)-1])
+
+	return name, value, nil
+}
+
+// ParsePGN parses the first game of a PGN text with its variations, comments and NAGs.
+// The Variant and FEN tags choose the variant and the starting position of the game.
+// The current node of the returned tree is the root
+func ParsePGN(pgn string) (tree *GameTree, err error) {
+	tokens, err := tokenizePGN(pgn)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens = firstPGNGame(tokens)
+
+	tags := map[string]string{}
+	for _, token := range tokens {
+		if token.kind == "tag" {
+			tags[token.name] = token.value
+		}
+	}
+
+	variant := Standard
+	if name, ok := tags["Variant"]; ok {
+		name = strings.NewReplacer(" ", "", "-", "").Replace(strings.ToLower(name))
+		if variant, err = VariantByName(name); err != nil {
+			return nil, err
+		}
+	}
+
+	// The fen parser panics on invalid input
+	defer func() {
+		if r := recover(); r != nil {
+			tree, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+	var game Game
+	if fen, ok := tags["FEN"]; ok {
+		game = NewVariantGameFromFEN(variant, fen)
+	} else {
+		game = NewVariantGame(variant)
+	}
+
+	tree = NewGameTree(&game)
+	tree.Tags = tags
+
+	// The variations start from the parent of the last move, which is saved in a stack to return to it
+	stack := []*GameTreeNode{}
+	startingComment := ""
+	lineStart := true
+	for _, token := range tokens {
+		switch token.kind {
+		case "comment":
+			comment := strings.Join(strings.Fields(token.value), " ")
+			switch {
+			case comment == "":
+			case !lineStart:
+				tree.current.Comment = joinPGNComments(tree.current.Comment, comment)
+			case len(stack) == 0:
+				tree.Root.Comment = joinPGNComments(tree.Root.Comment, comment)
+			default:
+				startingComment = joinPGNComments(startingComment, comment)
+			}
+		case "(":
+			if lineStart {
+				return nil, fmt.Errorf("variation without a previous move")
+			}
+			stack = append(stack, tree.current)
+			tree.Back()
+			lineStart = true
+		case ")":
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("unmatched closing bracket")
+			}
+			tree.GoTo(stack[len(stack)-1])
+			stack = stack[:len(stack)-1]
+			lineStart = false
+		case "nag":
+			nag, err := strconv.Atoi(token.value)
+			if err != nil || lineStart {
+				return nil, fmt.Errorf("invalid NAG $%s", token.value)
+			}
+			tree.current.NAGs = append(tree.current.NAGs, nag)
+		case "symbol":
+			if pgnResults[token.value] {
+				if _, ok := tree.Tags["Result"]; !ok {
+					tree.Tags["Result"] = token.value
+				}
+				break
+			}
+			if nag, ok := pgnSuffixNAGs[token.value]; ok && !lineStart {
+				tree.current.NAGs = append(tree.current.NAGs, nag)
+				break
+			}
+
+			san := strings.TrimRight(token.value, "!?")
+			move, err := tree.game.ParseSANMove(san)
+			if err != nil {
+				return nil, fmt.Errorf("%s in %s", err, tree.game.position.FEN())
+			}
+
+			node := tree.AddMove(move)
+			node.StartingComment = joinPGNComments(node.StartingComment, startingComment)
+			startingComment = ""
+			if nag, ok := pgnSuffixNAGs[token.value[len(san):]]; ok {
+				node.NAGs = append(node.NAGs, nag)
+			}
+			lineStart = false
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("unterminated variation")
+	}
+
+	tree.GoTo(tree.Root)
+	return tree, nil
+}
+
+// joinPGNComments joins two comments of the same move
+func joinPGNComments(first string, second string) string {
+	if first == "" || second == "" {
+		return first + second
+	}
+
+	return first + " " + second
+}
@@ -0,0 +1,178 @@
+package chessboard
+
+import "testing"
+
+func TestSAN(t *testing.T) {
+	var tests = []struct {
+		variant Variant
+		fen     string
+		move    string
+		san     string
+	}{
+		{Standard, startingPositionFEN, "g1f3", "Nf3"},
+		{Standard, startingPositionFEN, "e2e4", "e4"},
+		{Standard, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "e1g1", "O-O"},
+		{Standard, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "e1c1", "O-O-O"},
+		{Standard, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "d5e6", "dxe6"},
+		{Standard, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "e5f7", "Nxf7"},
+		// The starting file, rank or square are added to tell apart the pieces reaching the same square
+		{Standard, "4k3/8/8/8/R6R/8/8/R3K3 w - - 0 1", "a4d4", "Rad4"},
+		{Standard, "4k3/8/8/8/R6R/8/8/R3K3 w - - 0 1", "a1a2", "R1a2"},
+		{Standard, "8/8/7k/8/8/Q1Q5/8/Q3K3 w - - 0 1", "a3b2", "Qa3b2"},
+		{Standard, "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", "b8=Q+"},
+		{Standard, "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", "a1a8", "Ra8#"},
+		{Standard, "4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", "exd6"},
+		{Crazyhouse, "4k3/8/8/8/8/8/8/4K3[N] w - - 0 1", "N@f6", "N@f6+"},
+	}
+
+	for _, test := range tests {
+		game := NewVariantGameFromFEN(test.variant, test.fen)
+		move, err := game.ParseUCIMove(test.move)
+		if err != nil {
+			t.Fatalf("%s should be a legal move in %s", test.move, test.fen)
+		}
+
+		if san := game.SAN(move); san != test.san {
+			t.Errorf("The SAN of %s in %s should be %s, %s was returned instead", test.move, test.fen, test.san, san)
+		}
+		if parsed, err := game.ParseSANMove(test.san); err != nil || *parsed != *move {
+			t.Errorf("%s in %s should be parsed as %s", test.san, test.fen, test.move)
+		}
+	}
+}
+
+func TestParseSANMove(t *testing.T) {
+	var tests = []struct {
+		fen  string
+		san  string
+		move string
+	}{
+		{startingPositionFEN, "Nf3?!", "g1f3"},
+		{startingPositionFEN, "e4!!", "e2e4"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "0-0-0", "e1c1"},
+		{startingPositionFEN, "Ke2", ""},
+		{startingPositionFEN, "e5", ""},
+		// Non canonical notations: over disambiguation, long algebraic and promotions without =
+		{startingPositionFEN, "Ngf3", "g1f3"},
+		{startingPositionFEN, "Ng1-f3", "g1f3"},
+		{startingPositionFEN, "Nbf3", ""},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8Q", "b7b8q"},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b8", ""},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "de6", "d5e6"},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", "Bb5", "e2b5"},
+		// The missing disambiguation is still an error
+		{"4k3/8/8/8/R6R/8/8/R3K3 w - - 0 1", "Rd4", ""},
+	}
+
+	for _, test := range tests {
+		game := NewGameFromFEN(test.fen)
+		move, err := game.ParseSANMove(test.san)
+
+		if test.move == "" {
+			if err == nil {
+				t.Errorf("%s in %s should be an illegal move, %s was returned instead", test.san, test.fen, move.UCI())
+			}
+		} else if err != nil || move.UCI() != test.move {
+			t.Errorf("%s in %s should be parsed as %s", test.san, test.fen, test.move)
+		}
+	}
+}
+
+func TestPGN(t *testing.T) {
+	var tests = []struct {
+		pgn      string
+		expected string
+	}{
+		{
+			`[Event "Test"]
+[White "A"]
+[Black "B \"the second\""]
+[Result "1-0"]
+
+{Opening}
+1.e4 e5 (1... c5 {Sicilian} 2. Nf3 (2. c3) d6) 2. Nf3! Nc6 $6 ; a line comment
+3. Bb5 a6 1-0
+
+[Event "Next game"]
+1. d4 *`,
+			`[Event "Test"]
+[Site "?"]
+[Date "?"]
+[Round "?"]
+[White "A"]
+[Black "B \"the second\""]
+[Result "1-0"]
+
+{Opening} 1. e4 e5 (1... c5 {Sicilian} 2. Nf3 (2. c3) 2... d6) 2. Nf3 $1 Nc6 $6
+{a line comment} 3. Bb5 a6 1-0
+`,
+		},
+		{
+			`[FEN "4k3/8/8/8/8/8/8/4K2R b K - 0 10"]
+
+10... Kd7 ({Or} 10... Ke7 11. Rh7+) 11. O-O *`,
+			`[Event "?"]
+[Site "?"]
+[Date "?"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "*"]
+[FEN "4k3/8/8/8/8/8/8/4K2R b K - 0 10"]
+[SetUp "1"]
+
+10... Kd7 ({Or} 10... Ke7 11. Rh7+) 11. O-O *
+`,
+		},
+		{
+			`[Variant "Crazyhouse"]
+
+1. e4 d5 2. exd5 Qxd5 3. P@e4 *`,
+			`[Event "?"]
+[Site "?"]
+[Date "?"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "*"]
+[Variant "crazyhouse"]
+
+1. e4 d5 2. exd5 Qxd5 3. P@e4 *
+`,
+		},
+	}
+
+	for _, test := range tests {
+		tree, err := ParsePGN(test.pgn)
+		if err != nil {
+			t.Fatalf("The PGN should be valid, %v was returned instead", err)
+		}
+
+		if pgn := tree.PGN(); pgn != test.expected {
+			t.Errorf("The PGN should be\n%s\n%s was returned instead", test.expected, pgn)
+		}
+
+		// Parsing the exported PGN gives back the same game
+		reparsed, err := ParsePGN(tree.PGN())
+		if err != nil || reparsed.PGN() != test.expected {
+			t.Errorf("The exported PGN should be parsed back to the same game\n%s", test.expected)
+		}
+	}
+}
+
+func TestInvalidPGN(t *testing.T) {
+	var tests = []string{
+		"1. e4 e5 2. Ke3 *",
+		"1. e4 (1. d4 *",
+		"( 1. e4 ) *",
+		"1. e4 {unterminated *",
+		`[FEN "invalid"] 1. e4 *`,
+		`[Variant "unknown"] 1. e4 *`,
+	}
+
+	for _, pgn := range tests {
+		if _, err := ParsePGN(pgn); err == nil {
+			t.Errorf("%s should be an invalid PGN", pgn)
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package chessboard
 
+import (
+	"fmt"
+	"strings"
+)
+
 // Color holds the color of a piece
 type Color int
 
@@ -57,6 +62,53 @@ const (
 	BlackPawn
 )
 
+// PieceKind is the type of a piece regardless of its color; e.g. king, rook, ...
+type PieceKind int8
+
+// Names for all the kinds of pieces, in the same order of the pieces
+const (
+	NoPieceKind PieceKind = iota
+	King
+	Queen
+	Rook
+	Bishop
+	Knight
+	Pawn
+)
+
+// NewPiece returns the piece of the passed color and kind
+func NewPiece(color Color, kind PieceKind) Piece {
+	switch {
+	case kind == NoPieceKind:
+		return NoPiece
+	case color == WhiteColor:
+		return Piece(kind)
+	case color == BlackColor:
+		return Piece(kind) + 6
+	default:
+		panic("Unrecognized color")
+	}
+}
+
+// Kind returns the kind of the piece
+func (p Piece) Kind() PieceKind {
+	if p == NoPiece {
+		return NoPieceKind
+	}
+
+	return PieceKind((p-1)%6 + 1)
+}
+
+// ParsePiece returns the piece written with its fen letter, uppercase for white and lowercase for black
+func ParsePiece(raw string) (Piece, error) {
+	index := strings.Index("KQRBNPkqrbnp", raw)
+	if len(raw) != 1 || index == -1 {
+		return NoPiece, fmt.Errorf("%s is not a valid piece", raw)
+	}
+
+	return Piece(index + 1), nil
+}
+
 func (p Piece) String() string {
 	switch {
 	case p == NoPiece:
@@ -2,7 +2,9 @@ package chessboard
 
 import (
 	"fmt"
+	"math/bits"
 	"strconv"
+	"strings"
 )
 
 // CastleRights stored information about which side each player
@@ -33,18 +35,45 @@ func (rights CastleRights) String() string {
 	return s
 }
 
+// standardCastlingRooks are the starting squares of the castling rooks in standard chess
+var standardCastlingRooks = [4]Square{H1, A1, H8, A8}
+
+// castleRightHash returns the zobrist hash of a castle right, in the order of the castlingRooks
+func castleRightHash(index int) ZobristHash {
+	return [4]ZobristHash{zobristHashWhiteKingCastle, zobristHashWhiteQueenCastle,
+		zobristHashBlackKingCastle, zobristHashBlackQueenCastle}[index]
+}
+
 // Position contains all the information about a give position in a game
 // including turn, enpassant information, castle rights, valid moves in this position...
 type Position struct {
-	board           Board
-	turn            Color
-	castleRights    CastleRights
-	enPassantSquare square
+	board        Board
+	turn         Color
+	castleRights CastleRights
+	// castlingRooks are the starting squares of the castling rooks, in the order of the castle rights.
+	// They are the corners in standard chess and can be on any file of the back rank in Chess960
+	castlingRooks   [4]Square
+	enPassantSquare Square
 	halfMoveClock   int
 	moveCount       int
 	inCheck         bool
 	legalMoves      []*Move
 	hash            ZobristHash
+	// pawnHash is the zobrist hash of the pawns only, used to cache the pawn structure evaluation
+	pawnHash ZobristHash
+	// checks are the checks given by white and black, they are counted only
+	// when countChecks is set as in three-check games
+	checks      [2]int
+	countChecks bool
+	// pockets are the pieces captured by white and black which can be dropped back on the board,
+	// indexed by piece type from the king to the pawn. They are used only when hasPockets is set
+	// as in crazyhouse games
+	pockets    [2][6]int
+	hasPockets bool
+	// promoted are the squares of the promoted pieces, which go back to the pocket as pawns when captured
+	promoted Bitboard
+	// precomputedData are the attack tables of the game, used to answer the attack queries
+	precomputedData *PrecomputedData
 }
 
 func (pos Position) String() string {
@@ -59,10 +88,139 @@ func (pos *Position) Hash() ZobristHash {
 	return pos.hash
 }
 
+// PawnHash returns the zobrist hash of the pawns in the position
+func (pos *Position) PawnHash() ZobristHash {
+	return pos.pawnHash
+}
+
+// isKingAttacked returns whether the king of the side to move is attacked,
+// the side to move can have no king in some variants
+func (pos *Position) isKingAttacked(precomputedData *PrecomputedData) bool {
+	if pos.board.sidePieces(pos.turn).king == 0 {
+		return false
+	}
+
+	return pos.board.IsUnderAttack(precomputedData, pos.turn, pos.board.kingSquare(pos.turn))
+}
+
+// addCheck counts a check given by the color
+func (pos *Position) addCheck(color Color) {
+	index := 0
+	if color == BlackColor {
+		index = 1
+	}
+
+	pos.hash ^= zobristHashChecks[index][pos.checks[index]]
+	pos.checks[index]++
+	pos.hash ^= zobristHashChecks[index][pos.checks[index]]
+}
+
+// changePocket adds delta pieces of the same type of the passed one to the pocket of the color
+func (pos *Position) changePocket(color Color, piece Piece, delta int) {
+	index := 0
+	if color == BlackColor {
+		index = 1
+	}
+	kind := (piece - 1) % 6
+
+	count := pos.pockets[index][kind] + delta
+	if count < 0 || count > maxPocketCount {
+		panic("Invalid number of pieces in the pocket")
+	}
+
+	pos.hash ^= zobristHashPockets[index][kind][pos.pockets[index][kind]]
+	pos.pockets[index][kind] = count
+	pos.hash ^= zobristHashPockets[index][kind][count]
+}
+
+// updatePockets moves the piece captured by the move to the pocket of the side to move,
+// removes the dropped pieces from it and keeps track of the promoted pieces.
+// It must be called before applying the move to the board
+func (pos *Position) updatePockets(move *Move) {
+	if move.IsDrop() {
+		pos.changePocket(pos.turn, move.DropPiece(), -1)
+		return
+	}
+	if move.IsCastle() {
+		return
+	}
+
+	captureSquare := move.To()
+	if *move&WhiteEnPassantFlag != 0 {
+		captureSquare -= 8
+	} else if *move&BlackEnPassantFlag != 0 {
+		captureSquare += 8
+	}
+
+	if captured := pos.board.Piece(captureSquare); captured != NoPiece {
+		// Only the type of the piece matters in the pocket, so the color of the pawn is irrelevant
+		if pos.promoted&captureSquare.Bitboard() != 0 {
+			captured = WhitePawn
+		}
+		pos.changePocket(pos.turn, captured, 1)
+	}
+
+	fromBB, toBB := move.From().Bitboard(), move.To().Bitboard()
+	pos.promoted &^= captureSquare.Bitboard() | toBB
+	if pos.promoted&fromBB != 0 || move.Promotion() != NoPiece {
+		pos.promoted = pos.promoted&^fromBB | toBB
+	}
+}
+
+// SideToMove returns the color of the player that has to move
+func (pos *Position) SideToMove() Color {
+	return pos.turn
+}
+
+// PieceAt returns the piece in the passed square, NoPiece if it's empty
+func (pos *Position) PieceAt(sq Square) Piece {
+	return pos.board.Piece(sq)
+}
+
+// CastlingRights returns the sides each player is still allowed to castle to
+func (pos *Position) CastlingRights() CastleRights {
+	return pos.castleRights
+}
+
+// EnPassant returns the square where a pawn can be captured en passant, NoSquare if there is none
+func (pos *Position) EnPassant() Square {
+	return pos.enPassantSquare
+}
+
+// InCheck returns whether the side to move is in check
+func (pos *Position) InCheck() bool {
+	return pos.inCheck
+}
+
+// Pieces returns the bitboard of the pieces of the passed color and kind
+func (pos *Position) Pieces(color Color, kind PieceKind) Bitboard {
+	return pos.board.Pieces(color, kind)
+}
+
+// AttackersTo returns the pieces of the passed color attacking the square
+func (pos *Position) AttackersTo(sq Square, color Color) Bitboard {
+	return pos.board.attackersTo(pos.precomputedData, sq, color, ^pos.board.emptySquares)
+}
+
+// Checkers returns the pieces giving check to the king of the side to move
+func (pos *Position) Checkers() Bitboard {
+	if !pos.inCheck {
+		return 0
+	}
+
+	return pos.AttackersTo(pos.board.kingSquare(pos.turn), pos.turn.Other())
+}
+
 // Move returns a new position applying the move, the operation is NOT in place
 func (pos Position) Move(move *Move) Position {
+	whiteKing, blackKing := pos.board.whiteKingSquare, pos.board.blackKingSquare
+
 	// Check whether the move passed is the null move
 	if move.From() != NoSquare {
+		if pos.hasPockets {
+			pos.updatePockets(move)
+		}
+		pos.pawnHash ^= pos.board.pawnHashUpdate(move)
 		pos.hash ^= pos.board.Move(move)
 	}
 	pos.turn = pos.turn.Other()
@@ -77,45 +235,27 @@ func (pos Position) Move(move *Move) Position {
 
 	pos.legalMoves = nil
 
-	// update castle rights
-	if move.From() == E1 {
-		if pos.castleRights.WhiteKingSide {
-			pos.hash ^= zobristHashWhiteKingCastle
-			pos.castleRights.WhiteKingSide = false
-
+	// update castle rights: moving the king loses both the rights of its color,
+	// moving or capturing a rook loses its right
+	rights := [4]*bool{&pos.castleRights.WhiteKingSide, &pos.castleRights.WhiteQueenSide,
+		&pos.castleRights.BlackKingSide, &pos.castleRights.BlackQueenSide}
+	for i, right := range rights {
+		if !*right {
+			continue
 		}
-		if pos.castleRights.WhiteQueenSide {
-			pos.hash ^= zobristHashWhiteQueenCastle
-			pos.castleRights.WhiteQueenSide = false
-		}
-	}
-	if move.From() == E8 {
-		if pos.castleRights.BlackKingSide {
-			pos.hash ^= zobristHashBlackKingCastle
-			pos.castleRights.BlackKingSide = false
 
+		king, color := whiteKing, WhiteColor
+		if i >= 2 {
+			king, color = blackKing, BlackColor
 		}
-		if pos.castleRights.BlackQueenSide {
-			pos.hash ^= zobristHashBlackQueenCastle
-			pos.castleRights.BlackQueenSide = false
+		// In atomic chess the king and the rook can also be exploded by a capture next to them
+		pieces := pos.board.sidePieces(color)
+		exploded := pos.board.explosions && (pieces.king == 0 || pieces.rooks&pos.castlingRooks[i].Bitboard() == 0)
+		if move.From() == king || move.From() == pos.castlingRooks[i] || move.To() == pos.castlingRooks[i] || exploded {
+			*right = false
+			pos.hash ^= castleRightHash(i)
 		}
 	}
-	if (move.From() == A1 || move.To() == A1) && pos.castleRights.WhiteQueenSide {
-		pos.hash ^= zobristHashWhiteQueenCastle
-		pos.castleRights.WhiteQueenSide = false
-	}
-	if (move.From() == A8 || move.To() == A8) && pos.castleRights.BlackQueenSide {
-		pos.hash ^= zobristHashBlackQueenCastle
-		pos.castleRights.BlackQueenSide = false
-	}
-	if (move.From() == H1 || move.To() == H1) && pos.castleRights.WhiteKingSide {
-		pos.hash ^= zobristHashWhiteKingCastle
-		pos.castleRights.WhiteKingSide = false
-	}
-	if (move.From() == H8 || move.To() == H8) && pos.castleRights.BlackKingSide {
-		pos.hash ^= zobristHashBlackKingCastle
-		pos.castleRights.BlackKingSide = false
-	}
 
 	// Remove previous enpassant square from the hash
 	if pos.enPassantSquare != NoSquare {
@@ -184,6 +324,9 @@ func (pos *Position) FEN() string {
 			default:
 				panic("Unrecognized piece")
 			}
+			if pos.promoted&sq.Bitboard() != 0 {
+				fen += "~"
+			}
 		}
 
 		clearCounter()
@@ -192,6 +335,10 @@ func (pos *Position) FEN() string {
 		}
 	}
 
+	if pos.hasPockets {
+		fen += "[" + pos.pocketsFEN() + "]"
+	}
+
 	fen += " "
 
 	switch pos.turn {
@@ -203,30 +350,87 @@ func (pos *Position) FEN() string {
 
 	fen += " "
 
-	castleRights := ""
-	if pos.castleRights.WhiteKingSide {
-		castleRights += "K"
-	}
-	if pos.castleRights.WhiteQueenSide {
-		castleRights += "Q"
-	}
-	if pos.castleRights.BlackKingSide {
-		castleRights += "k"
-	}
-	if pos.castleRights.BlackQueenSide {
-		castleRights += "q"
-	}
-	if castleRights == "" {
-		castleRights = "-"
-	}
+	castleRights := pos.castleRightsFEN()
 	fen += castleRights
 
 	fen += " "
 	fen += pos.enPassantSquare.String()
 	fen += " "
+	if pos.countChecks {
+		fen += fmt.Sprintf("%d+%d ", maxChecks-pos.checks[0], maxChecks-pos.checks[1])
+	}
 	fen += strconv.Itoa(pos.halfMoveClock)
 	fen += " "
 	fen += strconv.Itoa(pos.moveCount)
 
 	return fen
 }
+
+// pocketsFEN returns the pieces in the pockets, the white ones in uppercase followed by the black ones in lowercase
+func (pos *Position) pocketsFEN() string {
+	pockets := ""
+	for i, letters := range []string{"KQRBNP", "kqrbnp"} {
+		for kind, count := range pos.pockets[i] {
+			pockets += strings.Repeat(string(letters[kind]), count)
+		}
+	}
+
+	return pockets
+}
+
+// castleRightsFEN returns the castle rights in X-FEN notation: KQkq when the castling rook
+// is the outermost one on its side of the king, as in standard chess, and the file of the
+// rook otherwise, which happens only in Chess960
+func (pos *Position) castleRightsFEN() string {
+	rights := [4]bool{pos.castleRights.WhiteKingSide, pos.castleRights.WhiteQueenSide,
+		pos.castleRights.BlackKingSide, pos.castleRights.BlackQueenSide}
+
+	castleRights := ""
+	for i, right := range rights {
+		if !right {
+			continue
+		}
+
+		color, letters, files := WhiteColor, "KQ", "ABCDEFGH"
+		if i >= 2 {
+			color, letters, files = BlackColor, "kq", "abcdefgh"
+		}
+
+		rook := pos.castlingRooks[i]
+		if rook == outermostRook(&pos.board, color, i%2 == 0) {
+			castleRights += string(letters[i%2])
+		} else {
+			castleRights += string(files[rook%8])
+		}
+	}
+	if castleRights == "" {
+		castleRights = "-"
+	}
+
+	return castleRights
+}
+
+// outermostRook returns the rook on the back rank furthest from the king on the king side
+// or the queen side, NoSquare when there are none
+func outermostRook(board *Board, color Color, kingSide bool) Square {
+	backRank := 0
+	if color == BlackColor {
+		backRank = 7
+	}
+
+	king := board.kingSquare(color)
+	rooks := board.sidePieces(color).rooks & rankBitboard(backRank)
+	if kingSide {
+		rooks &= ^(Bitboard(2)<<king - 1)
+		if rooks == 0 {
+			return NoSquare
+		}
+		return Square(bits.Len64(uint64(rooks)) - 1)
+	}
+
+	rooks &= Bitboard(1)<<king - 1
+	if rooks == 0 {
+		return NoSquare
+	}
+	return Square(rooks.LeastSignificant1Bit())
+}
@@ -8,3 +8,72 @@ func TestFENGeneration(t *testing.T) {
 		t.Errorf("FEN at starting position should be rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1, %s was returned instead", got)
 	}
 }
+
+func TestPositionQueries(t *testing.T) {
+	game := NewGameFromFEN("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	pos := game.Position()
+
+	if piece := pos.PieceAt(E5); piece != WhiteKnight {
+		t.Errorf("The piece on e5 should be %s, %s was returned instead", WhiteKnight, piece)
+	}
+	if piece := pos.PieceAt(E3); piece != NoPiece {
+		t.Errorf("e3 should be empty, %s was returned instead", piece)
+	}
+	if pos.SideToMove() != WhiteColor || pos.InCheck() || pos.EnPassant() != NoSquare {
+		t.Errorf("White should be to move, not in check and without en passant")
+	}
+	if rights := pos.CastlingRights(); rights != (CastleRights{true, true, true, true}) {
+		t.Errorf("All the castle rights should be available, %s was returned instead", rights)
+	}
+	if knights := pos.Pieces(WhiteColor, Knight); knights != C3.Bitboard()|E5.Bitboard() {
+		t.Errorf("The white knights should be on c3 and e5, %v was returned instead", knights)
+	}
+
+	// f7 is attacked by the knight on e5 and defended by the king and the queen
+	if attackers := pos.AttackersTo(F7, WhiteColor); attackers != E5.Bitboard() {
+		t.Errorf("f7 should be attacked by the knight on e5, %v was returned instead", attackers)
+	}
+	if attackers := pos.AttackersTo(F7, BlackColor); attackers != E8.Bitboard()|E7.Bitboard() {
+		t.Errorf("f7 should be defended by the king and the queen, %v was returned instead", attackers)
+	}
+
+	game = NewGameFromFEN("4k3/8/8/8/1b6/8/3P4/4K2r w - - 0 1")
+	pos = game.Position()
+	if checkers := pos.Checkers(); !pos.InCheck() || checkers != H1.Bitboard() {
+		t.Errorf("The white king should be in check by the rook on h1, %v was returned instead", checkers)
+	}
+
+	game = NewGameFromFEN("4k3/8/8/8/1b6/8/8/4K2r w - - 0 1")
+	pos = game.Position()
+	if checkers := pos.Checkers(); checkers != B4.Bitboard()|H1.Bitboard() {
+		t.Errorf("The white king should be in double check, %v was returned instead", checkers)
+	}
+}
+
+func TestPieceKinds(t *testing.T) {
+	var tests = []struct {
+		raw   string
+		piece Piece
+		color Color
+		kind  PieceKind
+	}{
+		{"K", WhiteKing, WhiteColor, King},
+		{"P", WhitePawn, WhiteColor, Pawn},
+		{"q", BlackQueen, BlackColor, Queen},
+		{"n", BlackKnight, BlackColor, Knight},
+	}
+
+	for _, test := range tests {
+		piece, err := ParsePiece(test.raw)
+		if err != nil || piece != test.piece {
+			t.Errorf("%s should be parsed as %s, %s was returned instead", test.raw, test.piece, piece)
+		}
+		if piece.Kind() != test.kind || NewPiece(test.color, test.kind) != test.piece {
+			t.Errorf("%s should have kind %d", test.piece, test.kind)
+		}
+	}
+
+	if _, err := ParsePiece("x"); err == nil {
+		t.Errorf("x should not be a valid piece")
+	}
+}
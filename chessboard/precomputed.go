@@ -0,0 +1,259 @@
+package chessboard
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// rawPrecomputedData contains the tables written by bitboard_generators in the binary format
+//
+//go:embed precomputed.bin
+var rawPrecomputedData []byte
+
+// Magic numbers of the sliding pieces, from https://github.com/GunshipPenguin/shallow-blue/.
+// New magics and index bits can be found with the -magics mode of bitboard_generators, which
+// also uses these ones to write the tables. They must not be modified
+var RookMagicNumbers = [64]uint64{
+	0xa8002c000108020, 0x6c00049b0002001, 0x100200010090040, 0x2480041000800801,
+	0x280028004000800, 0x900410008040022, 0x280020001001080, 0x2880002041000080,
+	0xa000800080400034, 0x4808020004000, 0x2290802004801000, 0x411000d00100020,
+	0x402800800040080, 0xb000401004208, 0x2409000100040200, 0x1002100004082,
+	0x22878001e24000, 0x1090810021004010, 0x801030040200012, 0x500808008001000,
+	0xa08018014000880, 0x8000808004000200, 0x201008080010200, 0x801020000441091,
+	0x800080204005, 0x1040200040100048, 0x120200402082, 0xd14880480100080,
+	0x12040280080080, 0x100040080020080, 0x9020010080800200, 0x813241200148449,
+	0x491604001800080, 0x100401000402001, 0x4820010021001040, 0x400402202000812,
+	0x209009005000802, 0x810800601800400, 0x4301083214000150, 0x204026458e001401,
+	0x40204000808000, 0x8001008040010020, 0x8410820820420010, 0x1003001000090020,
+	0x804040008008080, 0x12000810020004, 0x1000100200040208, 0x430000a044020001,
+	0x280009023410300, 0xe0100040002240, 0x200100401700, 0x2244100408008080,
+	0x8000400801980, 0x2000810040200, 0x8010100228810400, 0x2000009044210200,
+	0x4080008040102101, 0x40002080411d01, 0x2005524060000901, 0x502001008400422,
+	0x489a000810200402, 0x1004400080a13, 0x4000011008020084, 0x26002114058042,
+}
+
+var BishopMagicNumbers = [64]uint64{
+	0x89a1121896040240, 0x2004844802002010, 0x2068080051921000, 0x62880a0220200808,
+	0x4042004000000, 0x100822020200011, 0xc00444222012000a, 0x28808801216001,
+	0x400492088408100, 0x201c401040c0084, 0x840800910a0010, 0x82080240060,
+	0x2000840504006000, 0x30010c4108405004, 0x1008005410080802, 0x8144042209100900,
+	0x208081020014400, 0x4800201208ca00, 0xf18140408012008, 0x1004002802102001,
+	0x841000820080811, 0x40200200a42008, 0x800054042000, 0x88010400410c9000,
+	0x520040470104290, 0x1004040051500081, 0x2002081833080021, 0x400c00c010142,
+	0x941408200c002000, 0x658810000806011, 0x188071040440a00, 0x4800404002011c00,
+	0x104442040404200, 0x511080202091021, 0x4022401120400, 0x80c0040400080120,
+	0x8040010040820802, 0x480810700020090, 0x102008e00040242, 0x809005202050100,
+	0x8002024220104080, 0x431008804142000, 0x19001802081400, 0x200014208040080,
+	0x3308082008200100, 0x41010500040c020, 0x4012020c04210308, 0x208220a202004080,
+	0x111040120082000, 0x6803040141280a00, 0x2101004202410000, 0x8200000041108022,
+	0x21082088000, 0x2410204010040, 0x40100400809000, 0x822088220820214,
+	0x40808090012004, 0x910224040218c9, 0x402814422015008, 0x90014004842410,
+	0x1000042304105, 0x10008830412a00, 0x2520081090008908, 0x40102000a0a60140,
+}
+
+// Index bits of the magics of the sliding pieces, with one bit for each relevant blocker the table
+// of the square has a slot for every subset of its blockers
+var RookIndexBits = [64]int{
+	12, 11, 11, 11, 11, 11, 11, 12,
+	11, 10, 10, 10, 10, 10, 10, 11,
+	11, 10, 10, 10, 10, 10, 10, 11,
+	11, 10, 10, 10, 10, 10, 10, 11,
+	11, 10, 10, 10, 10, 10, 10, 11,
+	11, 10, 10, 10, 10, 10, 10, 11,
+	11, 10, 10, 10, 10, 10, 10, 11,
+	12, 11, 11, 11, 11, 11, 11, 12,
+}
+
+var BishopIndexBits = [64]int{
+	6, 5, 5, 5, 5, 5, 5, 6,
+	5, 5, 5, 5, 5, 5, 5, 5,
+	5, 5, 7, 7, 7, 7, 5, 5,
+	5, 5, 7, 9, 9, 7, 5, 5,
+	5, 5, 7, 9, 9, 7, 5, 5,
+	5, 5, 7, 7, 7, 7, 5, 5,
+	5, 5, 5, 5, 5, 5, 5, 5,
+	6, 5, 5, 5, 5, 5, 5, 6,
+}
+
+// Directions of the sliding pieces as file and rank steps
+var (
+	rookDirections   = [4][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	bishopDirections = [4][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+)
+
+// Magic contains what's needed to look up the attacks of a sliding piece on a square: the blockers
+// inside the mask are multiplied by the magic number and the top bits of the product are the index
+// of the attacks in the table of the square, which starts at Offset in the attacks table.
+// With one index bit for each square of the mask, the table of each square has a slot for
+// every subset of the mask like with PEXT indexing
+type Magic struct {
+	Mask   Bitboard
+	Number uint64
+	Shift  uint8
+	Offset uint32
+}
+
+// index returns the position in the attacks table of the attacks with the passed occupied squares
+func (magic *Magic) index(occupied Bitboard) uint32 {
+	return magic.Offset + uint32((uint64(occupied&magic.Mask)*magic.Number)>>magic.Shift)
+}
+
+// PrecomputedData contains all the precalculated bitboards used in move generation and evaluation.
+// The data is decoded once and shared by all the games, so it must not be modified
+type PrecomputedData struct {
+	KingMoves    [64]Bitboard
+	KnightMoves  [64]Bitboard
+	RookMagics   [64]Magic
+	RookMoves    []Bitboard
+	BishopMagics [64]Magic
+	BishopMoves  []Bitboard
+
+	DoublePawnsForwardMasks [64]Bitboard
+	DoublePawnsSidesMasks   [64]Bitboard
+	PassedPawnWhiteMasks    [64]Bitboard
+	PassedPawnBlackMasks    [64]Bitboard
+	// KPKBitbase contains a bit for each king and pawn against king position, set when the
+	// side with the pawn wins, see kpkIndex for the layout
+	KPKBitbase [kpkBitbaseSize]uint64
+}
+
+var (
+	precomputed     *PrecomputedData
+	precomputedOnce sync.Once
+)
+
+// sharedPrecomputedData returns the precomputed data, decoding the embedded tables the first time it's needed
+func sharedPrecomputedData() *PrecomputedData {
+	precomputedOnce.Do(func() {
+		data, err := ReadPrecomputedData(bytes.NewReader(rawPrecomputedData))
+		if err != nil {
+			panic(err)
+		}
+
+		precomputed = data
+	})
+
+	return precomputed
+}
+
+// UsePrecomputedData makes the games use the passed data, e.g. read with LoadPrecomputedDataFile, instead
+// of the embedded one. It must be called before creating the first game, otherwise it returns an error
+func UsePrecomputedData(data *PrecomputedData) error {
+	used := false
+	precomputedOnce.Do(func() {
+		precomputed = data
+		used = true
+	})
+
+	if !used {
+		return errors.New("the precomputed data is already in use")
+	}
+
+	return nil
+}
+
+// generatePrecomputedData computes the attack tables and the masks of the precomputed data, which
+// the tests compare with the embedded ones. The KPK bitbase is generated only by bitboard_generators
+func generatePrecomputedData() *PrecomputedData {
+	data := &PrecomputedData{}
+
+	for sq := Square(0); sq < 64; sq++ {
+		data.KingMoves[sq] = adjacentSquares(sq)
+		data.KnightMoves[sq] = knightMoves(sq)
+
+		data.DoublePawnsForwardMasks[sq] = fileBitboard(sq) << (sq + 8 - sq%8)
+		data.DoublePawnsSidesMasks[sq] = adjacentFiles(sq)
+		data.PassedPawnWhiteMasks[sq] = (fileBitboard(sq) | adjacentFiles(sq)) << (sq + 8 - sq%8)
+		data.PassedPawnBlackMasks[sq] = (fileBitboard(sq) | adjacentFiles(sq)) >> (64 - sq + sq%8)
+	}
+
+	data.RookMoves = fillMagics(&data.RookMagics, RookMagicNumbers, RookIndexBits, rookDirections)
+	data.BishopMoves = fillMagics(&data.BishopMagics, BishopMagicNumbers, BishopIndexBits, bishopDirections)
+
+	return data
+}
+
+// adjacentFiles returns the files next to the one of the square
+func adjacentFiles(sq Square) Bitboard {
+	file := fileBitboard(sq)
+	return (file&^fileABitboard)>>1 | (file&^fileHBitboard)<<1
+}
+
+// knightMoves returns the squares reached by a knight on the passed square
+func knightMoves(sq Square) Bitboard {
+	moves := Bitboard(0)
+	for _, step := range [8][2]int{{1, 2}, {2, 1}, {2, -1}, {1, -2}, {-1, -2}, {-2, -1}, {-2, 1}, {-1, 2}} {
+		file, rank := sq.File()+step[0], sq.Rank()+step[1]
+		if file >= 0 && file < 8 && rank >= 0 && rank < 8 {
+			moves |= SquareFromFileRank(file, rank).Bitboard()
+		}
+	}
+
+	return moves
+}
+
+// slidingAttacks returns the squares attacked by a piece sliding in the passed directions,
+// stopping at the first occupied square of each direction
+func slidingAttacks(sq Square, occupied Bitboard, directions [4][2]int) Bitboard {
+	attacks := Bitboard(0)
+	for _, direction := range directions {
+		file, rank := sq.File()+direction[0], sq.Rank()+direction[1]
+		for file >= 0 && file < 8 && rank >= 0 && rank < 8 {
+			target := SquareFromFileRank(file, rank).Bitboard()
+			attacks |= target
+			if occupied&target != 0 {
+				break
+			}
+
+			file, rank = file+direction[0], rank+direction[1]
+		}
+	}
+
+	return attacks
+}
+
+// fillMagics computes the magics of a sliding piece and returns the attacks table, the tables of the
+// squares are stored one after the other. It panics if a magic number maps two different attack
+// sets to the same slot
+func fillMagics(magics *[64]Magic, numbers [64]uint64, indexBits [64]int, directions [4][2]int) []Bitboard {
+	size := 0
+	for _, bits := range indexBits {
+		size += 1 << bits
+	}
+	table := make([]Bitboard, size)
+
+	offset := 0
+	for sq := Square(0); sq < 64; sq++ {
+		// The blockers on the edges of the board don't change the attacks,
+		// unless the piece is on the same edge
+		edges := (rankBitboard(0)|rankBitboard(7))&^rankBitboard(sq.Rank()) |
+			(fileABitboard|fileHBitboard)&^fileBitboard(sq)
+		mask := slidingAttacks(sq, 0, directions) &^ edges
+		bits := indexBits[sq]
+
+		magics[sq] = Magic{Mask: mask, Number: numbers[sq], Shift: uint8(64 - bits), Offset: uint32(offset)}
+
+		// The Carry-Rippler trick enumerates all the subsets of the mask
+		blockers := Bitboard(0)
+		for {
+			attacks := slidingAttacks(sq, blockers, directions)
+			index := magics[sq].index(blockers)
+			if table[index] != 0 && table[index] != attacks {
+				panic(fmt.Sprintf("Invalid magic number for square %s", sq))
+			}
+			table[index] = attacks
+
+			blockers = (blockers - mask) & mask
+			if blockers == 0 {
+				break
+			}
+		}
+
+		offset += 1 << bits
+	}
+
+	return table
+}
@@ -0,0 +1,166 @@
+package chessboard
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// precomputedMagic starts every precomputed data file, followed by the format version
+const precomputedMagic = "GCPD"
+const precomputedVersion = 2
+
+// precomputedHeader is stored at the beginning of the precomputed data files, all the values are little endian
+type precomputedHeader struct {
+	Magic           [4]byte
+	Version         uint32
+	RookTableSize   uint32
+	BishopTableSize uint32
+	// Checksum is the CRC-32 (IEEE) of the tables after the header
+	Checksum uint32
+}
+
+// tables returns the tables of the precomputed data in the order they are stored in the files
+func (data *PrecomputedData) tables() []interface{} {
+	return []interface{}{
+		&data.KingMoves, &data.KnightMoves,
+		&data.RookMagics, data.RookMoves,
+		&data.BishopMagics, data.BishopMoves,
+		&data.DoublePawnsForwardMasks, &data.DoublePawnsSidesMasks,
+		&data.PassedPawnWhiteMasks, &data.PassedPawnBlackMasks,
+		&data.KPKBitbase,
+	}
+}
+
+// LoadPrecomputedDataFile reads the precomputed data from a file. The file contains the header followed by
+// the king and knight moves, the rook magics and attacks, the bishop magics and attacks, the doubled
+// and passed pawn masks and the KPK bitbase. Each magic is stored as the mask and the number (uint64),
+// the shift (uint8) and the offset (uint32), the bitboards and the bitbase words are uint64 and the
+// attacks tables have the sizes in the header
+func LoadPrecomputedDataFile(path string) (*PrecomputedData, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := ReadPrecomputedData(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return data, nil
+}
+
+// ReadPrecomputedData reads the precomputed data in the format described in LoadPrecomputedDataFile
+func ReadPrecomputedData(r io.Reader) (*PrecomputedData, error) {
+	var header precomputedHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("invalid precomputed data header: %w", err)
+	}
+
+	if string(header.Magic[:]) != precomputedMagic {
+		return nil, errors.New("not a precomputed data file")
+	}
+	if header.Version != precomputedVersion {
+		return nil, fmt.Errorf("unsupported precomputed data version %d", header.Version)
+	}
+	if header.RookTableSize > 64*4096 || header.BishopTableSize > 64*512 {
+		return nil, fmt.Errorf("invalid attacks table sizes %d and %d", header.RookTableSize, header.BishopTableSize)
+	}
+
+	data := &PrecomputedData{
+		RookMoves:   make([]Bitboard, header.RookTableSize),
+		BishopMoves: make([]Bitboard, header.BishopTableSize),
+	}
+
+	checksum := crc32.NewIEEE()
+	tables := io.TeeReader(r, checksum)
+	for _, table := range data.tables() {
+		if err := binary.Read(tables, binary.LittleEndian, table); err != nil {
+			return nil, fmt.Errorf("truncated precomputed data: %w", err)
+		}
+	}
+
+	// Trailing data means that the header doesn't describe the tables
+	if n, _ := r.Read(make([]byte, 1)); n != 0 {
+		return nil, errors.New("unexpected data after the precomputed tables")
+	}
+	if checksum.Sum32() != header.Checksum {
+		return nil, errors.New("the checksum of the precomputed data doesn't match")
+	}
+
+	// The lookups must stay inside the attacks tables
+	if err := checkMagics(&data.RookMagics, len(data.RookMoves)); err != nil {
+		return nil, fmt.Errorf("invalid rook magics: %w", err)
+	}
+	if err := checkMagics(&data.BishopMagics, len(data.BishopMoves)); err != nil {
+		return nil, fmt.Errorf("invalid bishop magics: %w", err)
+	}
+
+	return data, nil
+}
+
+// checkMagics returns an error if a magic can index a slot outside of the attacks table
+func checkMagics(magics *[64]Magic, tableSize int) error {
+	for sq, magic := range magics {
+		if magic.Shift < 64-12 || magic.Shift >= 64 {
+			return fmt.Errorf("invalid shift %d for square %s", magic.Shift, Square(sq))
+		}
+		if int(magic.Offset)+1<<(64-magic.Shift) > tableSize {
+			return fmt.Errorf("the table of square %s exceeds the attacks table", Square(sq))
+		}
+	}
+
+	return nil
+}
+
+// Save writes the precomputed data to a file in the format described in LoadPrecomputedDataFile
+func (data *PrecomputedData) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	if err := data.Write(writer); err != nil {
+		file.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// Write writes the precomputed data in the format described in LoadPrecomputedDataFile
+func (data *PrecomputedData) Write(w io.Writer) error {
+	// The tables are encoded first because the header contains their checksum
+	var tables bytes.Buffer
+	for _, table := range data.tables() {
+		if err := binary.Write(&tables, binary.LittleEndian, table); err != nil {
+			return err
+		}
+	}
+
+	header := precomputedHeader{
+		Version:         precomputedVersion,
+		RookTableSize:   uint32(len(data.RookMoves)),
+		BishopTableSize: uint32(len(data.BishopMoves)),
+		Checksum:        crc32.ChecksumIEEE(tables.Bytes()),
+	}
+	copy(header.Magic[:], precomputedMagic)
+
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	_, err := w.Write(tables.Bytes())
+	return err
+}
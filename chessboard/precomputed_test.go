@@ -0,0 +1,102 @@
+package chessboard
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPrecomputedDataRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "precomputed.bin")
+	data := sharedPrecomputedData()
+
+	if err := data.Save(path); err != nil {
+		t.Fatalf("Saving the precomputed data should succeed, %v was returned instead", err)
+	}
+
+	loaded, err := LoadPrecomputedDataFile(path)
+	if err != nil {
+		t.Fatalf("Loading the precomputed data should succeed, %v was returned instead", err)
+	}
+	if !reflect.DeepEqual(loaded, data) {
+		t.Errorf("The loaded precomputed data should be equal to the saved one")
+	}
+
+	// The tables can't be replaced once the games use them
+	if err := UsePrecomputedData(loaded); err == nil {
+		t.Errorf("Replacing the precomputed data in use should fail")
+	}
+}
+
+func TestEmbeddedPrecomputedData(t *testing.T) {
+	// The embedded tables must be regenerated with bitboard_generators when the magics change
+	embedded, generated := sharedPrecomputedData(), generatePrecomputedData()
+	generated.KPKBitbase = embedded.KPKBitbase
+	if !reflect.DeepEqual(embedded, generated) {
+		t.Errorf("The embedded precomputed data should be equal to the generated one")
+	}
+}
+
+func TestLoadInvalidPrecomputedData(t *testing.T) {
+	var valid bytes.Buffer
+	if err := sharedPrecomputedData().Write(&valid); err != nil {
+		t.Fatal(err)
+	}
+	data := valid.Bytes()
+
+	wrongMagic := append([]byte("GCNN"), data[4:]...)
+	wrongVersion := append(append([]byte{}, data[:4]...), append([]byte{1, 0, 0, 0}, data[8:]...)...)
+	corrupted := append([]byte{}, data...)
+	corrupted[len(corrupted)-100] ^= 1
+
+	// The checksum is valid but the magic of a1 indexes outside of the rook attacks
+	invalidMagics := *sharedPrecomputedData()
+	invalidMagics.RookMagics[A1].Shift = 40
+	var invalid bytes.Buffer
+	if err := invalidMagics.Write(&invalid); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][]byte{
+		"empty":         {},
+		"wrong magic":   wrongMagic,
+		"wrong version": wrongVersion,
+		"truncated":     data[:len(data)-1],
+		"trailing data": append(append([]byte{}, data...), 0),
+		"corruption":    corrupted,
+		"invalid shift": invalid.Bytes(),
+	}
+
+	dir := t.TempDir()
+	for name, content := range tests {
+		path := filepath.Join(dir, "precomputed.bin")
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadPrecomputedDataFile(path); err == nil {
+			t.Errorf("Loading a precomputed data file with %s should fail, no error was returned instead", name)
+		}
+	}
+}
+
+func BenchmarkGeneratePrecomputedData(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		generatePrecomputedData()
+	}
+}
+
+func BenchmarkReadPrecomputedData(b *testing.B) {
+	var file bytes.Buffer
+	if err := sharedPrecomputedData().Write(&file); err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadPrecomputedData(bytes.NewReader(file.Bytes())); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
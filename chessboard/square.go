@@ -3,14 +3,14 @@ package chessboard
 import "fmt"
 
 // Square represents a square on the board
-type square int8
+type Square int8
 
 // Bitboard returns a bitboard with only the passed square on
-func (s square) Bitboard() Bitboard {
+func (s Square) Bitboard() Bitboard {
 	return Bitboard(1 << s)
 }
 
-func (s square) String() string {
+func (s Square) String() string {
 	if s == NoSquare {
 		return "-"
 	}
@@ -22,7 +22,7 @@ func (s square) String() string {
 }
 
 // Color returns the color of the square
-func (s square) Color() Color {
+func (s Square) Color() Color {
 	file := s % 8
 	rank := s / 8
 
@@ -33,16 +33,36 @@ func (s square) Color() Color {
 	return WhiteColor
 }
 
+// File returns the file of the square: 0=A 1=B ... 7=H
+func (s Square) File() int {
+	return int(s % 8)
+}
+
+// Rank returns the rank of the square: 0=1 1=2 ... 7=8
+func (s Square) Rank() int {
+	return int(s / 8)
+}
+
+// ParseSquare returns the square written in algebraic notation, e.g. e4
+func ParseSquare(raw string) (Square, error) {
+	sq, ok := stringToSquare[raw]
+	if !ok || sq == NoSquare {
+		return NoSquare, fmt.Errorf("%s is not a valid square", raw)
+	}
+
+	return sq, nil
+}
+
 // SquareFromFileRank returns the square corresponding to the passed file and rank
 // file: 0=A 1=B ... 7=H
 // rank: 0=1 1=2 ... 7=8
-func SquareFromFileRank(file int, rank int) square {
-	return square(file + rank*8)
+func SquareFromFileRank(file int, rank int) Square {
+	return Square(file + rank*8)
 }
 
 // All publicly available values for square
 const (
-	NoSquare square = iota - 1
+	NoSquare Square = iota - 1
 	A1
 	B1
 	C1
@@ -109,7 +129,7 @@ const (
 	H8
 )
 
-var stringToSquare = map[string]square{
+var stringToSquare = map[string]Square{
 	"a1": A1, "a2": A2, "a3": A3, "a4": A4, "a5": A5, "a6": A6, "a7": A7, "a8": A8,
 	"b1": B1, "b2": B2, "b3": B3, "b4": B4, "b5": B5, "b6": B6, "b7": B7, "b8": B8,
 	"c1": C1, "c2": C2, "c3": C3, "c4": C4, "c5": C5, "c6": C6, "c7": C7, "c8": C8,
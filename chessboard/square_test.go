@@ -46,23 +46,49 @@ func TestColor(t *testing.T) {
 }
 
 func TestSquareFromIndex(t *testing.T) {
-	got := square(0)
+	got := Square(0)
 	if got.String() != "a1" {
 		t.Errorf("Square from index 0 should be a1, %s was returned instead", got)
 	}
 
-	got = square(4)
+	got = Square(4)
 	if got.String() != "e1" {
 		t.Errorf("Square from index 4 should be a5, %s was returned instead", got)
 	}
 
-	got = square(10)
+	got = Square(10)
 	if got.String() != "c2" {
 		t.Errorf("Square from index 10 should be c2, %s was returned instead", got)
 	}
 
-	got = square(63)
+	got = Square(63)
 	if got.String() != "h8" {
 		t.Errorf("Square from index 63 should be h8, %s was returned instead", got)
 	}
 }
+
+func TestParseSquare(t *testing.T) {
+	var tests = []struct {
+		raw   string
+		sq    Square
+		valid bool
+	}{
+		{"a1", A1, true},
+		{"e4", E4, true},
+		{"h8", H8, true},
+		{"-", NoSquare, false},
+		{"i1", NoSquare, false},
+		{"E4", NoSquare, false},
+	}
+
+	for _, test := range tests {
+		sq, err := ParseSquare(test.raw)
+		if (err == nil) != test.valid || sq != test.sq {
+			t.Errorf("%s should be parsed as %s, %s was returned instead", test.raw, test.sq, sq)
+		}
+	}
+
+	if E4.File() != 4 || E4.Rank() != 3 {
+		t.Errorf("E4 should be on file 4 and rank 3, %d and %d were returned instead", E4.File(), E4.Rank())
+	}
+}
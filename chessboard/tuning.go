@@ -0,0 +1,378 @@
+package chessboard
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TuningPosition is a position labeled with the result of the game it was played in
+type TuningPosition struct {
+	FEN string
+	// Result is the score of the game for white: 1 for a win, 0.5 for a draw and 0 for a loss
+	Result float64
+
+	position Position
+}
+
+// tuningResults maps the accepted notations of a game result to the score for white
+var tuningResults = map[string]float64{
+	"1-0":     1,
+	"0-1":     0,
+	"1/2-1/2": 0.5,
+	"1.0":     1,
+	"0.5":     0.5,
+	"0.0":     0,
+	"1":       1,
+	"0":       0,
+}
+
+// ParseTuningPosition parses a line of a labeled position set. The line starts with a FEN,
+// whose clocks can be omitted as in EPD, followed by the game result written either as
+// a PGN result (1-0, 0-1, 1/2-1/2), as a score for white in brackets ([1.0], [0.5], [0.0])
+// or as an EPD c9 opcode (c9 "1-0";)
+func ParseTuningPosition(line string, precomputedData *PrecomputedData) (pos TuningPosition, err error) {
+	cleaner := strings.NewReplacer(";", " ", "\"", " ", "[", " ", "]", " ")
+	fields := strings.Fields(cleaner.Replace(line))
+	if len(fields) < 4 {
+		return TuningPosition{}, fmt.Errorf("a position needs at least 4 fen fields")
+	}
+
+	fenFields := fields[:4]
+	rest := fields[4:]
+	if len(rest) >= 2 && isTuningNumber(rest[0]) && isTuningNumber(rest[1]) {
+		fenFields = fields[:6]
+		rest = fields[6:]
+	} else {
+		fenFields = append(fenFields, "0", "1")
+	}
+
+	// The result is the last recognized token, so that EPD opcodes before it are skipped
+	found := false
+	for _, field := range rest {
+		if result, ok := tuningResults[field]; ok {
+			pos.Result = result
+			found = true
+		}
+	}
+	if !found {
+		return TuningPosition{}, fmt.Errorf("missing game result")
+	}
+
+	pos.FEN = strings.Join(fenFields, " ")
+
+	// The fen parser panics on invalid input
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	pos.position = parseFEN(pos.FEN, precomputedData)
+
+	return pos, nil
+}
+
+func isTuningNumber(field string) bool {
+	_, err := strconv.Atoi(field)
+	return err == nil
+}
+
+// LoadTuningPositions reads a labeled position set with one position per line,
+// empty lines and lines starting with # are skipped
+func LoadTuningPositions(path string) ([]TuningPosition, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	precomputedData := sharedPrecomputedData()
+
+	positions := []TuningPosition{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pos, err := ParseTuningPosition(line, precomputedData)
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: %w", path, lineNumber, err)
+		}
+		positions = append(positions, pos)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return positions, nil
+}
+
+// tuningSample is a quiet position used to measure the evaluation error
+type tuningSample struct {
+	position Position
+	result   float64
+}
+
+// Tuner optimizes the evaluation parameters with the Texel tuning method: the static evaluation
+// of quiet positions is mapped to an expected score with a sigmoid and the parameters are changed
+// one at a time to minimize the mean squared error against the results of the games
+type Tuner struct {
+	// Params are the parameters being tuned, they are modified in place
+	Params *EvalParams
+	// K is the scaling constant of the sigmoid, it should be fitted with FitK before tuning
+	K float64
+
+	training   []tuningSample
+	validation []tuningSample
+	// values points to all the integer weights contained in Params
+	values []*int
+	// step is the change tried on each weight, it is halved when a pass doesn't improve the error
+	step          int
+	trainingError float64
+	// engines are used to evaluate the samples, one for each thread
+	engines []*BruteForceEngine
+}
+
+// tuningInitialStep is the first change tried on each weight by the local search
+const tuningInitialStep = 8
+
+// NewTuner creates a tuner for the passed parameters. The positions are resolved with a quiescent
+// search using the initial parameters, then the positions whose game ended are dropped and
+// validationRatio of the remaining ones are kept aside to measure the validation error
+func NewTuner(params *EvalParams, positions []TuningPosition, validationRatio float64, threads int) *Tuner {
+	if threads < 1 {
+		threads = 1
+	}
+
+	tuner := Tuner{Params: params, K: 1, values: params.tunableValues(), step: tuningInitialStep}
+
+	game := NewGame()
+	for i := 0; i < threads; i++ {
+		eng := NewBruteForceEngine(&game)
+		eng.EvalParams = params
+		// The pawn table would keep the evaluations of the parameters before each change
+		eng.PawnHashTableEnabled = false
+		tuner.engines = append(tuner.engines, eng)
+	}
+
+	samples := make([]*tuningSample, len(positions))
+	evaluations := &(ZobristTable{})
+	quiescentEvaluations := &(ZobristTable{})
+	tuner.parallel(len(positions), func(eng *BruteForceEngine, i int) {
+		if quiet, ok := eng.quietPosition(positions[i].position, evaluations, quiescentEvaluations); ok {
+			samples[i] = &tuningSample{quiet, positions[i].Result}
+		}
+	})
+
+	// Every sample for which the expected count of validation samples reaches a new integer
+	// is used for validation, which spreads them evenly over the set
+	count := 0
+	for _, sample := range samples {
+		if sample == nil {
+			continue
+		}
+
+		if int(float64(count+1)*validationRatio) > int(float64(count)*validationRatio) {
+			tuner.validation = append(tuner.validation, *sample)
+		} else {
+			tuner.training = append(tuner.training, *sample)
+		}
+		count++
+	}
+
+	tuner.trainingError = tuner.meanSquaredError(tuner.training)
+
+	return &tuner
+}
+
+// quietPosition returns the position reached playing the principal variation of the quiescent
+// search from the passed position, it returns false if the game ends in the quiet position
+func (eng *BruteForceEngine) quietPosition(pos Position, evaluations *ZobristTable, quiescentEvaluations *ZobristTable) (Position, bool) {
+	eng.game.setPosition(pos)
+
+	_, line := eng.quiescentSearch(7, -Infinity, Infinity, evaluations, quiescentEvaluations)
+
+	// The main line is stored from the last move to the first
+	for i := len(line) - 1; i >= 0; i-- {
+		eng.game.Move(line[i])
+	}
+	if eng.game.Result() != NoResult {
+		return Position{}, false
+	}
+
+	quiet := *eng.game.position
+	quiet.legalMoves = nil
+
+	return quiet, true
+}
+
+// parallel calls f for all the indices from 0 to n-1 splitting them between the engines
+func (t *Tuner) parallel(n int, f func(eng *BruteForceEngine, i int)) {
+	var wg sync.WaitGroup
+
+	for thread, eng := range t.engines {
+		wg.Add(1)
+		go func(thread int, eng *BruteForceEngine) {
+			defer wg.Done()
+
+			for i := thread; i < n; i += len(t.engines) {
+				f(eng, i)
+			}
+		}(thread, eng)
+	}
+
+	wg.Wait()
+}
+
+// TrainingSize returns the number of positions used to tune the parameters
+func (t *Tuner) TrainingSize() int {
+	return len(t.training)
+}
+
+// ValidationSize returns the number of positions used to validate the parameters
+func (t *Tuner) ValidationSize() int {
+	return len(t.validation)
+}
+
+// TrainingError returns the mean squared error of the current parameters on the training positions
+func (t *Tuner) TrainingError() float64 {
+	return t.trainingError
+}
+
+// ValidationError returns the mean squared error of the current parameters on the validation positions
+func (t *Tuner) ValidationError() float64 {
+	return t.meanSquaredError(t.validation)
+}
+
+// Converged returns true when changing any weight by one doesn't improve the training error
+func (t *Tuner) Converged() bool {
+	return t.step == 0
+}
+
+// expectedScore maps an evaluation in 256th of a pawn to the expected score of the game for white
+func expectedScore(evaluation int, k float64) float64 {
+	centipawns := float64(evaluation) * 100 / 256
+	return 1 / (1 + math.Pow(10, -k*centipawns/400))
+}
+
+// evaluations returns the static evaluation of the samples from white's point of view
+func (t *Tuner) evaluations(samples []tuningSample) []int {
+	evaluations := make([]int, len(samples))
+	t.parallel(len(samples), func(eng *BruteForceEngine, i int) {
+		pos := &samples[i].position
+		evaluations[i] = eng.evaluate(pos, eng.game.precomputedData, Standard, gamePhase(pos), nil)
+	})
+
+	return evaluations
+}
+
+func (t *Tuner) meanSquaredError(samples []tuningSample) float64 {
+	if len(samples) == 0 {
+		return 0
+	}
+
+	return tuningError(samples, t.evaluations(samples), t.K)
+}
+
+func tuningError(samples []tuningSample, evaluations []int, k float64) float64 {
+	sum := 0.0
+	for i, sample := range samples {
+		difference := sample.result - expectedScore(evaluations[i], k)
+		sum += difference * difference
+	}
+
+	return sum / float64(len(samples))
+}
+
+// FitK sets K to the value minimizing the training error of the current parameters,
+// scanning intervals around the best value found with increasing precision
+func (t *Tuner) FitK() float64 {
+	if len(t.training) == 0 {
+		return t.K
+	}
+
+	evaluations := t.evaluations(t.training)
+	low, high := 0.0, 10.0
+	best := t.K
+	bestError := tuningError(t.training, evaluations, best)
+
+	for round := 0; round < 6; round++ {
+		step := (high - low) / 20
+		for k := low; k <= high; k += step {
+			if err := tuningError(t.training, evaluations, k); err < bestError {
+				best, bestError = k, err
+			}
+		}
+
+		low, high = math.Max(0, best-step), best+step
+	}
+
+	t.K = best
+	t.trainingError = bestError
+
+	return best
+}
+
+// Iterate runs a pass of the local search, trying to increase and decrease each weight by the
+// current step and keeping the changes which reduce the training error. When no weight is
+// changed the step is halved for the next pass. Returns the number of changed weights
+func (t *Tuner) Iterate() int {
+	if t.Converged() {
+		return 0
+	}
+
+	changed := 0
+	for _, value := range t.values {
+		original := *value
+
+		for _, candidate := range []int{original + t.step, original - t.step} {
+			*value = candidate
+			if err := t.meanSquaredError(t.training); err < t.trainingError {
+				t.trainingError = err
+				changed++
+				break
+			}
+
+			*value = original
+		}
+	}
+
+	if changed == 0 {
+		t.step /= 2
+	}
+
+	return changed
+}
+
+// tunableValues returns pointers to all the integer weights of the parameters
+func (params *EvalParams) tunableValues() []*int {
+	values := []*int{}
+
+	var collect func(value reflect.Value)
+	collect = func(value reflect.Value) {
+		switch value.Kind() {
+		case reflect.Int:
+			values = append(values, value.Addr().Interface().(*int))
+		case reflect.Struct:
+			for i := 0; i < value.NumField(); i++ {
+				collect(value.Field(i))
+			}
+		case reflect.Array:
+			for i := 0; i < value.Len(); i++ {
+				collect(value.Index(i))
+			}
+		default:
+			panic(fmt.Sprintf("Unsupported evaluation parameter kind %s", value.Kind()))
+		}
+	}
+	collect(reflect.ValueOf(params).Elem())
+
+	return values
+}
@@ -0,0 +1,121 @@
+package chessboard
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseTuningPosition(t *testing.T) {
+	game := NewGame()
+
+	var tests = []struct {
+		line   string
+		fen    string
+		result float64
+	}{
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1 [1.0]", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", 1},
+		{"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1; 1/2-1/2", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", 0.5},
+		{"8/5k2/8/8/8/8/2K1R3/8 w - - 12 60 0-1", "8/5k2/8/8/8/8/2K1R3/8 w - - 12 60", 0},
+		{"8/5k2/8/8/8/8/2K1R3/8 w - - [0.5]", "8/5k2/8/8/8/8/2K1R3/8 w - - 0 1", 0.5},
+		{`8/5k2/8/8/8/8/2K1R3/8 w - - id "test 1"; c9 "1-0";`, "8/5k2/8/8/8/8/2K1R3/8 w - - 0 1", 1},
+	}
+
+	for _, test := range tests {
+		pos, err := ParseTuningPosition(test.line, game.precomputedData)
+		if err != nil {
+			t.Errorf("Parsing %s should succeed, %v was returned instead", test.line, err)
+			continue
+		}
+
+		if pos.FEN != test.fen {
+			t.Errorf("The fen of %s should be %s, %s was returned instead", test.line, test.fen, pos.FEN)
+		}
+		if pos.Result != test.result {
+			t.Errorf("The result of %s should be %v, %v was returned instead", test.line, test.result, pos.Result)
+		}
+		expected := NewGameFromFEN(test.fen)
+		if pos.position.hash != expected.position.hash || pos.position.board != expected.position.board {
+			t.Errorf("The position of %s should be the one of the fen", test.line)
+		}
+	}
+
+	for _, line := range []string{
+		"8/5k2/8/8/8/8/2K1R3/8 w - - 0 1",
+		"8/5k2/8/8/8/8/2K1R3/8 x - - 0 1 1-0",
+		"8/5k2 w 1-0",
+	} {
+		if _, err := ParseTuningPosition(line, game.precomputedData); err == nil {
+			t.Errorf("Parsing %s should fail, no error was returned instead", line)
+		}
+	}
+}
+
+func TestLoadTuningPositions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "positions.epd")
+	data := "# Test positions\n\n8/5k2/8/8/8/8/2K1R3/8 w - - [1.0]\n8/5k2/8/8/8/8/2K1R3/8 w - - oops\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadTuningPositions(path)
+	if err == nil || err.Error() != path+": line 4: missing game result" {
+		t.Errorf("Loading the positions should fail on line 4, %v was returned instead", err)
+	}
+}
+
+func TestTuner(t *testing.T) {
+	game := NewGame()
+	lines := []string{
+		// White can win the queen, so the quiet position is a winning one
+		"4k3/8/8/3q4/8/8/8/3RK3 w - - [1.0]",
+		"4k3/8/8/8/8/8/3Q4/4K3 w - - [1.0]",
+		"4k3/3q4/8/8/8/8/8/4K3 b - - [0.0]",
+		"4k3/8/8/8/8/8/8/R3K3 b - - [1.0]",
+		"r3k3/8/8/8/8/8/8/4K3 w - - [0.0]",
+		"4k3/pppp4/8/8/8/8/PPPP4/4K3 w - - [0.5]",
+		"4k3/pppp4/8/8/8/8/PPP5/4K3 w - - [0.5]",
+		"4k3/ppp5/8/8/8/8/PPPP4/4K3 b - - [0.5]",
+		// Checkmated positions are dropped
+		"R3k3/8/4K3/8/8/8/8/8 b - - [1.0]",
+	}
+
+	positions := []TuningPosition{}
+	for _, line := range lines {
+		pos, err := ParseTuningPosition(line, game.precomputedData)
+		if err != nil {
+			t.Fatalf("Parsing %s should succeed, %v was returned instead", line, err)
+		}
+		positions = append(positions, pos)
+	}
+
+	params := DefaultEvalParams()
+	tuner := NewTuner(params, positions, 0.25, 2)
+
+	if tuner.TrainingSize() != 6 || tuner.ValidationSize() != 2 {
+		t.Errorf("The tuner should have 6 training and 2 validation positions, %d and %d were returned instead", tuner.TrainingSize(), tuner.ValidationSize())
+	}
+	if board := tuner.training[0].position.board; board.bbBlackQueen != 0 || board.bbWhiteRook == 0 {
+		t.Errorf("The quiet position should be reached after the rook captures the queen")
+	}
+
+	initialError := tuner.TrainingError()
+	k := tuner.FitK()
+	if k <= 0 {
+		t.Errorf("K should be positive, %v was returned instead", k)
+	}
+	if tuner.TrainingError() > initialError {
+		t.Errorf("Fitting K should not increase the training error %v, %v was returned instead", initialError, tuner.TrainingError())
+	}
+
+	fittedError := tuner.TrainingError()
+	if changed := tuner.Iterate(); changed == 0 {
+		t.Errorf("The first tuning pass should change some parameters")
+	}
+	if tuner.TrainingError() >= fittedError {
+		t.Errorf("Tuning should reduce the training error %v, %v was returned instead", fittedError, tuner.TrainingError())
+	}
+	if *params == *DefaultEvalParams() {
+		t.Errorf("Tuning should modify the passed parameters")
+	}
+}
@@ -0,0 +1,104 @@
+package chessboard
+
+import "fmt"
+
+// Variant contains the rules which change between chess variants, the standard
+// rules apply to everything a variant doesn't change
+type Variant interface {
+	// Name returns the name of the variant, as used by the UCI_Variant option
+	Name() string
+	// StartFEN returns the fen of the starting position of the variant
+	StartFEN() string
+
+	// setup adapts a position parsed from a fen to the rules of the variant
+	setup(pos *Position)
+	// royalKing returns whether the king can't be left in check, otherwise it's a normal piece
+	royalKing() bool
+	// filterMoves turns the moves legal with the standard rules into the legal moves of the variant
+	filterMoves(pos *Position, moves []*Move) []*Move
+	// result returns the result of the game in the current position
+	result(game *Game) Result
+	// evaluate returns the evaluation terms of the variant for each side, when replace is
+	// true they are the whole evaluation, otherwise they are added to the standard one
+	evaluate(pos *Position, params *EvalParams) (white TaperedScore, black TaperedScore, replace bool)
+}
+
+var (
+	// Standard is the standard chess, which also covers Chess960
+	Standard Variant = standardVariant{}
+	// KingOfTheHill is won by checkmate or by bringing the king to one of the four central squares
+	KingOfTheHill Variant = kingOfTheHillVariant{}
+	// ThreeCheck is won by checkmate or by giving check three times
+	ThreeCheck Variant = threeCheckVariant{}
+	// Horde is played by white with 36 pawns and no king, which wins by checkmate,
+	// against black which wins by capturing all the white pieces
+	Horde Variant = hordeVariant{}
+	// Antichess is won by losing all the pieces or being stalemated, captures are mandatory
+	// and the king is a normal piece
+	Antichess Variant = antichessVariant{}
+	// Crazyhouse lets the players drop the captured pieces back on the board as their own
+	Crazyhouse Variant = crazyhouseVariant{}
+	// Atomic is won by exploding the enemy king: captures explode all the pieces except
+	// the pawns next to the captured one, together with the capturing piece
+	Atomic Variant = atomicVariant{}
+)
+
+// Variants contains all the supported variants
+var Variants = []Variant{Standard, KingOfTheHill, ThreeCheck, Horde, Antichess, Crazyhouse, Atomic}
+
+// variantAliases are the other names used for the variants by GUIs and servers
+var variantAliases = map[string]Variant{
+	"standard":   Standard,
+	"threecheck": ThreeCheck,
+	"giveaway":   Antichess,
+	// Chess960 and the games from a custom position use the standard rules
+	"chess960":     Standard,
+	"fromposition": Standard,
+}
+
+// VariantByName returns the variant with the passed name
+func VariantByName(name string) (Variant, error) {
+	for _, variant := range Variants {
+		if variant.Name() == name {
+			return variant, nil
+		}
+	}
+	if variant, ok := variantAliases[name]; ok {
+		return variant, nil
+	}
+
+	return nil, fmt.Errorf("unknown variant %s", name)
+}
+
+// Variant returns the variant of the game
+func (game *Game) Variant() Variant {
+	return game.variant
+}
+
+type standardVariant struct{}
+
+func (standardVariant) Name() string {
+	return "chess"
+}
+
+func (standardVariant) StartFEN() string {
+	return startingPositionFEN
+}
+
+func (standardVariant) setup(pos *Position) {}
+
+func (standardVariant) royalKing() bool {
+	return true
+}
+
+func (standardVariant) filterMoves(pos *Position, moves []*Move) []*Move {
+	return moves
+}
+
+func (standardVariant) result(game *Game) Result {
+	return game.standardResult()
+}
+
+func (standardVariant) evaluate(pos *Position, params *EvalParams) (TaperedScore, TaperedScore, bool) {
+	return TaperedScore{}, TaperedScore{}, false
+}
@@ -0,0 +1,78 @@
+package chessboard
+
+// antichessPieceValue is the penalty for each piece still on the board in antichess
+var antichessPieceValue = TaperedScore{256, 256}
+
+type antichessVariant struct{}
+
+func (antichessVariant) Name() string {
+	return "antichess"
+}
+
+func (antichessVariant) StartFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"
+}
+
+// setup removes the castle rights, castling isn't allowed in antichess
+func (antichessVariant) setup(pos *Position) {
+	for i, right := range []bool{pos.castleRights.WhiteKingSide, pos.castleRights.WhiteQueenSide,
+		pos.castleRights.BlackKingSide, pos.castleRights.BlackQueenSide} {
+		if right {
+			pos.hash ^= castleRightHash(i)
+		}
+	}
+
+	pos.castleRights = CastleRights{}
+	pos.inCheck = false
+}
+
+func (antichessVariant) royalKing() bool {
+	return false
+}
+
+// filterMoves adds the promotions to king and keeps only the captures when there are any
+func (antichessVariant) filterMoves(pos *Position, moves []*Move) []*Move {
+	captures := make([]*Move, 0, len(moves))
+	for _, move := range moves {
+		switch move.Promotion() {
+		case WhiteQueen:
+			king := *move&^promotionMask | Move(WhiteKing)<<12
+			moves = append(moves, &king)
+		case BlackQueen:
+			king := *move&^promotionMask | Move(BlackKing)<<12
+			moves = append(moves, &king)
+		}
+	}
+
+	for _, move := range moves {
+		if move.IsEnPassant() || pos.board.Piece(move.To()) != NoPiece {
+			captures = append(captures, move)
+		}
+	}
+
+	if len(captures) > 0 {
+		return captures
+	}
+	return moves
+}
+
+// result makes the side without moves win, either because it has no pieces left or because it's stalemated
+func (antichessVariant) result(game *Game) Result {
+	// Draw by 75 moves rule
+	if game.position.halfMoveClock >= 75 {
+		return Draw
+	}
+
+	if len(game.LegalMoves()) == 0 {
+		return VariantWin
+	}
+
+	return NoResult
+}
+
+// evaluate replaces the standard evaluation with a penalty for each piece left,
+// because the goal is losing all of them
+func (antichessVariant) evaluate(pos *Position, params *EvalParams) (TaperedScore, TaperedScore, bool) {
+	return antichessPieceValue.mul(-pos.board.whiteSquares.PopCount()),
+		antichessPieceValue.mul(-pos.board.blackSquares.PopCount()), true
+}
@@ -0,0 +1,47 @@
+package chessboard
+
+type atomicVariant struct{}
+
+func (atomicVariant) Name() string {
+	return "atomic"
+}
+
+func (atomicVariant) StartFEN() string {
+	return startingPositionFEN
+}
+
+// setup makes the captures explode, the check computed with the standard rules is removed
+// when the kings are next to each other, because neither of them can be captured
+func (atomicVariant) setup(pos *Position) {
+	pos.board.explosions = true
+
+	if pos.board.bbWhiteKing != 0 && adjacentSquares(pos.board.whiteKingSquare)&pos.board.bbBlackKing != 0 {
+		pos.inCheck = false
+	}
+}
+
+func (atomicVariant) royalKing() bool {
+	return true
+}
+
+// filterMoves removes all the moves once a king has exploded, because the game is over
+func (atomicVariant) filterMoves(pos *Position, moves []*Move) []*Move {
+	if pos.board.bbWhiteKing == 0 || pos.board.bbBlackKing == 0 {
+		return []*Move{}
+	}
+
+	return moves
+}
+
+func (atomicVariant) result(game *Game) Result {
+	// The moves exploding the own king are illegal, so only the king of the side to move can be missing
+	if game.position.board.sidePieces(game.position.turn).king == 0 {
+		return VariantLoss
+	}
+
+	return game.movesResult()
+}
+
+func (atomicVariant) evaluate(pos *Position, params *EvalParams) (TaperedScore, TaperedScore, bool) {
+	return TaperedScore{}, TaperedScore{}, false
+}
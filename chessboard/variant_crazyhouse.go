@@ -0,0 +1,52 @@
+package chessboard
+
+// maxPocketCount is the maximum number of pieces of the same type in a crazyhouse pocket,
+// a side can capture at most the 16 pieces of the opponent
+const maxPocketCount = 16
+
+// pocketBonus is the bonus given to a piece in the pocket over the same piece on the board,
+// indexed by piece type from the king to the pawn. A piece in hand can be dropped on any square
+// and its value grows in the middle game, when the drops lead to attacks on the king
+var pocketBonus = [6]TaperedScore{{0, 0}, {96, 32}, {64, 16}, {48, 16}, {64, 16}, {32, 0}}
+
+type crazyhouseVariant struct{}
+
+func (crazyhouseVariant) Name() string {
+	return "crazyhouse"
+}
+
+func (crazyhouseVariant) StartFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR[] w KQkq - 0 1"
+}
+
+// setup starts using the pockets in positions parsed from fens without them
+func (crazyhouseVariant) setup(pos *Position) {
+	pos.hasPockets = true
+}
+
+func (crazyhouseVariant) royalKing() bool {
+	return true
+}
+
+func (crazyhouseVariant) filterMoves(pos *Position, moves []*Move) []*Move {
+	return moves
+}
+
+func (crazyhouseVariant) result(game *Game) Result {
+	// There are no draws by insufficient material, the captured pieces can always be dropped back
+	return game.movesResult()
+}
+
+// evaluate adds the material in the pockets, which isn't on the board
+func (crazyhouseVariant) evaluate(pos *Position, params *EvalParams) (TaperedScore, TaperedScore, bool) {
+	values := [6]TaperedScore{{0, 0}, params.QueenValue, params.RookValue, params.BishopValue, params.KnightValue, params.PawnValue}
+
+	scores := [2]TaperedScore{}
+	for side := range pos.pockets {
+		for kind, count := range pos.pockets[side] {
+			scores[side] = scores[side].add(values[kind].add(pocketBonus[kind]).mul(count))
+		}
+	}
+
+	return scores[0], scores[1], false
+}
@@ -0,0 +1,52 @@
+package chessboard
+
+// hordePawnAdvancement is the bonus given to the white pawns for each rank they advanced in horde
+var hordePawnAdvancement = TaperedScore{6, 12}
+
+type hordeVariant struct{}
+
+func (hordeVariant) Name() string {
+	return "horde"
+}
+
+func (hordeVariant) StartFEN() string {
+	return "rnbqkbnr/pppppppp/8/1PP2PP1/PPPPPPPP/PPPPPPPP/PPPPPPPP/PPPPPPPP w kq - 0 1"
+}
+
+func (hordeVariant) setup(pos *Position) {}
+
+func (hordeVariant) royalKing() bool {
+	return true
+}
+
+// filterMoves keeps the standard moves, the white pawns on the first rank can already
+// move by two squares because the double push is allowed below the third rank
+func (hordeVariant) filterMoves(pos *Position, moves []*Move) []*Move {
+	return moves
+}
+
+// result makes black win when all the white pieces are captured, the insufficient material
+// rule doesn't apply because a single white pawn can still promote and mate
+func (hordeVariant) result(game *Game) Result {
+	if game.position.board.whiteSquares == 0 {
+		if game.position.turn == WhiteColor {
+			return VariantLoss
+		}
+		return VariantWin
+	}
+
+	return game.movesResult()
+}
+
+// evaluate replaces the standard evaluation, which relies on both kings being on the board,
+// with the material and the advancement of the white pawns
+func (hordeVariant) evaluate(pos *Position, params *EvalParams) (TaperedScore, TaperedScore, bool) {
+	white, black := material(pos, params)
+
+	for pawns := pos.board.bbWhitePawn; pawns != 0; pawns.ClearLeastSignificant1Bit() {
+		rank := relativeRank(Square(pawns.LeastSignificant1Bit()), WhiteColor)
+		white = white.add(hordePawnAdvancement.mul(rank))
+	}
+
+	return white, black, true
+}
@@ -0,0 +1,65 @@
+package chessboard
+
+// hillBitboard contains the four central squares the kings race to in king of the hill
+const hillBitboard = Bitboard(1<<D4 | 1<<E4 | 1<<D5 | 1<<E5)
+
+// hillBonus is the bonus given to a king by its number of moves from the hill
+var hillBonus = [8]TaperedScore{
+	{0, 0}, {384, 640}, {160, 320}, {48, 128}, {0, 32}, {0, 0}, {0, 0}, {0, 0},
+}
+
+type kingOfTheHillVariant struct{}
+
+func (kingOfTheHillVariant) Name() string {
+	return "kingofthehill"
+}
+
+func (kingOfTheHillVariant) StartFEN() string {
+	return startingPositionFEN
+}
+
+func (kingOfTheHillVariant) setup(pos *Position) {}
+
+func (kingOfTheHillVariant) royalKing() bool {
+	return true
+}
+
+// filterMoves removes all the moves once a king is on the hill, because the game is over
+func (kingOfTheHillVariant) filterMoves(pos *Position, moves []*Move) []*Move {
+	if (pos.board.bbWhiteKing|pos.board.bbBlackKing)&hillBitboard != 0 {
+		return []*Move{}
+	}
+
+	return moves
+}
+
+// result ends the game as soon as a king reaches the hill, there are no legal moves afterwards
+func (kingOfTheHillVariant) result(game *Game) Result {
+	if game.position.board.sidePieces(game.position.turn.Other()).king&hillBitboard != 0 {
+		return VariantLoss
+	}
+	if game.position.board.sidePieces(game.position.turn).king&hillBitboard != 0 {
+		return VariantWin
+	}
+
+	// There are no draws by insufficient material, a lone king can still reach the hill
+	return game.movesResult()
+}
+
+// evaluate rewards the kings close to the hill
+func (kingOfTheHillVariant) evaluate(pos *Position, params *EvalParams) (TaperedScore, TaperedScore, bool) {
+	return hillBonus[hillDistance(pos.board.kingSquare(WhiteColor))],
+		hillBonus[hillDistance(pos.board.kingSquare(BlackColor))], false
+}
+
+// hillDistance returns the number of king moves needed to reach the hill from the square
+func hillDistance(sq Square) int {
+	distance := 7
+	for hill := hillBitboard; hill != 0; hill.ClearLeastSignificant1Bit() {
+		if d := squareDistance(sq, Square(hill.LeastSignificant1Bit())); d < distance {
+			distance = d
+		}
+	}
+
+	return distance
+}
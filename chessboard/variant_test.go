@@ -0,0 +1,214 @@
+package chessboard
+
+import (
+	"io"
+	"testing"
+)
+
+func TestVariantPerft(t *testing.T) {
+	var tests = []struct {
+		variant Variant
+		fen     string
+		nodes   []int
+	}{
+		{KingOfTheHill, KingOfTheHill.StartFEN(), []int{20, 400, 8902}},
+		// Moving the king to d4 or e4 ends the game
+		{KingOfTheHill, "8/8/8/8/8/3K4/8/k7 w - - 0 1", []int{8, 15}},
+		{ThreeCheck, ThreeCheck.StartFEN(), []int{20, 400, 8902}},
+		// The checks given by black on the second ply end the game
+		{ThreeCheck, "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 1+1 0 1", []int{48, 2039, 97848}},
+		{Horde, Horde.StartFEN(), []int{8, 128, 1274, 23310}},
+		{Horde, "4k3/pp4q1/3P2p1/8/P3PP2/PPP2r2/PPP5/PPPP4 b - - 0 1", []int{30, 241, 6633}},
+		{Antichess, Antichess.StartFEN(), []int{20, 400, 8067}},
+		// Captures are mandatory and the side without pieces has no moves
+		{Antichess, "8/1p6/8/8/8/8/P7/8 w - - 0 1", []int{2, 4, 4, 3, 1, 0}},
+		{Crazyhouse, Crazyhouse.StartFEN(), []int{20, 400, 8902, 197281, 4888832}},
+		// Pawns can't be dropped on the first and last rank
+		{Crazyhouse, "2k5/8/8/8/8/8/8/4K3[QRBNPqrbnp] w - - 0 1", []int{301, 75353}},
+		// Only the drops blocking the check are legal
+		{Crazyhouse, "4k3/8/8/8/8/8/8/r3K3[N] w - - 0 1", []int{6}},
+		{Atomic, Atomic.StartFEN(), []int{20, 400, 8902, 197326}},
+		{Atomic, "rn1qkb1r/p5pp/2p5/3p4/N3P3/5P2/PPP4P/R1BQK3 w Qkq - 0 1", []int{28, 833, 23353, 714499}},
+		// The king can castle next to the enemy king even if the destination is attacked
+		{Atomic, "8/8/8/8/8/8/2k5/rR4KR w KQ - 0 1", []int{18, 180, 4364}},
+	}
+
+	for _, test := range tests {
+		game := NewVariantGameFromFEN(test.variant, test.fen)
+		for depth, expected := range test.nodes {
+			if nodes := game.Perft(depth + 1); nodes != expected {
+				t.Errorf("%s perft %d of %s should be %d, %d was returned instead", test.variant.Name(), depth+1, test.fen, expected, nodes)
+			}
+		}
+	}
+}
+
+func TestVariantResult(t *testing.T) {
+	var tests = []struct {
+		variant Variant
+		fen     string
+		result  Result
+	}{
+		{Standard, "8/8/8/3K4/8/8/8/k6R b - - 0 1", NoResult},
+		{KingOfTheHill, "8/8/8/3K4/8/8/8/k6R b - - 0 1", VariantLoss},
+		{KingOfTheHill, "8/8/8/2K5/8/8/8/k6R b - - 0 1", NoResult},
+		{ThreeCheck, "4k3/8/8/8/8/8/8/4K2R b - - 1+3 0 1", NoResult},
+		{ThreeCheck, "4k3/8/8/8/8/8/8/4K2R b - - 0+3 0 1", VariantLoss},
+		{ThreeCheck, "4k3/8/8/8/8/8/8/4K2R b - - 3+0 0 1", VariantWin},
+		{ThreeCheck, "4k3/8/8/8/8/8/8/4K2R b - - 0 1 +1+3", VariantWin},
+		{Horde, "4k3/8/8/8/8/8/8/8 w - - 0 1", VariantLoss},
+		{Horde, "4k3/8/8/8/8/8/8/P7 b - - 0 1", NoResult},
+		{Antichess, "4k3/8/8/8/8/8/8/8 w - - 0 1", VariantWin},
+		// The stalemated side wins
+		{Antichess, "4k3/8/8/8/8/8/P7/8 w - - 0 1", NoResult},
+		{Antichess, "4k3/8/8/8/p7/P7/8/8 w - - 0 1", VariantWin},
+		{Atomic, "rnbq3r/1pppp1pp/8/p7/8/8/PPPPPPPP/RNBQKB1R b KQ - 0 3", VariantLoss},
+		// The kings next to each other can't be in check
+		{Atomic, "8/8/8/8/8/8/1k6/rK6 w - - 0 1", NoResult},
+	}
+
+	for _, test := range tests {
+		game := NewVariantGameFromFEN(test.variant, test.fen)
+		if result := game.Result(); result != test.result {
+			t.Errorf("The %s result of %s should be %v, %v was returned instead", test.variant.Name(), test.fen, test.result, result)
+		}
+	}
+}
+
+func TestThreeCheckCounters(t *testing.T) {
+	game := NewVariantGame(ThreeCheck)
+	for _, uci := range []string{"e2e4", "f7f6", "d1h5", "g7g6"} {
+		move, err := game.ParseUCIMove(uci)
+		if err != nil {
+			t.Fatalf("%s should be legal, %v was returned instead", uci, err)
+		}
+		game.Move(move)
+	}
+
+	expected := "rnbqkbnr/ppppp2p/5pp1/7Q/4P3/8/PPPP1PPP/RNB1KBNR w KQkq - 2+3 0 5"
+	fen := game.position.FEN()
+	if fen != expected {
+		t.Errorf("The fen after a check should be %s, %s was returned instead", expected, fen)
+	}
+
+	parsed := NewVariantGameFromFEN(ThreeCheck, fen)
+	if parsed.position.hash != game.position.hash {
+		t.Errorf("The hash should include the checks given")
+	}
+	if standard := NewGameFromFEN("rnbqkbnr/ppppp2p/5pp1/7Q/4P3/8/PPPP1PPP/RNB1KBNR w KQkq - 0 5"); standard.position.hash == game.position.hash {
+		t.Errorf("The hash of a position with checks given should differ from the standard one")
+	}
+}
+
+func TestAntichessMoves(t *testing.T) {
+	game := NewVariantGameFromFEN(Antichess, "8/3P4/8/8/8/8/8/4k3 w - - 0 1")
+	promotions := map[string]bool{}
+	for _, move := range game.LegalMoves() {
+		promotions[move.UCI()] = true
+	}
+	if len(promotions) != 5 || !promotions["d7d8k"] {
+		t.Errorf("The pawn should be able to promote to any piece including the king, %v was returned instead", promotions)
+	}
+
+	game = NewVariantGameFromFEN(Antichess, "4k3/8/8/8/8/8/3r4/4K3 w KQkq - 0 1")
+	moves := game.LegalMoves()
+	if len(moves) != 1 || moves[0].UCI() != "e1d2" {
+		t.Errorf("The king should be forced to capture the rook, %v was returned instead", moves)
+	}
+	if game.position.inCheck || game.position.FEN() != "4k3/8/8/8/8/8/3r4/4K3 w - - 0 1" {
+		t.Errorf("Antichess positions should have no check and no castle rights")
+	}
+}
+
+func TestCrazyhousePockets(t *testing.T) {
+	game := NewVariantGameFromFEN(Crazyhouse, "1r6/P1k5/8/8/8/8/8/4K3[] w - - 0 1")
+
+	var tests = []struct {
+		move string
+		fen  string
+	}{
+		// The promoted queen is marked with a ~ and goes back to the pocket as a pawn
+		{"a7b8q", "1Q~6/2k5/8/8/8/8/8/4K3[R] b - - 0 2"},
+		{"c7b8", "1k6/8/8/8/8/8/8/4K3[Rp] w - - 0 3"},
+		{"R@b1", "1k6/8/8/8/8/8/8/1R2K3[p] b - - 1 4"},
+		{"P@b2", "1k6/8/8/8/8/8/1p6/1R2K3[] w - - 2 5"},
+	}
+
+	for _, test := range tests {
+		move, err := game.ParseUCIMove(test.move)
+		if err != nil {
+			t.Fatalf("%s should be a legal move in %s", test.move, game.position.FEN())
+		}
+		game.Move(move)
+
+		if fen := game.position.FEN(); fen != test.fen {
+			t.Errorf("The fen after %s should be %s, %s was returned instead", test.move, test.fen, fen)
+		}
+		parsed := NewVariantGameFromFEN(Crazyhouse, test.fen)
+		if game.position.Hash() != parsed.position.Hash() {
+			t.Errorf("The hash after %s should be the one of %s", test.move, test.fen)
+		}
+	}
+}
+
+func TestAtomicExplosions(t *testing.T) {
+	var tests = []struct {
+		start string
+		moves []string
+		fen   string
+	}{
+		// The knight capturing on f7 explodes the black king, bishop and knight
+		{Atomic.StartFEN(), []string{"g1f3", "a7a6", "f3g5", "a6a5", "g5f7"}, "rnbq3r/1pppp1pp/8/p7/8/8/PPPPPPPP/RNBQKB1R b KQ - 0 6"},
+		// The rook exploded next to the capture loses its castle right
+		{"r3k2r/8/8/8/8/8/7p/R3K1NR b KQkq - 0 1", []string{"h2g1q"}, "r3k2r/8/8/8/8/8/8/R3K3 w Qkq - 0 2"},
+	}
+
+	for _, test := range tests {
+		game := NewVariantGameFromFEN(Atomic, test.start)
+		for _, uciMove := range test.moves {
+			move, err := game.ParseUCIMove(uciMove)
+			if err != nil {
+				t.Fatalf("%s should be a legal move in %s", uciMove, game.position.FEN())
+			}
+			game.Move(move)
+		}
+
+		if fen := game.position.FEN(); fen != test.fen {
+			t.Errorf("The fen after %v should be %s, %s was returned instead", test.moves, test.fen, fen)
+		}
+		parsed := NewVariantGameFromFEN(Atomic, test.fen)
+		if game.position.Hash() != parsed.position.Hash() || game.position.PawnHash() != parsed.position.PawnHash() {
+			t.Errorf("The hashes after %v should be the ones of %s", test.moves, test.fen)
+		}
+	}
+}
+
+func TestVariantSearch(t *testing.T) {
+	var tests = []struct {
+		variant Variant
+		fen     string
+		move    string
+	}{
+		// The king reaching the hill wins immediately
+		{KingOfTheHill, "r5k1/5ppp/8/8/8/2K5/8/8 w - - 0 1", "c3d4"},
+		// The third check wins even without mate
+		{ThreeCheck, "6k1/5pp1/8/8/8/8/8/4K2R w - - 1+3 0 1", "h1h8"},
+		// Giving away the last piece wins, because the capture is mandatory
+		{Antichess, "8/8/8/8/8/2p5/8/3R4 w - - 0 1", "d1d2"},
+		// Dropping the queen next to the king mates
+		{Crazyhouse, "k7/8/2K5/8/8/8/8/8[Q] w - - 0 1", "Q@b7"},
+		// The capture on f7 explodes the king
+		{Atomic, "rnbqkbnr/1ppppppp/8/p5N1/8/8/PPPPPPPP/RNBQKB1R w KQkq - 0 3", "g5f7"},
+	}
+
+	for _, test := range tests {
+		game := NewVariantGameFromFEN(test.variant, test.fen)
+		eng := NewBruteForceEngine(&game)
+		eng.MaxDepth = 3
+		eng.LogOutput = io.Discard
+
+		if move := eng.Analyze(10)[0].Move; move.UCI() != test.move {
+			t.Errorf("The best %s move in %s should be %s, %s was returned instead", test.variant.Name(), test.fen, test.move, move.UCI())
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package chessboard
+
+// maxChecks is the number of checks which wins a three-check game
+const maxChecks = 3
+
+// checksBonus is the bonus given to a side by the number of checks it has given
+var checksBonus = [maxChecks + 1]TaperedScore{{0, 0}, {192, 128}, {640, 512}, {0, 0}}
+
+type threeCheckVariant struct{}
+
+func (threeCheckVariant) Name() string {
+	return "3check"
+}
+
+func (threeCheckVariant) StartFEN() string {
+	return "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 3+3 0 1"
+}
+
+// setup starts counting the checks of positions parsed from fens without the checks field
+func (threeCheckVariant) setup(pos *Position) {
+	pos.countChecks = true
+}
+
+func (threeCheckVariant) royalKing() bool {
+	return true
+}
+
+// filterMoves removes all the moves once a side has given three checks, because the game is over
+func (threeCheckVariant) filterMoves(pos *Position, moves []*Move) []*Move {
+	if pos.checks[0] >= maxChecks || pos.checks[1] >= maxChecks {
+		return []*Move{}
+	}
+
+	return moves
+}
+
+func (threeCheckVariant) result(game *Game) Result {
+	own, opponent := game.position.checks[0], game.position.checks[1]
+	if game.position.turn == BlackColor {
+		own, opponent = opponent, own
+	}
+
+	if opponent >= maxChecks {
+		return VariantLoss
+	}
+	if own >= maxChecks {
+		return VariantWin
+	}
+
+	// There are no draws by insufficient material, a lone king can still be checked
+	return game.movesResult()
+}
+
+// evaluate rewards the checks already given, the third one ends the game
+func (threeCheckVariant) evaluate(pos *Position, params *EvalParams) (TaperedScore, TaperedScore, bool) {
+	return checksBonus[pos.checks[0]], checksBonus[pos.checks[1]], false
+}
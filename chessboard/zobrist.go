@@ -2,6 +2,8 @@ package chessboard
 
 import (
 	"math/rand"
+	"sync"
+	"sync/atomic"
 )
 
 type ZobristHash uint64
@@ -14,31 +16,69 @@ var (
 	zobristHashBlackTurn        ZobristHash = 0
 	zobristHashEnPassant        [8]ZobristHash
 	zobristHashMoves            [12][64]ZobristHash
+	// zobristHashChecks contains the keys of the checks given by white and black in three-check,
+	// no checks have no key so that the hash of the positions not counting the checks is unchanged
+	zobristHashChecks [2][maxChecks + 1]ZobristHash
+	// zobristHashPockets contains the keys of the number of pieces of each type in the crazyhouse
+	// pockets of white and black, empty pockets have no key like the checks
+	zobristHashPockets [2][6][maxPocketCount + 1]ZobristHash
 )
 
+var zobristHashesOnce sync.Once
+
+// initializeZobristHashes fills the random zobrist keys, the keys are generated
+// only once because games created concurrently share them
 func initializeZobristHashes() {
-	rand.Seed(1)
-	zobristHashWhiteKingCastle = ZobristHash(rand.Uint64())
-	zobristHashWhiteQueenCastle = ZobristHash(rand.Uint64())
-	zobristHashBlackKingCastle = ZobristHash(rand.Uint64())
-	zobristHashBlackQueenCastle = ZobristHash(rand.Uint64())
-	zobristHashBlackTurn = ZobristHash(rand.Uint64())
-
-	for i := 0; i < 12; i++ {
-		for j := 0; j < 64; j++ {
-			zobristHashMoves[i][j] = ZobristHash(rand.Uint64())
+	zobristHashesOnce.Do(func() {
+		// A private source keeps the keys deterministic without reseeding the global source
+		source := rand.New(rand.NewSource(1))
+
+		zobristHashWhiteKingCastle = ZobristHash(source.Uint64())
+		zobristHashWhiteQueenCastle = ZobristHash(source.Uint64())
+		zobristHashBlackKingCastle = ZobristHash(source.Uint64())
+		zobristHashBlackQueenCastle = ZobristHash(source.Uint64())
+		zobristHashBlackTurn = ZobristHash(source.Uint64())
+
+		for i := 0; i < 12; i++ {
+			for j := 0; j < 64; j++ {
+				zobristHashMoves[i][j] = ZobristHash(source.Uint64())
+			}
 		}
-	}
 
-	for i := 0; i < 8; i++ {
-		zobristHashEnPassant[i] = ZobristHash(rand.Uint64())
-	}
+		for i := 0; i < 8; i++ {
+			zobristHashEnPassant[i] = ZobristHash(source.Uint64())
+		}
+
+		for i := 0; i < 2; i++ {
+			for j := 1; j <= maxChecks; j++ {
+				zobristHashChecks[i][j] = ZobristHash(source.Uint64())
+			}
+		}
+
+		for i := 0; i < 2; i++ {
+			for j := 0; j < 6; j++ {
+				for k := 1; k <= maxPocketCount; k++ {
+					zobristHashPockets[i][j][k] = ZobristHash(source.Uint64())
+				}
+			}
+		}
+	})
 }
 
-// SetData sets the evaluation data in the zobrist hash for a given position
+// maxCachedDepth is the largest depth that fits in the 5 bits of the hash table entries
+const maxCachedDepth = 31
+
+// SetData sets the evaluation data in the zobrist hash for a given position.
+// The data uses the lowest 22 bits: 16 bits for the evaluation, 5 bits for the depth
+// and 1 bit for the lower bound flag. Deeper searches are stored with depth 31, which
+// only makes the entry usable by fewer searches
 func (h ZobristHash) SetData(evaluation int16, depth int8, lowerBound bool) ZobristHash {
-	h |= ZobristHash(evaluation << 7)
-	h |= ZobristHash(depth << 1)
+	if depth > maxCachedDepth {
+		depth = maxCachedDepth
+	}
+
+	h |= ZobristHash(uint16(evaluation)) << 6
+	h |= ZobristHash(depth) << 1 & depthMask
 
 	if lowerBound {
 		h |= 1
@@ -61,14 +101,14 @@ func (h ZobristHash) HashValue() ZobristHash {
 	return h << zobristCacheSize
 }
 
-const evaluationMask ZobristHash = 0b111111111111110000000
+const evaluationMask ZobristHash = 0b1111111111111111000000
 
 // Evaluation returns the evaluation encoded in the zobrist hash
 func (h ZobristHash) Evaluation() int {
-	return int((h & evaluationMask) >> 6)
+	return int(int16((h & evaluationMask) >> 6))
 }
 
-const depthMask ZobristHash = 0b1111110
+const depthMask ZobristHash = 0b111110
 
 // Depth returns the depth of the stored evaluation
 func (h ZobristHash) Depth() int {
@@ -80,31 +120,29 @@ func (h ZobristHash) LowerBound() bool {
 	return h&1 != 0
 }
 
-// ZobristTable is an HashTable using the zobrist hash algorithm
+// ZobristTable is an HashTable using the zobrist hash algorithm.
+// Each entry is a single 64 bit word containing both the verification bits and
+// the data, so the table can be shared between threads without locks: entries are
+// read and written atomically and a torn entry can never pass the verification
 type ZobristTable [1 << zobristCacheSize]ZobristHash
 
-var _zobristCacheHits = 0
-var _zobristCacheMisses = 0
-
 // Get gets an element from the table, returns a boolean value representing whether the
 // value was found and the value itself if found
 func (tb *ZobristTable) Get(hash ZobristHash) (bool, ZobristHash) {
 	key := hash.Key()
-	if tb[key] == 0 {
-		_zobristCacheMisses++
+	value := ZobristHash(atomic.LoadUint64((*uint64)(&tb[key])))
+	if value == 0 {
 		return false, 0
 	}
 
-	if (tb[key] >> zobristCacheSize) != (hash.HashValue() >> zobristCacheSize) {
-		_zobristCacheMisses++
+	if (value >> zobristCacheSize) != (hash.HashValue() >> zobristCacheSize) {
 		return false, 0
 	}
 
-	_zobristCacheHits++
-	return true, tb[key]
+	return true, value
 }
 
 // Set saves an hash in the table
 func (tb *ZobristTable) Set(key int32, hash ZobristHash) {
-	tb[key] = hash
+	atomic.StoreUint64((*uint64)(&tb[key]), uint64(hash))
 }
@@ -79,3 +79,89 @@ func BenchmarkZobristType2Collisions(b *testing.B) {
 
 	b.ReportMetric(float64(total), "type2collisions")
 }
+
+func checkPawnHashes(t *testing.T, game *Game, depth int) {
+	if expected := game.position.board.pawnHash(); game.position.PawnHash() != expected {
+		t.Errorf("Pawn hash of %s should be %d, %d was returned instead", game.position.FEN(), expected, game.position.PawnHash())
+	}
+
+	if depth == 0 {
+		return
+	}
+
+	for _, move := range game.LegalMoves() {
+		game.Move(move)
+		checkPawnHashes(t, game, depth-1)
+		game.UndoMove()
+	}
+}
+
+func TestPawnHash(t *testing.T) {
+	// Positions with captures of pawns, en passant and promotions
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"rnbqkb1r/ppp1p1pp/5n2/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 4",
+		"n1n5/PPPk4/8/8/8/8/4Kppp/5N1N b - - 0 1",
+	}
+
+	for _, fen := range fens {
+		game := NewGameFromFEN(fen)
+		checkPawnHashes(t, &game, 3)
+	}
+}
+
+func TestFENHashMatchesIncrementalHash(t *testing.T) {
+	var tests = []struct {
+		fen   string
+		moves []string
+	}{
+		{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []string{"e2e4", "b8c6", "g1f3", "e7e5", "f1c4", "f8c5", "d2d3", "c5f2", "e1f2", "g8f6"}},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []string{"e1g1", "b6d5", "e4d5", "h3g2", "f3g2", "b4c3"}},
+	}
+
+	for _, test := range tests {
+		game := NewGameFromFEN(test.fen)
+		for _, uciMove := range test.moves {
+			move, err := game.ParseUCIMove(uciMove)
+			if err != nil {
+				t.Fatalf("%s should be a legal move in %s", uciMove, game.position.FEN())
+			}
+			game.Move(move)
+
+			// The hash of the position parsed from the fen must match the incrementally updated one
+			fen := game.position.FEN()
+			parsed := NewGameFromFEN(fen)
+			if parsed.position.Hash() != game.position.Hash() {
+				t.Errorf("The hash of %s should be %v, %v was returned instead", fen, game.position.Hash(), parsed.position.Hash())
+			}
+			if parsed.position.PawnHash() != game.position.PawnHash() {
+				t.Errorf("The pawn hash of %s should be %v, %v was returned instead", fen, game.position.PawnHash(), parsed.position.PawnHash())
+			}
+		}
+	}
+}
+
+func TestZobristHashData(t *testing.T) {
+	var tests = []struct {
+		evaluation int16
+		depth      int8
+		lowerBound bool
+		expected   int
+	}{
+		{-150, 0, false, 0},
+		{32000, 31, true, 31},
+		// The depths that don't fit in 5 bits are clamped instead of wrapping around
+		{42, 32, false, 31},
+		{-42, 100, true, 31},
+	}
+
+	for _, test := range tests {
+		hash := ZobristHash(0x1234).HashValue().SetData(test.evaluation, test.depth, test.lowerBound)
+		if hash.Evaluation() != int(test.evaluation) || hash.Depth() != test.expected || hash.LowerBound() != test.lowerBound {
+			t.Errorf("Storing %d, %d, %t should return %d, %d, %t, %d, %d, %t were returned instead",
+				test.evaluation, test.depth, test.lowerBound, test.evaluation, test.expected, test.lowerBound,
+				hash.Evaluation(), hash.Depth(), hash.LowerBound())
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/ZaninAndrea/chess_engine/chessboard"
+)
+
+func main() {
+	games := flag.Int("games", 1000, "number of self-play games")
+	depth := flag.Int("depth", 4, "depth of the search of each move, 0 for no limit")
+	nodes := flag.Int("nodes", 0, "nodes of the search of each move, 0 for no limit")
+	randomPlies := flag.Int("random-plies", 8, "number of random moves starting each game")
+	maxPlies := flag.Int("max-plies", 400, "length after which a game is adjudicated as a draw")
+	threads := flag.Int("threads", runtime.NumCPU(), "number of games played at the same time")
+	seed := flag.Int64("seed", 1, "seed of the random openings, the same seed generates the same data")
+	paramsPath := flag.String("params", "", "evaluation parameters file, the defaults are used when empty")
+	networkPath := flag.String("nnue", "", "network file, the engines use the network evaluation when set")
+	outputPath := flag.String("output", "data.bin", "file where the positions are written")
+	convertPath := flag.String("convert", "", "binary data file converted to text in the output file instead of generating data")
+	flag.Parse()
+
+	if *convertPath != "" {
+		convert(*convertPath, *outputPath)
+		return
+	}
+
+	gen := chessboard.NewDataGenerator()
+	gen.Depth = *depth
+	gen.Nodes = *nodes
+	gen.RandomPlies = *randomPlies
+	gen.MaxPlies = *maxPlies
+	gen.Threads = *threads
+	gen.Seed = *seed
+
+	if *paramsPath != "" {
+		params, err := chessboard.LoadEvalParams(*paramsPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		gen.EvalParams = params
+	}
+	if *networkPath != "" {
+		net, err := chessboard.LoadNetwork(*networkPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		gen.Network = net
+	}
+
+	file, err := os.Create(*outputPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+
+	start := time.Now()
+	positions, err := gen.Generate(*games, file, func(games int, positions int) {
+		if games%100 == 0 {
+			fmt.Printf("%d games, %d positions in %v\n", games, positions, time.Since(start).Round(time.Second))
+		}
+	})
+	if err == nil {
+		err = file.Close()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("%d positions written to %s in %v\n", positions, *outputPath, time.Since(start).Round(time.Second))
+}
+
+// convert writes the binary data as text, one position per line
+func convert(inputPath string, outputPath string) {
+	input, err := os.Open(inputPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer input.Close()
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer output.Close()
+
+	count, err := chessboard.ConvertTrainingData(input, output)
+	if err == nil {
+		err = output.Close()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, inputPath+":", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%d positions written to %s\n", count, outputPath)
+}
@@ -4,7 +4,7 @@ go 1.16
 
 require (
 	github.com/gin-contrib/cors v1.3.1
-	github.com/gin-gonic/autotls v0.0.3 // indirect
+	github.com/gin-gonic/autotls v0.0.3
 	github.com/gin-gonic/gin v1.6.3
 	github.com/go-playground/validator/v10 v10.4.1 // indirect
 	github.com/golang/protobuf v1.4.3 // indirect
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"runtime"
 	"strconv"
 	"time"
 
@@ -38,12 +40,51 @@ func main() {
 			c.JSON(400, gin.H{
 				"error": "Invalid time passed",
 			})
+			return
 		}
 
+		// More threads than CPUs only slow down the search, and each one allocates its own tables
+		threads, err := strconv.Atoi(c.DefaultQuery("threads", "1"))
+		if err != nil || threads < 1 || threads > runtime.NumCPU() {
+			c.JSON(400, gin.H{
+				"error": fmt.Sprintf("Invalid threads passed, between 1 and %d are supported", runtime.NumCPU()),
+			})
+			return
+		}
+
+		// When pondering is enabled the engine keeps searching the expected reply while
+		// the opponent is thinking, and resumes that search if the reply is played
+		ponder := c.DefaultQuery("ponder", "false") == "true"
+
 		game := chessboard.NewGameFromFEN(fen)
-		engine := chessboard.NewBruteForceEngine(&game)
+		engine, pondered := takePonder(fen)
+		if pondered {
+			engine.PonderHit(time)
+		} else {
+			if engine == nil {
+				engine = chessboard.NewBruteForceEngine(&game)
+			} else {
+				engine.SetGame(&game)
+			}
+			engine.Threads = threads
+			engine.StartAnalysis(time)
+		}
+
+		lines := engine.Wait()
+		if len(lines) == 0 {
+			c.JSON(400, gin.H{
+				"error": "No legal moves in the passed position",
+			})
+			return
+		}
+		game.Move(lines[0].Move)
+
+		if ponder && game.Result() == chessboard.NoResult {
+			if expectedMove := engine.PonderMove(); expectedMove != nil {
+				startPonder(engine, &game, expectedMove)
+			}
+		}
 
-		game.Move(engine.BestMove(time))
 		pos := game.Position()
 		c.JSON(200, gin.H{
 			"fen":    pos.FEN(),
@@ -51,9 +92,102 @@ func main() {
 		})
 	})
 
+	r.GET("/analysis", func(c *gin.Context) {
+		fen := c.DefaultQuery("fen", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+		time, err := strconv.Atoi(c.DefaultQuery("time", "60"))
+		if err != nil {
+			c.JSON(400, gin.H{
+				"error": "Invalid time passed",
+			})
+			return
+		}
+
+		multiPV, err := strconv.Atoi(c.DefaultQuery("multipv", "3"))
+		if err != nil || multiPV < 1 {
+			c.JSON(400, gin.H{
+				"error": "Invalid multipv passed",
+			})
+			return
+		}
+
+		game := chessboard.NewGameFromFEN(fen)
+		engine := chessboard.NewBruteForceEngine(&game)
+		engine.MultiPV = multiPV
+
+		analysisLines := engine.Analyze(time)
+		if len(analysisLines) == 0 {
+			c.JSON(400, gin.H{
+				"error": "No legal moves in the passed position",
+			})
+			return
+		}
+
+		lines := []gin.H{}
+		for _, line := range analysisLines {
+			pv := []string{}
+			for _, move := range line.PV {
+				pv = append(pv, move.UCI())
+			}
+
+			analysis := gin.H{
+				"move":  line.Move.UCI(),
+				"score": line.Score,
+				"depth": line.Depth,
+				"pv":    pv,
+			}
+			if mate, ok := chessboard.MateIn(line.Score); ok {
+				analysis["mate"] = mate
+			}
+
+			lines = append(lines, analysis)
+		}
+
+		c.JSON(200, gin.H{
+			"fen":   fen,
+			"lines": lines,
+		})
+	})
+
+	r.GET("/evaluate", func(c *gin.Context) {
+		fen := c.DefaultQuery("fen", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+
+		game := chessboard.NewGameFromFEN(fen)
+		engine := chessboard.NewBruteForceEngine(&game)
+
+		c.JSON(200, gin.H{
+			"fen":        fen,
+			"evaluation": engine.Evaluate(game.Position()),
+		})
+	})
+
+	r.GET("/attacks", func(c *gin.Context) {
+		fen := c.DefaultQuery("fen", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+
+		game := chessboard.NewGameFromFEN(fen)
+		pos := game.Position()
+
+		c.JSON(200, gin.H{
+			"fen":        fen,
+			"white":      pos.AttackMap(chessboard.WhiteColor),
+			"black":      pos.AttackMap(chessboard.BlackColor),
+			"pinned":     squareNames(pos.Pinned(chessboard.WhiteColor) | pos.Pinned(chessboard.BlackColor)),
+			"discovered": squareNames(pos.DiscoveredAttackers(chessboard.WhiteColor) | pos.DiscoveredAttackers(chessboard.BlackColor)),
+		})
+	})
+
 	if gin.Mode() == "release" {
 		log.Fatal(autotls.Run(r, "baidachess.westeurope.cloudapp.azure.com"))
 	} else {
 		r.Run()
 	}
 }
+
+// squareNames returns the squares of the bitboard in algebraic notation
+func squareNames(bb chessboard.Bitboard) []string {
+	names := []string{}
+	for _, sq := range bb.Squares() {
+		names = append(names, sq.String())
+	}
+
+	return names
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ZaninAndrea/chess_engine/chessboard"
+)
+
+// maxPonderTime is how long a ponder search waits for the opponent's move before being discarded
+const maxPonderTime = 60 * time.Second
+
+// ponderSearch is a search on the opponent's time of the position identified by key,
+// reached if the opponent plays the expected move
+type ponderSearch struct {
+	engine *chessboard.BruteForceEngine
+	key    string
+}
+
+// ponder is the only search running on the opponent's time: starting a new one cancels
+// it and reuses its engine, so that the transposition tables are allocated once
+var ponder *ponderSearch
+var ponderMutex sync.Mutex
+
+// ponderKey identifies a position using the FEN fields relevant for the search,
+// the move counters are ignored because they don't change the best move
+func ponderKey(fen string) string {
+	fields := strings.Fields(fen)
+	if len(fields) > 4 {
+		fields = fields[:4]
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// takePonder returns the engine of the ponder search, if any, and whether it is searching
+// the passed position. When the position is a different one the search is stopped and
+// the engine can be reused
+func takePonder(fen string) (*chessboard.BruteForceEngine, bool) {
+	ponderMutex.Lock()
+	defer ponderMutex.Unlock()
+
+	search := ponder
+	ponder = nil
+	if search == nil {
+		return nil, false
+	}
+	if search.key != ponderKey(fen) {
+		search.engine.PonderMiss()
+		return search.engine, false
+	}
+
+	return search.engine, true
+}
+
+// startPonder starts searching with the passed engine the position reached when the
+// opponent replies to the current position of the game with the expected move,
+// canceling the previous ponder search. Nothing is started when the expected move ends the game
+func startPonder(engine *chessboard.BruteForceEngine, game *chessboard.Game, expectedMove *chessboard.Move) {
+	expectedGame := game.Clone()
+	expectedGame.Move(expectedMove)
+	if expectedGame.Result() != chessboard.NoResult {
+		return
+	}
+
+	expectedPosition := expectedGame.Position()
+	search := &ponderSearch{engine: engine, key: ponderKey(expectedPosition.FEN())}
+
+	ponderMutex.Lock()
+	if ponder != nil {
+		ponder.engine.PonderMiss()
+	}
+	engine.SetGame(game)
+	engine.Ponder(expectedMove)
+	ponder = search
+	ponderMutex.Unlock()
+
+	// Discard the search if the opponent doesn't play the expected move in time
+	time.AfterFunc(maxPonderTime, func() {
+		ponderMutex.Lock()
+		defer ponderMutex.Unlock()
+
+		if ponder == search {
+			ponder = nil
+			engine.PonderMiss()
+		}
+	})
+}
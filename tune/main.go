@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/ZaninAndrea/chess_engine/chessboard"
+)
+
+func main() {
+	positionsPath := flag.String("positions", "", "labeled positions file, one FEN or EPD with the game result per line")
+	paramsPath := flag.String("params", "", "initial evaluation parameters file, the defaults are used when empty")
+	outputPath := flag.String("output", "tuned.json", "file where the tuned parameters are written, .json or .toml")
+	iterations := flag.Int("iterations", 100, "maximum number of passes over all the parameters")
+	validation := flag.Float64("validation", 0.1, "fraction of the positions used to compute the validation error")
+	threads := flag.Int("threads", runtime.NumCPU(), "number of threads evaluating the positions")
+	flag.Parse()
+
+	if *positionsPath == "" {
+		fmt.Fprintln(os.Stderr, "the -positions flag is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	params := chessboard.DefaultEvalParams()
+	if *paramsPath != "" {
+		var err error
+		params, err = chessboard.LoadEvalParams(*paramsPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	positions, err := chessboard.LoadTuningPositions(*positionsPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Loaded %d positions\n", len(positions))
+
+	start := time.Now()
+	tuner := chessboard.NewTuner(params, positions, *validation, *threads)
+	fmt.Printf("Resolved quiet positions in %v: %d for training, %d for validation\n",
+		time.Since(start).Round(time.Millisecond), tuner.TrainingSize(), tuner.ValidationSize())
+
+	k := tuner.FitK()
+	fmt.Printf("Fitted K: %.4f, training error: %.6f, validation error: %.6f\n", k, tuner.TrainingError(), tuner.ValidationError())
+
+	// The parameters are saved after each pass, so that an interrupted run keeps its progress
+	for i := 1; i <= *iterations && !tuner.Converged(); i++ {
+		start := time.Now()
+		changed := tuner.Iterate()
+
+		if err := params.Save(*outputPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Iteration %d: %d parameters changed in %v, training error: %.6f, validation error: %.6f\n",
+			i, changed, time.Since(start).Round(time.Millisecond), tuner.TrainingError(), tuner.ValidationError())
+	}
+
+	if err := params.Save(*outputPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Tuned parameters written to %s\n", *outputPath)
+}
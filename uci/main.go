@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ZaninAndrea/chess_engine/chessboard"
+)
+
+// uciEngine contains the state of the engine between commands of the UCI protocol
+type uciEngine struct {
+	game    chessboard.Game
+	multiPV int
+	threads int
+	// evalParams are the evaluation parameters loaded with the EvalFile option
+	evalParams *chessboard.EvalParams
+	// evalMode selects the classical evaluation or the network loaded with the NNUEFile option
+	evalMode chessboard.EvalMode
+	network  *chessboard.Network
+	// variant is the variant played, chosen with the UCI_Variant option
+	variant chessboard.Variant
+	// chess960 writes castling moves as the king capturing its own rook, set by the UCI_Chess960 option
+	chess960 bool
+
+	// State of the search running in the background, engine is nil when idle
+	engine *chessboard.BruteForceEngine
+	// remainingTime is the time available for the move, used when a ponder search becomes the real one
+	remainingTime int
+	// released is closed when the GUI allows sending the best move of a ponder or infinite search
+	released chan struct{}
+	// finished is closed when the background search has printed its best move
+	finished chan struct{}
+}
+
+func main() {
+	tablesPath := flag.String("tables", "", "precomputed tables written by bitboard_generators -binary, the embedded ones when empty")
+	flag.Parse()
+
+	if *tablesPath != "" {
+		data, err := chessboard.LoadPrecomputedDataFile(*tablesPath)
+		if err == nil {
+			err = chessboard.UsePrecomputedData(data)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	uci := uciEngine{
+		game:       chessboard.NewGame(),
+		multiPV:    1,
+		threads:    1,
+		evalParams: chessboard.DefaultEvalParams(),
+		variant:    chessboard.Standard,
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "uci":
+			fmt.Println("id name chess_engine")
+			fmt.Println("id author Andrea Zanin")
+			fmt.Println("option name MultiPV type spin default 1 min 1 max 256")
+			fmt.Println("option name Threads type spin default 1 min 1 max 256")
+			fmt.Println("option name Ponder type check default false")
+			fmt.Println("option name EvalFile type string default <empty>")
+			fmt.Println("option name EvalMode type combo default Classical var Classical var Network")
+			fmt.Println("option name NNUEFile type string default <empty>")
+			fmt.Println("option name UCI_Chess960 type check default false")
+			variants := ""
+			for _, variant := range chessboard.Variants {
+				variants += " var " + variant.Name()
+			}
+			fmt.Printf("option name UCI_Variant type combo default %s%s\n", chessboard.Standard.Name(), variants)
+			fmt.Println("uciok")
+		case "isready":
+			fmt.Println("readyok")
+		case "ucinewgame":
+			uci.stop()
+			uci.game = chessboard.NewVariantGame(uci.variant)
+		case "setoption":
+			uci.setOption(fields[1:])
+		case "position":
+			uci.stop()
+			uci.position(fields[1:])
+		case "go":
+			uci.stop()
+			uci.search(fields[1:])
+		case "stop":
+			uci.stop()
+		case "ponderhit":
+			uci.ponderHit()
+		case "eval":
+			uci.eval(fields[1:])
+		case "quit":
+			uci.stop()
+			return
+		}
+	}
+}
+
+// setOption parses a command like "setoption name MultiPV value 3"
+func (uci *uciEngine) setOption(args []string) {
+	if len(args) < 4 || args[0] != "name" || args[2] != "value" {
+		fmt.Fprintln(os.Stderr, "Invalid setoption command")
+		return
+	}
+
+	switch args[1] {
+	case "UCI_Variant":
+		variant, err := chessboard.VariantByName(args[3])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		uci.variant = variant
+		return
+	case "UCI_Chess960":
+		// Castling moves are parsed in both notations, so the option only changes the output
+		uci.chess960 = args[3] == "true"
+		return
+	case "Ponder":
+		// Pondering is driven by the GUI through "go ponder", so the option needs no state
+		return
+	case "EvalFile":
+		// The path can contain spaces
+		path := strings.Join(args[3:], " ")
+		if path == "<empty>" {
+			uci.evalParams = chessboard.DefaultEvalParams()
+			return
+		}
+
+		evalParams, err := chessboard.LoadEvalParams(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		uci.evalParams = evalParams
+		return
+	case "EvalMode":
+		switch args[3] {
+		case "Classical":
+			uci.evalMode = chessboard.ClassicalEvaluation
+		case "Network":
+			uci.evalMode = chessboard.NetworkEvaluation
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown evaluation mode %s\n", args[3])
+		}
+		return
+	case "NNUEFile":
+		path := strings.Join(args[3:], " ")
+		if path == "<empty>" {
+			uci.network = nil
+			return
+		}
+
+		network, err := chessboard.LoadNetwork(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		uci.network = network
+		return
+	}
+
+	value, err := strconv.Atoi(args[3])
+	if err != nil || value < 1 {
+		fmt.Fprintf(os.Stderr, "Invalid value for option %s\n", args[1])
+		return
+	}
+
+	switch args[1] {
+	case "MultiPV":
+		uci.multiPV = value
+	case "Threads":
+		uci.threads = value
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown option %s\n", args[1])
+	}
+}
+
+// position parses a command like "position startpos moves e2e4 e7e5" or "position fen <fen> moves ..."
+func (uci *uciEngine) position(args []string) {
+	movesIndex := len(args)
+	for i, arg := range args {
+		if arg == "moves" {
+			movesIndex = i
+			break
+		}
+	}
+
+	switch {
+	case len(args) > 0 && args[0] == "startpos":
+		uci.game = chessboard.NewVariantGame(uci.variant)
+	case len(args) > 1 && args[0] == "fen":
+		uci.game = chessboard.NewVariantGameFromFEN(uci.variant, strings.Join(args[1:movesIndex], " "))
+	default:
+		fmt.Fprintln(os.Stderr, "Invalid position command")
+		return
+	}
+
+	for i := movesIndex + 1; i < len(args); i++ {
+		move, err := uci.game.ParseUCIMove(args[i])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+
+		uci.game.Move(move)
+	}
+}
+
+// eval parses a command like "eval" or "eval json" and prints the static evaluation of the
+// current position split in its terms, as a table or as JSON
+func (uci *uciEngine) eval(args []string) {
+	engine := chessboard.NewBruteForceEngine(&uci.game)
+	engine.EvalParams = uci.evalParams
+	trace := engine.Evaluate(uci.game.Position())
+
+	if len(args) > 0 && args[0] == "json" {
+		data, err := json.Marshal(trace)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+
+		fmt.Println(string(data))
+		return
+	}
+
+	fmt.Println(trace)
+
+	// The network evaluation has no terms, it is printed after the classical trace
+	if uci.evalMode == chessboard.NetworkEvaluation && uci.network != nil {
+		fmt.Printf("Network evaluation: %+.2f (side to move)\n", float64(uci.network.Evaluate(uci.game.Position()))/256)
+	}
+}
+
+// search parses a command like "go wtime 60000 btime 60000" and starts the search in the background
+func (uci *uciEngine) search(args []string) {
+	engine := chessboard.NewBruteForceEngine(&uci.game)
+	engine.LogOutput = os.Stderr
+	engine.MultiPV = uci.multiPV
+	engine.Threads = uci.threads
+	engine.EvalParams = uci.evalParams
+	if uci.evalMode == chessboard.NetworkEvaluation {
+		if uci.network != nil {
+			engine.EvalMode = chessboard.NetworkEvaluation
+			engine.Network = uci.network
+		} else {
+			fmt.Fprintln(os.Stderr, "No network loaded with the NNUEFile option, using the classical evaluation")
+		}
+	}
+
+	// The engine expects the remaining time in seconds and spends 1/40th of it on the move
+	remainingTime := 60
+	params := map[string]int{}
+	flags := map[string]bool{}
+	for i := 0; i < len(args); i++ {
+		if i+1 < len(args) {
+			if value, err := strconv.Atoi(args[i+1]); err == nil {
+				params[args[i]] = value
+				i++
+				continue
+			}
+		}
+
+		flags[args[i]] = true
+	}
+
+	// "go perft <depth>" counts the leaf nodes of the legal moves tree, used to debug the move generation
+	if value, ok := params["perft"]; ok {
+		fmt.Printf("Nodes searched: %d\n", uci.game.Perft(value))
+		return
+	}
+
+	pos := uci.game.Position()
+	if value, ok := params["wtime"]; ok && pos.SideToMove() == chessboard.WhiteColor {
+		remainingTime = value / 1000
+	}
+	if value, ok := params["btime"]; ok && pos.SideToMove() == chessboard.BlackColor {
+		remainingTime = value / 1000
+	}
+	if value, ok := params["movetime"]; ok {
+		remainingTime = value * 40 / 1000
+	}
+	if remainingTime < 1 {
+		remainingTime = 1
+	}
+	if value, ok := params["depth"]; ok {
+		engine.MaxDepth = value
+	}
+
+	uci.engine = engine
+	uci.remainingTime = remainingTime
+	uci.released = make(chan struct{})
+	uci.finished = make(chan struct{})
+
+	// The position sent with "go ponder" already contains the expected move, the
+	// search runs without time limit until "ponderhit" or "stop" like an infinite one
+	if flags["ponder"] || flags["infinite"] {
+		engine.Ponder(nil)
+	} else if value, ok := params["mate"]; ok {
+		// In mate mode look for a forced mate first and fall back to the normal search
+		engine.StartMateSearch(value, remainingTime)
+		close(uci.released)
+	} else {
+		engine.StartAnalysis(remainingTime)
+		close(uci.released)
+	}
+
+	go uci.report(engine, uci.released, uci.finished)
+}
+
+// report waits for the search to end and prints its lines and the best move, the best move
+// of ponder and infinite searches is held back until the GUI releases it
+func (uci *uciEngine) report(engine *chessboard.BruteForceEngine, released chan struct{}, finished chan struct{}) {
+	lines := engine.Wait()
+	<-released
+
+	info := engine.SearchInfo()
+	for i, line := range lines {
+		fmt.Printf("info depth %d seldepth %d multipv %d score %s nodes %d pv %s\n",
+			line.Depth, info.SelDepth, i+1, uciScore(line.Score), info.Nodes, uci.line(line.PV))
+	}
+
+	// Checkmated and stalemated positions have no lines, the null move is the UCI answer
+	if len(lines) == 0 {
+		fmt.Println("bestmove 0000")
+	} else if ponderMove := engine.PonderMove(); ponderMove != nil {
+		fmt.Printf("bestmove %s ponder %s\n", uci.notation(lines[0].Move), uci.notation(ponderMove))
+	} else {
+		fmt.Printf("bestmove %s\n", uci.notation(lines[0].Move))
+	}
+
+	close(finished)
+}
+
+// stop interrupts the background search, if any, and waits for its best move to be printed
+func (uci *uciEngine) stop() {
+	if uci.engine == nil {
+		return
+	}
+
+	uci.engine.Stop()
+	uci.release()
+	<-uci.finished
+	uci.engine = nil
+}
+
+// ponderHit turns the ponder search into the search for the engine's move
+func (uci *uciEngine) ponderHit() {
+	if uci.engine == nil {
+		return
+	}
+
+	uci.engine.PonderHit(uci.remainingTime)
+	uci.release()
+}
+
+func (uci *uciEngine) release() {
+	select {
+	case <-uci.released:
+	default:
+		close(uci.released)
+	}
+}
+
+// uciScore formats a score as "cp <centipawns>" or "mate <moves>"
+func uciScore(score int) string {
+	if mate, ok := chessboard.MateIn(score); ok {
+		return fmt.Sprintf("mate %d", mate)
+	}
+
+	// Scores are stored in 256ths of a pawn
+	return fmt.Sprintf("cp %d", score*100/256)
+}
+
+// notation formats a move in UCI notation, with castling moves written
+// as the king capturing its own rook when playing Chess960
+func (uci *uciEngine) notation(move *chessboard.Move) string {
+	if uci.chess960 {
+		return move.UCIChess960()
+	}
+
+	return move.UCI()
+}
+
+// line formats a list of moves in UCI notation
+func (uci *uciEngine) line(moves []*chessboard.Move) string {
+	notations := make([]string, len(moves))
+	for i, move := range moves {
+		notations[i] = uci.notation(move)
+	}
+
+	return strings.Join(notations, " ")
+}
@@ -3,6 +3,8 @@ import "./App.css"
 import Chessboard from "chessboardjsx"
 import rough from "roughjs/bundled/rough.cjs"
 import Chess from "chess.js"
+import Evaluation from "./Evaluation"
+import { fetchAttackMap, heatmapStyles } from "./AttackMap"
 
 const roughSquare = ({ squareElement, squareWidth }) => {
     let rc = rough.svg(squareElement)
@@ -31,6 +33,33 @@ class App extends React.Component {
         fen: startFEN,
         result: "NotStarted",
         userBlocked: false,
+        showEvaluation: false,
+        showAttacks: false,
+        attackStyles: {},
+    }
+
+    componentDidUpdate(prevProps, prevState) {
+        if (
+            this.state.showAttacks &&
+            (prevState.fen !== this.state.fen || !prevState.showAttacks)
+        ) {
+            this.fetchAttacks()
+        }
+    }
+
+    fetchAttacks = async () => {
+        const fen = this.state.fen
+
+        try {
+            const attacks = await fetchAttackMap(baseurl, fen)
+
+            // Ignore the responses for positions which are not shown anymore
+            if (fen === this.state.fen && this.state.showAttacks) {
+                this.setState({ attackStyles: heatmapStyles(attacks) })
+            }
+        } catch (error) {
+            this.setState({ attackStyles: {} })
+        }
     }
 
     playGame = async () => {
@@ -82,7 +111,11 @@ class App extends React.Component {
         if (result === "NoResult") {
             // Play computer move
             let response = await fetch(
-                baseurl + "bestmove?time=" + 120 + "&fen=" + encodeURI(fen)
+                baseurl +
+                    "bestmove?time=" +
+                    120 +
+                    "&ponder=true&fen=" +
+                    encodeURI(fen)
             ).then((res) => res.json())
 
             this.game = new Chess(response.fen)
@@ -101,7 +134,36 @@ class App extends React.Component {
                     position={this.state.fen}
                     roughSquare={roughSquare}
                     onDrop={this.onDrop}
+                    squareStyles={
+                        this.state.showAttacks ? this.state.attackStyles : {}
+                    }
                 />
+                <button
+                    id="toggle-attacks"
+                    onClick={() =>
+                        this.setState({
+                            showAttacks: !this.state.showAttacks,
+                            attackStyles: {},
+                        })
+                    }
+                >
+                    {this.state.showAttacks ? "HIDE ATTACKS" : "SHOW ATTACKS"}
+                </button>
+                <button
+                    id="toggle-evaluation"
+                    onClick={() =>
+                        this.setState({
+                            showEvaluation: !this.state.showEvaluation,
+                        })
+                    }
+                >
+                    {this.state.showEvaluation
+                        ? "HIDE EVALUATION"
+                        : "SHOW EVALUATION"}
+                </button>
+                {this.state.showEvaluation && (
+                    <Evaluation fen={this.state.fen} baseurl={baseurl} />
+                )}
                 {this.state.result !== "NoResult" && (
                     <button id="start-game" onClick={() => this.playGame()}>
                         START BOT SELF-PLAY
@@ -0,0 +1,45 @@
+// Squares are sent by the server in the order a1, b1, ..., h8
+const squareName = (index) =>
+    "abcdefgh"[index % 8] + (Math.floor(index / 8) + 1)
+
+// The more pieces control a square the stronger its color: blue for white, red for black
+const controlColor = (white, black) => {
+    const control = white - black
+    if (control === 0) {
+        return white > 0 ? "rgba(255, 190, 0, 0.35)" : null
+    }
+
+    const alpha = Math.min(0.7, 0.2 + 0.15 * Math.abs(control))
+    return control > 0
+        ? "rgba(40, 90, 255, " + alpha + ")"
+        : "rgba(230, 40, 40, " + alpha + ")"
+}
+
+export const fetchAttackMap = (baseurl, fen) =>
+    fetch(baseurl + "attacks?fen=" + encodeURIComponent(fen)).then((res) =>
+        res.json()
+    )
+
+// heatmapStyles returns the square styles for chessboardjsx showing which side controls each square,
+// the pinned pieces and the pieces which can give a discovered check are outlined
+export const heatmapStyles = (attacks) => {
+    const styles = {}
+
+    attacks.white.forEach((white, index) => {
+        const color = controlColor(white, attacks.black[index])
+        if (color) {
+            styles[squareName(index)] = { backgroundColor: color }
+        }
+    })
+    attacks.pinned.forEach((square) => {
+        styles[square] = { ...styles[square], boxShadow: "inset 0 0 0 3px black" }
+    })
+    attacks.discovered.forEach((square) => {
+        styles[square] = {
+            ...styles[square],
+            boxShadow: "inset 0 0 0 3px rgb(0, 160, 60)",
+        }
+    })
+
+    return styles
+}
@@ -0,0 +1,126 @@
+import React from "react"
+
+// Scores are returned in 256ths of a pawn
+const pawns = (score) => (score >= 0 ? "+" : "") + (score / 256).toFixed(2)
+
+class Evaluation extends React.Component {
+    state = {
+        evaluation: null,
+        error: null,
+    }
+
+    componentDidMount() {
+        this.fetchEvaluation()
+    }
+
+    componentDidUpdate(prevProps) {
+        if (prevProps.fen !== this.props.fen) {
+            this.fetchEvaluation()
+        }
+    }
+
+    fetchEvaluation = async () => {
+        const fen = this.props.fen
+
+        try {
+            let response = await fetch(
+                this.props.baseurl + "evaluate?fen=" + encodeURIComponent(fen)
+            ).then((res) => res.json())
+
+            // Ignore the responses for positions which are not shown anymore
+            if (fen === this.props.fen) {
+                this.setState({ evaluation: response.evaluation, error: null })
+            }
+        } catch (error) {
+            this.setState({ evaluation: null, error: "Evaluation unavailable" })
+        }
+    }
+
+    render() {
+        const { evaluation, error } = this.state
+        if (error) {
+            return <div className="evaluation">{error}</div>
+        }
+        if (!evaluation) {
+            return <div className="evaluation">Evaluating...</div>
+        }
+
+        // The longest bar is the term with the largest impact on the evaluation
+        const largest = Math.max(
+            1,
+            ...evaluation.terms.map((term) => Math.abs(term.score))
+        )
+
+        return (
+            <div className="evaluation">
+                <div className="evaluation-total">
+                    Evaluation {pawns(evaluation.total)} (game phase{" "}
+                    {evaluation.phase}/{evaluation.maxPhase})
+                </div>
+                {evaluation.endgame && (
+                    <div className="evaluation-endgame">
+                        Endgame {evaluation.endgame}
+                        {evaluation.terms.length > 0 &&
+                            " (end game scale " +
+                                evaluation.scale +
+                                "/" +
+                                evaluation.maxScale +
+                                ")"}
+                    </div>
+                )}
+                <table>
+                    <thead>
+                        <tr>
+                            <th>Term</th>
+                            <th>White</th>
+                            <th>Black</th>
+                            <th colSpan="2">Total</th>
+                        </tr>
+                    </thead>
+                    <tbody>
+                        {evaluation.terms.map((term) => (
+                            <tr
+                                key={term.name}
+                                title={
+                                    "Middle game " +
+                                    pawns(term.white.middleGame - term.black.middleGame) +
+                                    ", end game " +
+                                    pawns(term.white.endGame - term.black.endGame)
+                                }
+                            >
+                                <td>{term.name}</td>
+                                <td>{pawns(term.white.tapered)}</td>
+                                <td>{pawns(term.black.tapered)}</td>
+                                <td>{pawns(term.score)}</td>
+                                <td className="evaluation-bar">
+                                    <div
+                                        className={
+                                            term.score >= 0
+                                                ? "evaluation-bar-white"
+                                                : "evaluation-bar-black"
+                                        }
+                                        style={{
+                                            width:
+                                                (50 * Math.abs(term.score)) /
+                                                    largest +
+                                                "%",
+                                        }}
+                                    />
+                                </td>
+                            </tr>
+                        ))}
+                        <tr>
+                            <td>Tempo</td>
+                            <td />
+                            <td />
+                            <td>{pawns(evaluation.tempo)}</td>
+                            <td />
+                        </tr>
+                    </tbody>
+                </table>
+            </div>
+        )
+    }
+}
+
+export default Evaluation